@@ -34,7 +34,10 @@ func NewAPIServer(listenAddr string, store Storage, s3 *s3.Client) *APIServer {
 	router.HandleFunc("/api/healthcheck", makeHTTPHandlerFunc(server.handleHealth))
 	router.HandleFunc("/api/login", makeHTTPHandlerFunc(server.handleLogin))
 	router.HandleFunc("/api/public/products", makeHTTPHandlerFunc(server.handlePublicProducts))
-	router.HandleFunc("/api/public/products/{id}", makeHTTPHandlerFunc(server.handleGetProductByID))
+	router.HandleFunc("/api/public/products/{id}", makeHTTPHandlerFunc(server.handlePublicProductWithID))
+	router.HandleFunc("/api/public/categories", makeHTTPHandlerFunc(server.handlePublicCategories))
+	router.HandleFunc("/api/public/collections", makeHTTPHandlerFunc(server.handlePublicCollections))
+	router.HandleFunc("/api/public/orders", makeHTTPHandlerFunc(server.handlePublicOrders))
 	//router.HandleFunc("/api/signup", makeHTTPHandlerFunc(server.HandleSignUp))
 	router.HandleFunc("/api/users", withJWTAuth(makeHTTPHandlerFunc(server.handleUsers), server.store))
 	router.HandleFunc("/api/users/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleUsersWithID), server.store))
@@ -42,13 +45,58 @@ func NewAPIServer(listenAddr string, store Storage, s3 *s3.Client) *APIServer {
 	router.HandleFunc("/api/customers/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleCustomersWithID), server.store))
 	router.HandleFunc("/api/customers-3-months", withJWTAuth(makeHTTPHandlerFunc(server.handleCustomersLast3Months), server.store)) // added
 	router.HandleFunc("/api/products", withJWTAuth(makeHTTPHandlerFunc(server.handleProducts), server.store))
+	router.HandleFunc("/api/products/lookup/{code}", withJWTAuth(makeHTTPHandlerFunc(server.handleProductsLookup), server.store))
 	router.HandleFunc("/api/products/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleProductsWithID), server.store))
+	router.HandleFunc("/api/products/{id}/archive", withJWTAuth(makeHTTPHandlerFunc(server.handleProductArchive), server.store))
+	router.HandleFunc("/api/products/{id}/unarchive", withJWTAuth(makeHTTPHandlerFunc(server.handleProductUnarchive), server.store))
+	router.HandleFunc("/api/products/{id}/price-history", withJWTAuth(makeHTTPHandlerFunc(server.handlePriceHistory), server.store))
+	router.HandleFunc("/api/products/{id}/price-schedules", withJWTAuth(makeHTTPHandlerFunc(server.handlePriceSchedules), server.store))
+	router.HandleFunc("/api/products/{id}/price-schedules/{scheduleID}", withJWTAuth(makeHTTPHandlerFunc(server.handlePriceSchedulesWithID), server.store))
+	router.HandleFunc("/api/products/{id}/variants", withJWTAuth(makeHTTPHandlerFunc(server.handleCatalogVariants), server.store))
+	router.HandleFunc("/api/products/{id}/variants/{variantID}", withJWTAuth(makeHTTPHandlerFunc(server.handleCatalogVariantsWithID), server.store))
+	router.HandleFunc("/api/categories", withJWTAuth(makeHTTPHandlerFunc(server.handleCategories), server.store))
+	router.HandleFunc("/api/categories/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleCategoriesWithID), server.store))
+	router.HandleFunc("/api/tags", withJWTAuth(makeHTTPHandlerFunc(server.handleTags), server.store))
+	router.HandleFunc("/api/collections", withJWTAuth(makeHTTPHandlerFunc(server.handleCollections), server.store))
+	router.HandleFunc("/api/collections/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleCollectionsWithID), server.store))
+	router.HandleFunc("/api/collections/{id}/products", withJWTAuth(makeHTTPHandlerFunc(server.handleCollectionProducts), server.store))
+	router.HandleFunc("/api/order-requests", withJWTAuth(makeHTTPHandlerFunc(server.handleOrderRequests), server.store))
+	router.HandleFunc("/api/order-requests/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleOrderRequestsWithID), server.store))
+	router.HandleFunc("/api/order-requests/{id}/convert", withJWTAuth(makeHTTPHandlerFunc(server.handleOrderRequestConvert), server.store))
+	router.HandleFunc("/api/order-requests/{id}/reject", withJWTAuth(makeHTTPHandlerFunc(server.handleOrderRequestReject), server.store))
+	router.HandleFunc("/api/coupons", withJWTAuth(makeHTTPHandlerFunc(server.handleCoupons), server.store))
+	router.HandleFunc("/api/coupons/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleCouponsWithID), server.store))
 	router.HandleFunc("/api/sales", withJWTAuth(makeHTTPHandlerFunc(server.handleSales), server.store))
 	router.HandleFunc("/api/sales/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleSalesWithID), server.store))
+	router.HandleFunc("/api/sales/{id}/status", withJWTAuth(makeHTTPHandlerFunc(server.handleSaleStatus), server.store))
+	router.HandleFunc("/api/sales/{id}/status-history", withJWTAuth(makeHTTPHandlerFunc(server.handleSaleStatusHistory), server.store))
+	router.HandleFunc("/api/sales/{id}/receipt", withJWTAuth(makeHTTPHandlerFunc(server.handleSaleReceipt), server.store))
+	router.HandleFunc("/api/sales/{id}/receipt.pdf", withJWTAuth(makeHTTPHandlerFunc(server.handleSaleReceiptPDF), server.store))
+	router.HandleFunc("/api/sales/{id}/shipments", withJWTAuth(makeHTTPHandlerFunc(server.handleShipments), server.store))
+	router.HandleFunc("/api/sales/{id}/shipments/{shipmentID}", withJWTAuth(makeHTTPHandlerFunc(server.handleShipmentsWithID), server.store))
+	router.HandleFunc("/api/sales/{id}/payments", withJWTAuth(makeHTTPHandlerFunc(server.handlePayments), server.store))
+	router.HandleFunc("/api/sales/{id}/payments/{paymentID}", withJWTAuth(makeHTTPHandlerFunc(server.handlePaymentsWithID), server.store))
 	router.HandleFunc("/api/sales-3-months", withJWTAuth(makeHTTPHandlerFunc(server.handleSalesLast3Months), server.store)) // added
+	router.HandleFunc("/api/expense-categories", withJWTAuth(makeHTTPHandlerFunc(server.handleExpenseCategories), server.store))
+	router.HandleFunc("/api/expense-categories/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleExpenseCategoriesWithID), server.store))
+	router.HandleFunc("/api/vendors", withJWTAuth(makeHTTPHandlerFunc(server.handleVendors), server.store))
+	router.HandleFunc("/api/vendors/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleVendorsWithID), server.store))
+	router.HandleFunc("/api/recurring-expenses", withJWTAuth(makeHTTPHandlerFunc(server.handleRecurringExpenses), server.store))
+	router.HandleFunc("/api/recurring-expenses/upcoming", withJWTAuth(makeHTTPHandlerFunc(server.handleUpcomingExpenses), server.store))
+	router.HandleFunc("/api/recurring-expenses/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleRecurringExpensesWithID), server.store))
+	router.HandleFunc("/api/recurring-expenses/{id}/occurrences/{date}", withJWTAuth(makeHTTPHandlerFunc(server.handleRecurringExpenseOccurrence), server.store))
 	router.HandleFunc("/api/expenses", withJWTAuth(makeHTTPHandlerFunc(server.handleExpenses), server.store))
 	router.HandleFunc("/api/expenses/{id}", withJWTAuth(makeHTTPHandlerFunc(server.handleExpensesWithID), server.store))
+	router.HandleFunc("/api/exchange-rates", withJWTAuth(makeHTTPHandlerFunc(server.handleExchangeRates), server.store))
+	router.HandleFunc("/api/exchange-rates/import", withJWTAuth(makeHTTPHandlerFunc(server.handleExchangeRatesImport), server.store))
+	router.HandleFunc("/api/exchange-rates/{currency}/{date}", withJWTAuth(makeHTTPHandlerFunc(server.handleExchangeRatesWithDate), server.store))
 	router.HandleFunc("/api/earnings", withJWTAuth(makeHTTPHandlerFunc(server.handleEarnings), server.store))
+	router.HandleFunc("/api/kpis", withJWTAuth(makeHTTPHandlerFunc(server.handleKPIs), server.store))
+	router.HandleFunc("/api/reports/receivables", withJWTAuth(makeHTTPHandlerFunc(server.handleReceivables), server.store))
+	router.HandleFunc("/api/reports/product-profitability", withJWTAuth(makeHTTPHandlerFunc(server.handleProductProfitability), server.store))
+	router.HandleFunc("/api/reports/cohorts", withJWTAuth(makeHTTPHandlerFunc(server.handleCohorts), server.store))
+	router.HandleFunc("/api/reports/rfm", withJWTAuth(makeHTTPHandlerFunc(server.handleRFM), server.store))
+	router.HandleFunc("/api/reports/geography", withJWTAuth(makeHTTPHandlerFunc(server.handleGeography), server.store))
 
 	return server
 }
@@ -65,6 +113,7 @@ func (server *APIServer) Run() {
 		AllowCredentials: true,
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
 		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		ExposedHeaders:   []string{"ETag", "X-Total-Count"},
 	})
 
 	handler := c.Handler(server.Router)
@@ -188,7 +237,203 @@ func (server *APIServer) handleProductsWithID(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// handleProductsLookup handles product lookup by SKU or barcode
+func (server *APIServer) handleProductsLookup(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetProductByCode(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleProductArchive handles archive requests
+func (server *APIServer) handleProductArchive(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleArchiveProduct(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleProductUnarchive handles unarchive requests
+func (server *APIServer) handleProductUnarchive(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleUnarchiveProduct(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handlePriceHistory handles get requests
+func (server *APIServer) handlePriceHistory(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetPriceHistory(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handlePriceSchedules handles get and post requests
+func (server *APIServer) handlePriceSchedules(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetPriceSchedules(w, r)
+	case http.MethodPost:
+		return server.handleCreatePriceSchedule(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handlePriceSchedulesWithID handles cancel requests
+func (server *APIServer) handlePriceSchedulesWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodDelete:
+		return server.handleCancelPriceSchedule(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCatalogVariants handles get and post requests
+func (server *APIServer) handleCatalogVariants(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCatalogVariants(w, r)
+	case http.MethodPost:
+		return server.handleCreateCatalogVariant(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCatalogVariantsWithID handles get, update and delete requests
+func (server *APIServer) handleCatalogVariantsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCatalogVariantByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateCatalogVariant(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteCatalogVariant(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCategories handles get and post requests
+func (server *APIServer) handleCategories(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCategories(w, r)
+	case http.MethodPost:
+		return server.handleCreateCategory(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCategoriesWithID handles get, update and delete requests
+func (server *APIServer) handleCategoriesWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCategoryByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateCategory(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteCategory(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleTags handles get requests
+func (server *APIServer) handleTags(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetTags(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCollections handles get and post requests
+func (server *APIServer) handleCollections(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCollections(w, r)
+	case http.MethodPost:
+		return server.handleCreateCollection(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCollectionsWithID handles get, update and delete requests
+func (server *APIServer) handleCollectionsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCollectionByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateCollection(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteCollection(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCollectionProducts handles replacing the products of a collection
+func (server *APIServer) handleCollectionProducts(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPut:
+		return server.handleSetCollectionProducts(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
 // handleSales handles get and post requests
+func (server *APIServer) handleOrderRequests(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetOrderRequests(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleOrderRequestsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetOrderRequestByID(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleOrderRequestConvert(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleConvertOrderRequest(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleOrderRequestReject(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleRejectOrderRequest(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
 func (server *APIServer) handleSales(w http.ResponseWriter, r *http.Request) error {
 	switch r.Method {
 	case http.MethodGet:
@@ -221,6 +466,163 @@ func (server *APIServer) handleSalesWithID(w http.ResponseWriter, r *http.Reques
 }
 
 // handleExpenses handles get and post requests
+func (server *APIServer) handleSaleStatus(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleUpdateSaleStatus(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleSaleStatusHistory(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetSaleStatusHistory(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleCoupons(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCoupons(w, r)
+	case http.MethodPost:
+		return server.handleCreateCoupon(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleCouponsWithID handles get, update and delete requests
+func (server *APIServer) handleCouponsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCouponByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateCoupon(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteCoupon(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleSaleReceipt(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleIssueSaleReceipt(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleSaleReceiptPDF(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetSaleReceipt(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleShipments(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetShipments(w, r)
+	case http.MethodPost:
+		return server.handleCreateShipment(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleShipmentsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetShipmentByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateShipment(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteShipment(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handlePayments(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetPayments(w, r)
+	case http.MethodPost:
+		return server.handleCreatePayment(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handlePaymentsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetPaymentByID(w, r)
+	case http.MethodDelete:
+		return server.handleDeletePayment(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleExpenseCategories(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetExpenseCategories(w, r)
+	case http.MethodPost:
+		return server.handleCreateExpenseCategory(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleExpenseCategoriesWithID handles get, update and delete requests
+func (server *APIServer) handleExpenseCategoriesWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetExpenseCategoryByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateExpenseCategory(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteExpenseCategory(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleVendors(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetVendors(w, r)
+	case http.MethodPost:
+		return server.handleCreateVendor(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleVendorsWithID handles get, update and delete requests
+func (server *APIServer) handleVendorsWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetVendorByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateVendor(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteVendor(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
 func (server *APIServer) handleExpenses(w http.ResponseWriter, r *http.Request) error {
 	switch r.Method {
 	case http.MethodGet:
@@ -246,6 +648,51 @@ func (server *APIServer) handleExpensesWithID(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (server *APIServer) handleRecurringExpenses(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetRecurringExpenses(w, r)
+	case http.MethodPost:
+		return server.handleCreateRecurringExpense(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+// handleRecurringExpensesWithID handles get, update and delete requests
+func (server *APIServer) handleRecurringExpensesWithID(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetRecurringExpenseByID(w, r)
+	case http.MethodPut:
+		return server.handleUpdateRecurringExpense(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteRecurringExpense(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleUpcomingExpenses(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetUpcomingExpenses(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleRecurringExpenseOccurrence(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPut:
+		return server.handleUpdateOccurrence(w, r)
+	case http.MethodDelete:
+		return server.handleDeleteOccurrence(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
 // handleEarnings handles get by month
 func (server *APIServer) handleEarnings(w http.ResponseWriter, r *http.Request) error {
 	switch r.Method {
@@ -255,3 +702,86 @@ func (server *APIServer) handleEarnings(w http.ResponseWriter, r *http.Request)
 		return fmt.Errorf("unsupported method: %s", r.Method)
 	}
 }
+
+func (server *APIServer) handleKPIs(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetKPIs(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleReceivables(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetReceivables(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleProductProfitability(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetProductProfitability(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleCohorts(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetCohorts(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleRFM(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetRFM(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleGeography(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetGeography(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleExchangeRates(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return server.handleGetExchangeRates(w, r)
+	case http.MethodPost:
+		return server.handleCreateExchangeRate(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleExchangeRatesImport(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost:
+		return server.handleImportExchangeRates(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
+
+func (server *APIServer) handleExchangeRatesWithDate(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodDelete:
+		return server.handleDeleteExchangeRate(w, r)
+	default:
+		return fmt.Errorf("unsupported method: %s", r.Method)
+	}
+}
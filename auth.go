@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"log"
@@ -14,6 +15,7 @@ func createJWT(user *User) (string, error) {
 	claims := &jwt.MapClaims{
 		"expiresAt": 15000,
 		"email":     user.Email,
+		"user_id":   user.ID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -31,9 +33,21 @@ func permissionDeniedError(w http.ResponseWriter) {
 	}
 }
 
+// authUserIDKey is the request context key of the ID of the authenticated user.
+type authUserIDKey struct{}
+
+// getAuthUserID returns the ID of the user authenticated by withJWTAuth, or nil on public routes.
+func getAuthUserID(r *http.Request) *string {
+	if userID, ok := r.Context().Value(authUserIDKey{}).(string); ok {
+		return &userID
+	}
+	return nil
+}
+
 // withJWTAuth adds JWT authentication to the provided HTTP handler.
 // It validates the included JWT and authorizes the request.
 // If the JWT is invalid or the request is unauthorized, it responds with a permission denied error.
+// The ID of the user the token was issued to is available to the handler through getAuthUserID.
 // Returns an HTTP handler that wraps the original handler.
 func withJWTAuth(fn http.HandlerFunc, _ Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -59,7 +73,18 @@ func withJWTAuth(fn http.HandlerFunc, _ Storage) http.HandlerFunc {
 			return
 		}
 
-		fn(w, r)
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			permissionDeniedError(w)
+			return
+		}
+		userID, ok := claims["user_id"].(string)
+		if !ok || userID == "" {
+			permissionDeniedError(w)
+			return
+		}
+
+		fn(w, r.WithContext(context.WithValue(r.Context(), authUserIDKey{}, userID)))
 	}
 }
 
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const defaultCaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+
+// captchaEnabled reports whether public forms must send a captcha token.
+func captchaEnabled() bool {
+	return os.Getenv("CAPTCHA_SECRET") != ""
+}
+
+// verifyCaptcha checks a captcha token against the provider configured with
+// CAPTCHA_SECRET and CAPTCHA_VERIFY_URL. reCAPTCHA, hCaptcha and Turnstile
+// share the same verification API.
+func verifyCaptcha(token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("captcha is required")
+	}
+
+	verifyURL := os.Getenv("CAPTCHA_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = defaultCaptchaVerifyURL
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.PostForm(verifyURL, url.Values{
+		"secret":   {os.Getenv("CAPTCHA_SECRET")},
+		"response": {token},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return fmt.Errorf("error verifying captcha: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error verifying captcha: %v", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("invalid captcha")
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func (server *APIServer) handleCreateCategory(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateCategoryRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	category, err := NewCategory(
+		req.Name,
+		req.Slug,
+		req.Description,
+		req.ParentID,
+		req.Position,
+	)
+	if err != nil {
+		return err
+	}
+
+	if category.ParentID != nil {
+		if _, err := server.store.GetCategoryByID(*category.ParentID); err != nil {
+			return err
+		}
+	}
+
+	if err := server.store.CreateCategory(category); err != nil {
+		return err
+	}
+
+	// Recovering category from DB
+	createdCategory, err := server.store.GetCategoryByID(category.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdCategory)
+}
+
+func (server *APIServer) handleGetCategories(w http.ResponseWriter, _ *http.Request) error {
+	categories, err := server.store.GetCategories()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, categories)
+}
+
+func (server *APIServer) handleGetCategoryByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	category, err := server.store.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, category)
+}
+
+func (server *APIServer) handleUpdateCategory(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	var category Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		return err
+	}
+
+	category.ID = id
+	if category.Slug == "" {
+		category.Slug = slugify(category.Name)
+	}
+
+	// A category can't be moved under itself or one of its subcategories
+	if category.ParentID != nil {
+		isDescendant, err := server.store.IsCategoryDescendant(*category.ParentID, id)
+		if err != nil {
+			return err
+		}
+		if isDescendant {
+			return fmt.Errorf("category [%s] can't be its own parent", id)
+		}
+		if _, err := server.store.GetCategoryByID(*category.ParentID); err != nil {
+			return err
+		}
+	}
+
+	if err := server.store.UpdateCategory(&category); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedCategory, err := server.store.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedCategory)
+}
+
+func (server *APIServer) handleDeleteCategory(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteCategory(id); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
+
+func (server *APIServer) handlePublicCategories(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodGet {
+		return server.handleGetCategories(w, r)
+	}
+	return WriteJSON(w, http.StatusMethodNotAllowed, apiError{Error: "unsupported method: " + r.Method})
+}
+
+func (server *APIServer) handleGetTags(w http.ResponseWriter, _ *http.Request) error {
+	tags, err := server.store.GetTags()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, tags)
+}
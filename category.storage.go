@@ -0,0 +1,229 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+func (s *PostgresStore) CreateCategoriesTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS categories (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            slug VARCHAR(255) NOT NULL UNIQUE,
+            description TEXT,
+            parent_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+            position INTEGER NOT NULL DEFAULT 0,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("categories")
+}
+
+const categoryColumns = `
+	id, name, slug, description, parent_id, position, created_at, updated_at`
+
+func (s *PostgresStore) CreateCategory(category *Category) error {
+	query := `
+        INSERT INTO categories (
+            name,
+            slug,
+            description,
+            parent_id,
+            position
+        )
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		category.Name,
+		category.Slug,
+		category.Description,
+		category.ParentID,
+		category.Position,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted category
+	category.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetCategoryByID(id string) (*Category, error) {
+	rows, err := s.db.Query(`
+		SELECT `+categoryColumns+`
+		FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCategories(rows)
+	}
+
+	return nil, fmt.Errorf("category [%s] not found", id)
+}
+
+func (s *PostgresStore) GetCategories() ([]*Category, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + categoryColumns + `
+		FROM categories ORDER BY position, name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var categories []*Category
+	for rows.Next() {
+		category, err := scanIntoCategories(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+func scanIntoCategories(rows *sql.Rows) (*Category, error) {
+	category := new(Category)
+	var description sql.NullString
+	var parentID sql.NullString
+
+	err := rows.Scan(
+		&category.ID,
+		&category.Name,
+		&category.Slug,
+		&description,
+		&parentID,
+		&category.Position,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if description.Valid {
+		category.Description = &description.String
+	}
+
+	if parentID.Valid {
+		category.ParentID = &parentID.String
+	}
+
+	return category, nil
+}
+
+func (s *PostgresStore) UpdateCategory(category *Category) error {
+	query := `
+		UPDATE categories
+		SET
+		    name = $1,
+		    slug = $2,
+		    description = $3,
+		    parent_id = $4,
+		    position = $5
+		WHERE id = $6
+	`
+
+	_, err := s.db.Exec(
+		query,
+		category.Name,
+		category.Slug,
+		category.Description,
+		category.ParentID,
+		category.Position,
+		category.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsCategoryDescendant reports whether categoryID is ancestorID itself or one
+// of its subcategories, at any depth.
+func (s *PostgresStore) IsCategoryDescendant(categoryID, ancestorID string) (bool, error) {
+	var isDescendant bool
+	err := s.db.QueryRow(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = $2
+			UNION
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT EXISTS(SELECT 1 FROM tree WHERE id = $1)`, categoryID, ancestorID).Scan(&isDescendant)
+	if err != nil {
+		return false, err
+	}
+	return isDescendant, nil
+}
+
+func (s *PostgresStore) DeleteCategory(id string) error {
+	_, err := s.db.Exec("DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTags lists every tag of the products that are not archived with the
+// number of those products using it.
+func (s *PostgresStore) GetTags() ([]*TagSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT tag, COUNT(*) AS products
+		FROM products, UNNEST(tags) AS tag
+		WHERE archived_at IS NULL
+		GROUP BY tag
+		ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var tags []*TagSummary
+	for rows.Next() {
+		tag := new(TagSummary)
+		if err := rows.Scan(&tag.Name, &tag.Products); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Category groups products, categories can be nested through ParentID.
+type Category struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Description *string   `json:"description,omitempty"`
+	ParentID    *string   `json:"parent_id,omitempty"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateCategoryRequest struct {
+	Name        string  `json:"name"`
+	Slug        string  `json:"slug"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *string `json:"parent_id,omitempty"`
+	Position    int     `json:"position"`
+}
+
+type TagSummary struct {
+	Name     string `json:"name"`
+	Products int    `json:"products"`
+}
+
+func NewCategory(
+	name string,
+	slug string,
+	description *string,
+	parentID *string,
+	position int,
+) (*Category, error) {
+	if slug == "" {
+		slug = slugify(name)
+	}
+	if slug == "" {
+		return nil, fmt.Errorf("category name is required")
+	}
+
+	return &Category{
+		Name:        name,
+		Slug:        slug,
+		Description: description,
+		ParentID:    parentID,
+		Position:    position,
+	}, nil
+}
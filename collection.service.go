@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (server *APIServer) handleCreateCollection(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateCollectionRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	collection, err := NewCollection(
+		req.Name,
+		req.Slug,
+		req.Description,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateCollection(collection); err != nil {
+		return err
+	}
+
+	if len(req.ProductIDs) > 0 {
+		if err := server.store.SetCollectionProducts(collection.ID, req.ProductIDs); err != nil {
+			return err
+		}
+	}
+
+	// Recovering collection from DB
+	createdCollection, err := server.store.GetCollectionByID(collection.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdCollection)
+}
+
+func (server *APIServer) handleGetCollections(w http.ResponseWriter, _ *http.Request) error {
+	collections, err := server.store.GetCollections()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, collections)
+}
+
+func (server *APIServer) handleGetCollectionByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	collection, err := server.store.GetCollectionByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, collection)
+}
+
+func (server *APIServer) handleUpdateCollection(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCollectionByID(id)
+	if err != nil {
+		return err
+	}
+
+	var collection Collection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		return err
+	}
+
+	collection.ID = id
+	if collection.Slug == "" {
+		collection.Slug = slugify(collection.Name)
+	}
+
+	if err := server.store.UpdateCollection(&collection); err != nil {
+		return err
+	}
+
+	// Products are only replaced when the list is sent
+	if collection.ProductIDs != nil {
+		if err := server.store.SetCollectionProducts(id, collection.ProductIDs); err != nil {
+			return err
+		}
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedCollection, err := server.store.GetCollectionByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedCollection)
+}
+
+func (server *APIServer) handleSetCollectionProducts(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCollectionByID(id)
+	if err != nil {
+		return err
+	}
+
+	req := new(SetCollectionProductsRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if err := server.store.SetCollectionProducts(id, req.ProductIDs); err != nil {
+		return err
+	}
+
+	updatedCollection, err := server.store.GetCollectionByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedCollection)
+}
+
+func (server *APIServer) handlePublicCollections(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodGet {
+		return server.handleGetCollections(w, r)
+	}
+	return WriteJSON(w, http.StatusMethodNotAllowed, apiError{Error: "unsupported method: " + r.Method})
+}
+
+func (server *APIServer) handleDeleteCollection(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCollectionByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteCollection(id); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
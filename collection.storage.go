@@ -0,0 +1,210 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+func (s *PostgresStore) CreateCollectionsTables() error {
+	// Create the tables if they don't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS collections (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            slug VARCHAR(255) NOT NULL UNIQUE,
+            description TEXT,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE TABLE IF NOT EXISTS collection_products (
+            collection_id UUID REFERENCES collections(id) ON DELETE CASCADE,
+            product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+            position INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (collection_id, product_id)
+        );
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("collections")
+}
+
+const collectionColumns = `
+	c.id, c.name, c.slug, c.description,
+	ARRAY(
+		SELECT cp.product_id::TEXT FROM collection_products cp
+		WHERE cp.collection_id = c.id ORDER BY cp.position
+	),
+	c.created_at, c.updated_at`
+
+func (s *PostgresStore) CreateCollection(collection *Collection) error {
+	query := `
+        INSERT INTO collections (
+            name,
+            slug,
+            description
+        )
+        VALUES ($1, $2, $3)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		collection.Name,
+		collection.Slug,
+		collection.Description,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted collection
+	collection.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetCollectionByID(id string) (*Collection, error) {
+	rows, err := s.db.Query(`
+		SELECT `+collectionColumns+`
+		FROM collections c WHERE c.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCollections(rows)
+	}
+
+	return nil, fmt.Errorf("collection [%s] not found", id)
+}
+
+func (s *PostgresStore) GetCollections() ([]*Collection, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + collectionColumns + `
+		FROM collections c ORDER BY c.name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var collections []*Collection
+	for rows.Next() {
+		collection, err := scanIntoCollections(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, collection)
+	}
+
+	return collections, nil
+}
+
+func scanIntoCollections(rows *sql.Rows) (*Collection, error) {
+	collection := new(Collection)
+	var description sql.NullString
+
+	err := rows.Scan(
+		&collection.ID,
+		&collection.Name,
+		&collection.Slug,
+		&description,
+		pq.Array(&collection.ProductIDs),
+		&collection.CreatedAt,
+		&collection.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if description.Valid {
+		collection.Description = &description.String
+	}
+
+	if collection.ProductIDs == nil {
+		collection.ProductIDs = []string{}
+	}
+
+	return collection, nil
+}
+
+func (s *PostgresStore) UpdateCollection(collection *Collection) error {
+	query := `
+		UPDATE collections
+		SET
+		    name = $1,
+		    slug = $2,
+		    description = $3
+		WHERE id = $4
+	`
+
+	_, err := s.db.Exec(
+		query,
+		collection.Name,
+		collection.Slug,
+		collection.Description,
+		collection.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetCollectionProducts replaces the products of a collection, keeping the
+// order of productIDs as the display order.
+func (s *PostgresStore) SetCollectionProducts(collectionID string, productIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM collection_products WHERE collection_id = $1;
+	`, collectionID)
+	if err == nil {
+		_, err = tx.Exec(`
+			INSERT INTO collection_products (collection_id, product_id, position)
+			SELECT $1, product_id::UUID, (position - 1)::INTEGER
+			FROM UNNEST($2::TEXT[]) WITH ORDINALITY AS ids(product_id, position)
+			ON CONFLICT DO NOTHING
+		`, collectionID, pq.Array(productIDs))
+	}
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Printf("error rolling back transaction: %v", rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) DeleteCollection(id string) error {
+	_, err := s.db.Exec("DELETE FROM collections WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Collection is a curated, ordered list of products.
+type Collection struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Description *string   `json:"description,omitempty"`
+	ProductIDs  []string  `json:"product_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string   `json:"name"`
+	Slug        string   `json:"slug"`
+	Description *string  `json:"description,omitempty"`
+	ProductIDs  []string `json:"product_ids"`
+}
+
+type SetCollectionProductsRequest struct {
+	ProductIDs []string `json:"product_ids"`
+}
+
+func NewCollection(
+	name string,
+	slug string,
+	description *string,
+) (*Collection, error) {
+	if slug == "" {
+		slug = slugify(name)
+	}
+	if slug == "" {
+		return nil, fmt.Errorf("collection name is required")
+	}
+
+	return &Collection{
+		Name:        name,
+		Slug:        slug,
+		Description: description,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (server *APIServer) handleCreateCoupon(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateCouponRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	coupon, err := NewCoupon(
+		req.Code,
+		req.Description,
+		req.DiscountType,
+		req.DiscountValue,
+		req.StartsAt,
+		req.EndsAt,
+		req.MaxUses,
+		req.MaxUsesPerCustomer,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateCoupon(coupon); err != nil {
+		return err
+	}
+
+	// Recovering coupon from DB
+	createdCoupon, err := server.store.GetCouponByID(coupon.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdCoupon)
+}
+
+func (server *APIServer) handleGetCoupons(w http.ResponseWriter, _ *http.Request) error {
+	coupons, err := server.store.GetCoupons()
+	if err != nil {
+		return err
+	}
+	if coupons == nil {
+		coupons = []*Coupon{}
+	}
+	return WriteJSON(w, http.StatusOK, coupons)
+}
+
+func (server *APIServer) handleGetCouponByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	coupon, err := server.store.GetCouponByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, coupon)
+}
+
+func (server *APIServer) handleUpdateCoupon(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCouponByID(id)
+	if err != nil {
+		return err
+	}
+
+	var coupon Coupon
+	if err := json.NewDecoder(r.Body).Decode(&coupon); err != nil {
+		return err
+	}
+
+	coupon.ID = id
+	if err := coupon.Validate(); err != nil {
+		return err
+	}
+
+	if err := server.store.UpdateCoupon(&coupon); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedCoupon, err := server.store.GetCouponByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedCoupon)
+}
+
+func (server *APIServer) handleDeleteCoupon(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetCouponByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteCoupon(id); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
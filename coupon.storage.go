@@ -0,0 +1,297 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (s *PostgresStore) CreateCouponsTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS coupons (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            code VARCHAR(40) NOT NULL UNIQUE,
+            description TEXT,
+            discount_type VARCHAR(20) NOT NULL,
+            discount_value BIGINT NOT NULL,
+            starts_at TIMESTAMPTZ,
+            ends_at TIMESTAMPTZ,
+            max_uses INTEGER,
+            max_uses_per_customer INTEGER,
+            times_used INTEGER NOT NULL DEFAULT 0,
+            is_active BOOLEAN NOT NULL DEFAULT TRUE,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        -- The code is kept on the sale in case the coupon is deleted
+        ALTER TABLE sales ADD COLUMN IF NOT EXISTS coupon_id UUID REFERENCES coupons(id) ON DELETE SET NULL;
+        ALTER TABLE sales ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(40);
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("coupons")
+}
+
+const couponColumns = `
+	id, code, description, discount_type, discount_value, starts_at, ends_at,
+	max_uses, max_uses_per_customer, times_used, is_active, created_at, updated_at`
+
+func (s *PostgresStore) CreateCoupon(coupon *Coupon) error {
+	query := `
+        INSERT INTO coupons (
+            code,
+            description,
+            discount_type,
+            discount_value,
+            starts_at,
+            ends_at,
+            max_uses,
+            max_uses_per_customer,
+            is_active
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		coupon.Code,
+		coupon.Description,
+		coupon.DiscountType,
+		coupon.DiscountValue,
+		coupon.StartsAt,
+		coupon.EndsAt,
+		coupon.MaxUses,
+		coupon.MaxUsesPerCustomer,
+		coupon.IsActive,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted coupon
+	coupon.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetCouponByID(id string) (*Coupon, error) {
+	rows, err := s.db.Query(`
+		SELECT `+couponColumns+`
+		FROM coupons WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCoupons(rows)
+	}
+
+	return nil, fmt.Errorf("coupon [%s] not found", id)
+}
+
+func (s *PostgresStore) GetCouponByCode(code string) (*Coupon, error) {
+	rows, err := s.db.Query(`
+		SELECT `+couponColumns+`
+		FROM coupons WHERE code = $1`, normalizeCouponCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCoupons(rows)
+	}
+
+	return nil, fmt.Errorf("coupon [%s] not found", code)
+}
+
+func (s *PostgresStore) GetCoupons() ([]*Coupon, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + couponColumns + `
+		FROM coupons ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var coupons []*Coupon
+	for rows.Next() {
+		coupon, err := scanIntoCoupons(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		coupons = append(coupons, coupon)
+	}
+
+	return coupons, nil
+}
+
+func scanIntoCoupons(rows *sql.Rows) (*Coupon, error) {
+	coupon := new(Coupon)
+	var description sql.NullString
+	var startsAt, endsAt sql.NullTime
+	var maxUses, maxUsesPerCustomer sql.NullInt64
+
+	err := rows.Scan(
+		&coupon.ID,
+		&coupon.Code,
+		&description,
+		&coupon.DiscountType,
+		&coupon.DiscountValue,
+		&startsAt,
+		&endsAt,
+		&maxUses,
+		&maxUsesPerCustomer,
+		&coupon.TimesUsed,
+		&coupon.IsActive,
+		&coupon.CreatedAt,
+		&coupon.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon.Description = nullStringToPtr(description)
+	if startsAt.Valid {
+		coupon.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		coupon.EndsAt = &endsAt.Time
+	}
+	coupon.MaxUses = nullIntToPtr(maxUses)
+	coupon.MaxUsesPerCustomer = nullIntToPtr(maxUsesPerCustomer)
+
+	return coupon, nil
+}
+
+func (s *PostgresStore) UpdateCoupon(coupon *Coupon) error {
+	query := `
+		UPDATE coupons
+		SET
+		    code = $1,
+		    description = $2,
+		    discount_type = $3,
+		    discount_value = $4,
+		    starts_at = $5,
+		    ends_at = $6,
+		    max_uses = $7,
+		    max_uses_per_customer = $8,
+		    is_active = $9
+		WHERE id = $10
+	`
+
+	_, err := s.db.Exec(
+		query,
+		coupon.Code,
+		coupon.Description,
+		coupon.DiscountType,
+		coupon.DiscountValue,
+		coupon.StartsAt,
+		coupon.EndsAt,
+		coupon.MaxUses,
+		coupon.MaxUsesPerCustomer,
+		coupon.IsActive,
+		coupon.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) DeleteCoupon(id string) error {
+	_, err := s.db.Exec("DELETE FROM coupons WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CountCouponUsesByCustomer counts the sales of a customer that used the
+// coupon, cancelled sales don't count.
+func (s *PostgresStore) CountCouponUsesByCustomer(couponID, customerID string) (int, error) {
+	return countCouponUsesByCustomer(s.db, couponID, customerID)
+}
+
+func countCouponUsesByCustomer(db rowQueryer, couponID, customerID string) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM sales
+		WHERE coupon_id = $1 AND customer_id = $2 AND status != 'cancelled'`,
+		couponID, customerID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// redeemCoupon takes one use of the coupon of the sale in the transaction
+// storing the sale. The coupon is locked and checked again, uses by the
+// customer included, so concurrent sales can't go over its limits.
+func redeemCoupon(tx *sql.Tx, sale *SaleWithProducts) error {
+	rows, err := tx.Query(`
+		SELECT `+couponColumns+`
+		FROM coupons WHERE id = $1 FOR UPDATE`, *sale.CouponID)
+	if err != nil {
+		return err
+	}
+	var coupon *Coupon
+	if rows.Next() {
+		coupon, err = scanIntoCoupons(rows)
+	} else {
+		err = rows.Err()
+	}
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if coupon == nil {
+		return fmt.Errorf("coupon [%s] not found", *sale.CouponID)
+	}
+
+	// The discount of the sale was computed with the coupon as it was read
+	if coupon.DiscountType != sale.DiscountType || coupon.DiscountValue != sale.DiscountValue {
+		return fmt.Errorf("coupon [%s] changed while the sale was created, try again", coupon.Code)
+	}
+
+	customerUses, err := countCouponUsesByCustomer(tx, coupon.ID, sale.CustomerID)
+	if err != nil {
+		return err
+	}
+	if err := coupon.CheckRedeemable(time.Now().UTC(), customerUses); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE coupons SET times_used = times_used + 1 WHERE id = $1`, coupon.ID)
+	return err
+}
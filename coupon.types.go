@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,40}$`)
+
+// Coupon is a reusable discount code for sales. A coupon can only be redeemed
+// between StartsAt and EndsAt, at most MaxUses times in total and
+// MaxUsesPerCustomer times by each customer, when those are set.
+type Coupon struct {
+	ID                 string     `json:"id"`
+	Code               string     `json:"code"`
+	Description        *string    `json:"description"`
+	DiscountType       string     `json:"discount_type"`
+	DiscountValue      int        `json:"discount_value"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	MaxUses            *int       `json:"max_uses"`
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer"`
+	TimesUsed          int        `json:"times_used"`
+	IsActive           bool       `json:"is_active"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type CreateCouponRequest struct {
+	Code               string     `json:"code"`
+	Description        *string    `json:"description"`
+	DiscountType       string     `json:"discount_type"`
+	DiscountValue      int        `json:"discount_value"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	MaxUses            *int       `json:"max_uses"`
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer"`
+}
+
+func NewCoupon(
+	code string,
+	description *string,
+	discountType string,
+	discountValue int,
+	startsAt *time.Time,
+	endsAt *time.Time,
+	maxUses *int,
+	maxUsesPerCustomer *int,
+) (*Coupon, error) {
+	coupon := &Coupon{
+		Code:               code,
+		Description:        description,
+		DiscountType:       discountType,
+		DiscountValue:      discountValue,
+		StartsAt:           startsAt,
+		EndsAt:             endsAt,
+		MaxUses:            maxUses,
+		MaxUsesPerCustomer: maxUsesPerCustomer,
+		IsActive:           true,
+	}
+
+	if err := coupon.Validate(); err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate normalizes the code of the coupon and checks its discount, window
+// and limits.
+func (c *Coupon) Validate() error {
+	c.Code = normalizeCouponCode(c.Code)
+	if !couponCodePattern.MatchString(c.Code) {
+		return fmt.Errorf("invalid coupon code [%s], use 3 to 40 letters, numbers, dashes or underscores", c.Code)
+	}
+	if err := validateDiscount(c.DiscountType, c.DiscountValue); err != nil {
+		return err
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	if c.MaxUses != nil && *c.MaxUses <= 0 {
+		return fmt.Errorf("max_uses must be greater than zero")
+	}
+	if c.MaxUsesPerCustomer != nil && *c.MaxUsesPerCustomer <= 0 {
+		return fmt.Errorf("max_uses_per_customer must be greater than zero")
+	}
+	return nil
+}
+
+// CheckRedeemable checks that the coupon can be used at the given time by a
+// customer who already used it customerUses times.
+func (c *Coupon) CheckRedeemable(now time.Time, customerUses int) error {
+	if !c.IsActive {
+		return fmt.Errorf("coupon [%s] is not active", c.Code)
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return fmt.Errorf("coupon [%s] is not valid yet", c.Code)
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return fmt.Errorf("coupon [%s] has expired", c.Code)
+	}
+	if c.MaxUses != nil && c.TimesUsed >= *c.MaxUses {
+		return fmt.Errorf("coupon [%s] has no uses left", c.Code)
+	}
+	if c.MaxUsesPerCustomer != nil && customerUses >= *c.MaxUsesPerCustomer {
+		return fmt.Errorf("coupon [%s] was already used by this customer", c.Code)
+	}
+	return nil
+}
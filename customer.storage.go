@@ -19,8 +19,8 @@ func (s *PostgresStore) CreateCustomersTable() error {
             department VARCHAR(255) NOT NULL,
             comments VARCHAR(255) NOT NULL,
             cc VARCHAR(255) NOT NULL, 
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
         )
     `)
 	if err != nil {
@@ -268,3 +268,27 @@ func (s *PostgresStore) DeleteCustomer(id string) error {
 	}
 	return nil
 }
+
+// GetCustomerByPhone returns the most recent customer with the given phone, or
+// nil when there is none.
+func (s *PostgresStore) GetCustomerByPhone(phone int) (*Customer, error) {
+	rows, err := s.db.Query(`
+		SELECT * FROM customers WHERE phone = $1
+		ORDER BY created_at DESC LIMIT 1`, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCustomers(rows)
+	}
+
+	return nil, nil
+}
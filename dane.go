@@ -0,0 +1,218 @@
+package main
+
+import "strings"
+
+// daneDepartments are the DANE codes of the departments of Colombia, with
+// Bogotá as the Capital District.
+var daneDepartments = map[string]string{
+	"05": "Antioquia",
+	"08": "Atlántico",
+	"11": "Bogotá D.C.",
+	"13": "Bolívar",
+	"15": "Boyacá",
+	"17": "Caldas",
+	"18": "Caquetá",
+	"19": "Cauca",
+	"20": "Cesar",
+	"23": "Córdoba",
+	"25": "Cundinamarca",
+	"27": "Chocó",
+	"41": "Huila",
+	"44": "La Guajira",
+	"47": "Magdalena",
+	"50": "Meta",
+	"52": "Nariño",
+	"54": "Norte de Santander",
+	"63": "Quindío",
+	"66": "Risaralda",
+	"68": "Santander",
+	"70": "Sucre",
+	"73": "Tolima",
+	"76": "Valle del Cauca",
+	"81": "Arauca",
+	"85": "Casanare",
+	"86": "Putumayo",
+	"88": "San Andrés, Providencia y Santa Catalina",
+	"91": "Amazonas",
+	"94": "Guainía",
+	"95": "Guaviare",
+	"97": "Vaupés",
+	"99": "Vichada",
+}
+
+// daneDepartmentAliases are other names customers write for a department.
+var daneDepartmentAliases = map[string]string{
+	"bogota":     "11",
+	"bogota dc":  "11",
+	"valle":      "76",
+	"guajira":    "44",
+	"san andres": "88",
+}
+
+// daneCities are the DANE codes of the department capitals and the larger
+// municipalities, not the full DIVIPOLA list of about 1,100 municipalities.
+// Sales in any other municipality are reported as unmapped. The first two
+// digits are the code of the department.
+var daneCities = map[string]string{
+	"05001": "Medellín",
+	"05045": "Apartadó",
+	"05088": "Bello",
+	"05129": "Caldas",
+	"05148": "El Carmen de Viboral",
+	"05212": "Copacabana",
+	"05266": "Envigado",
+	"05308": "Girardota",
+	"05360": "Itagüí",
+	"05376": "La Ceja",
+	"05380": "La Estrella",
+	"05440": "Marinilla",
+	"05615": "Rionegro",
+	"05631": "Sabaneta",
+	"05837": "Turbo",
+	"08001": "Barranquilla",
+	"08433": "Malambo",
+	"08758": "Soledad",
+	"11001": "Bogotá D.C.",
+	"13001": "Cartagena de Indias",
+	"13430": "Magangué",
+	"15001": "Tunja",
+	"15238": "Duitama",
+	"15759": "Sogamoso",
+	"17001": "Manizales",
+	"17174": "Chinchiná",
+	"17380": "La Dorada",
+	"18001": "Florencia",
+	"19001": "Popayán",
+	"20001": "Valledupar",
+	"20011": "Aguachica",
+	"23001": "Montería",
+	"23417": "Lorica",
+	"23660": "Sahagún",
+	"25126": "Cajicá",
+	"25175": "Chía",
+	"25214": "Cota",
+	"25269": "Facatativá",
+	"25286": "Funza",
+	"25290": "Fusagasugá",
+	"25307": "Girardot",
+	"25377": "La Calera",
+	"25430": "Madrid",
+	"25473": "Mosquera",
+	"25754": "Soacha",
+	"25758": "Sopó",
+	"25817": "Tocancipá",
+	"25899": "Zipaquirá",
+	"27001": "Quibdó",
+	"41001": "Neiva",
+	"41551": "Pitalito",
+	"44001": "Riohacha",
+	"44430": "Maicao",
+	"47001": "Santa Marta",
+	"47189": "Ciénaga",
+	"50001": "Villavicencio",
+	"52001": "Pasto",
+	"52356": "Ipiales",
+	"52835": "Tumaco",
+	"54001": "Cúcuta",
+	"54405": "Los Patios",
+	"54498": "Ocaña",
+	"54874": "Villa del Rosario",
+	"63001": "Armenia",
+	"63130": "Calarcá",
+	"66001": "Pereira",
+	"66170": "Dosquebradas",
+	"66682": "Santa Rosa de Cabal",
+	"68001": "Bucaramanga",
+	"68081": "Barrancabermeja",
+	"68276": "Floridablanca",
+	"68307": "Girón",
+	"68547": "Piedecuesta",
+	"70001": "Sincelejo",
+	"73001": "Ibagué",
+	"73268": "Espinal",
+	"76001": "Cali",
+	"76109": "Buenaventura",
+	"76111": "Guadalajara de Buga",
+	"76147": "Cartago",
+	"76364": "Jamundí",
+	"76520": "Palmira",
+	"76834": "Tuluá",
+	"76892": "Yumbo",
+	"81001": "Arauca",
+	"85001": "Yopal",
+	"86001": "Mocoa",
+	"88001": "San Andrés",
+	"91001": "Leticia",
+	"94001": "Inírida",
+	"95001": "San José del Guaviare",
+	"97001": "Mitú",
+	"99001": "Puerto Carreño",
+}
+
+// daneCityAliases are other names customers write for a city, keyed by the
+// code of its department.
+var daneCityAliases = map[string]string{
+	"11|bogota":             "11001",
+	"11|bogota dc":          "11001",
+	"13|cartagena":          "13001",
+	"76|buga":               "76111",
+	"76|santiago de cali":   "76001",
+	"54|san jose de cucuta": "54001",
+	"68|san juan de giron":  "68307",
+}
+
+var (
+	daneDepartmentsByName = make(map[string]string)
+	daneCitiesByName      = make(map[string]string)
+)
+
+func init() {
+	for code, name := range daneDepartments {
+		daneDepartmentsByName[normalizePlaceName(name)] = code
+	}
+	for name, code := range daneDepartmentAliases {
+		daneDepartmentsByName[name] = code
+	}
+	for code, name := range daneCities {
+		daneCitiesByName[code[:2]+"|"+normalizePlaceName(name)] = code
+	}
+	for key, code := range daneCityAliases {
+		daneCitiesByName[key] = code
+	}
+}
+
+// placeNameReplacer drops the accents and punctuation of place names.
+var placeNameReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	".", "", ",", " ", "-", " ",
+)
+
+// normalizePlaceName lower-cases a place name without accents, punctuation or
+// repeated spaces, so names typed differently match.
+func normalizePlaceName(name string) string {
+	name = placeNameReplacer.Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// daneDepartmentCode is the DANE code of the named department, empty when it
+// is not known.
+func daneDepartmentCode(department string) string {
+	return daneDepartmentsByName[normalizePlaceName(department)]
+}
+
+// daneCityCode is the DANE code of the named city of the department of
+// departmentCode, empty when it is not known. Bogotá is its own department.
+func daneCityCode(departmentCode, city string) string {
+	if departmentCode == "" {
+		return ""
+	}
+	name := normalizePlaceName(city)
+	if code, ok := daneCitiesByName[departmentCode+"|"+name]; ok {
+		return code
+	}
+	// Bogotá is often written under Cundinamarca
+	if departmentCode == "25" {
+		return daneCitiesByName["11|"+name]
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package main
+
+import "fmt"
+
+// Kinds of discount. A percentage discount takes Value percent off, a fixed
+// discount takes Value pesos off.
+const (
+	DiscountTypePercentage = "percentage"
+	DiscountTypeFixed      = "fixed"
+)
+
+// validateDiscount checks the type and value of a discount.
+func validateDiscount(discountType string, value int) error {
+	switch discountType {
+	case DiscountTypePercentage:
+		if value <= 0 || value > 100 {
+			return fmt.Errorf("a percentage discount must be between 1 and 100")
+		}
+	case DiscountTypeFixed:
+		if value <= 0 {
+			return fmt.Errorf("a fixed discount must be greater than zero")
+		}
+	default:
+		return fmt.Errorf("invalid discount type [%s]", discountType)
+	}
+	return nil
+}
+
+// computeDiscount returns how much a discount takes off the given amount. An
+// empty discount type means no discount.
+func computeDiscount(discountType string, value int, amount Money) (Money, error) {
+	if discountType == "" {
+		return NewMoney(0, amount.Currency), nil
+	}
+	if err := validateDiscount(discountType, value); err != nil {
+		return Money{}, err
+	}
+
+	// Discounts are stored in whole pesos, so percentages are rounded down
+	if discountType == DiscountTypePercentage {
+		return amount.Percent(value).TruncateUnits(), nil
+	}
+	discount := MoneyFromUnits(value, amount.Currency)
+	if discount.Cmp(amount) > 0 {
+		return Money{}, fmt.Errorf("a discount of [%s] is greater than the amount [%s]", formatCurrency(discount), formatCurrency(amount))
+	}
+	return discount, nil
+}
@@ -1,11 +1,44 @@
 package main
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+)
 
-func (server *APIServer) handleGetEarnings(w http.ResponseWriter, _ *http.Request) error {
-	earnings, err := server.store.GetEarnings()
+// handleGetEarnings reads the ?granularity= of the periods (month by
+// default), the ?tz= they are computed in (the business time zone by
+// default) and the optional ?from= and ?to= days.
+func (server *APIServer) handleGetEarnings(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	opts := EarningsOptions{
+		PaidOnly:    query.Get("paid_only") == "true",
+		Granularity: query.Get("granularity"),
+	}
+
+	if opts.Granularity == "" {
+		opts.Granularity = GranularityMonth
+	}
+	if !isValidGranularity(opts.Granularity) {
+		return fmt.Errorf("invalid granularity [%s], use day, week, month, quarter or year", opts.Granularity)
+	}
+
+	var err error
+	if opts.Location, err = loadTimeZone(query.Get("tz")); err != nil {
+		return err
+	}
+	if opts.From, err = parseReportDate(query.Get("from"), "from"); err != nil {
+		return err
+	}
+	if opts.To, err = parseReportDate(query.Get("to"), "to"); err != nil {
+		return err
+	}
+	if opts.From != nil && opts.To != nil && opts.To.Before(*opts.From) {
+		return fmt.Errorf("to can't be before from")
+	}
+
+	facts, err := server.store.GetEarningsFacts(opts)
 	if err != nil {
 		return err
 	}
-	return WriteJSON(w, http.StatusOK, earnings)
+	return WriteJSON(w, http.StatusOK, BuildEarnings(facts, opts))
 }
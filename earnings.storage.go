@@ -2,197 +2,71 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
+
+	"github.com/lib/pq"
 )
 
-func (s *PostgresStore) GetEarnings() ([]*Earnings, error) {
-	rows, err := s.db.Query(`
+// dateRangeSQL keeps the rows whose column falls between the days of the
+// from and to params, in the time zone of the tz param. Either day can be
+// NULL.
+func dateRangeSQL(column string, from, to, tz int) string {
+	return fmt.Sprintf(`($%[2]d::DATE IS NULL OR %[1]s >= $%[2]d::DATE::TIMESTAMP AT TIME ZONE $%[4]d)
+			AND ($%[3]d::DATE IS NULL OR %[1]s < ($%[3]d::DATE + 1)::TIMESTAMP AT TIME ZONE $%[4]d)`, column, from, to, tz)
+}
+
+// countedSalesSQL keeps the sales s counted in the earnings, $1 being
+// opts.PaidOnly, $2 the paid statuses, $3 the time zone and $4 and $5 the
+// range.
+var countedSalesSQL = `s.status != 'cancelled' AND (NOT $1 OR s.status = ANY($2))
+			AND ` + dateRangeSQL("s.created_at", 4, 5, 3)
+
+// GetEarningsFacts loads what the earnings are built from. Cancelled sales
+// are never counted, and with opts.PaidOnly neither are the sales waiting for
+// payment.
+func (s *PostgresStore) GetEarningsFacts(opts EarningsOptions) (*EarningsFacts, error) {
+	facts := new(EarningsFacts)
+	var err error
+
+	if facts.Sales, err = s.getEarningsSales(opts); err != nil {
+		return nil, err
+	}
+	if facts.Lines, err = s.getEarningsLines(opts); err != nil {
+		return nil, err
+	}
+	if facts.Expenses, err = s.getEarningsExpenses(opts); err != nil {
+		return nil, err
+	}
 
-	WITH monthly_expenses AS (
+	return facts, nil
+}
+
+// getEarningsSales loads the counted sales with the shipping charged to the
+// customer and paid to carriers.
+func (s *PostgresStore) getEarningsSales(opts EarningsOptions) ([]EarningsSale, error) {
+	rows, err := s.db.Query(`
 		SELECT
-			DATE_TRUNC('month', e.created_at) AS month,
-			e.currency,
-			SUM(e.price) AS total_expense
+			s.id,
+			COALESCE(s.customer_id::TEXT, ''),
+			s.status,
+			s.created_at,
+			COALESCE(s.customer_city, ''),
+			COALESCE(s.customer_department, ''),
+			s.discount_amount,
+			COALESCE(SUM(sh.cost) FILTER (WHERE sh.charge_customer), 0),
+			COALESCE(SUM(sh.cost), 0)
 		FROM
-			expenses e
+			sales s
+		LEFT JOIN
+			shipments sh ON sh.sale_id = s.id
+		WHERE
+			`+countedSalesSQL+`
 		GROUP BY
-			month, e.currency
-	),
-		 all_expenses_in_month AS (
-			 SELECT DISTINCT
-				 e.id,
-				 e.name,
-				 e.price,
-				 e.type,
-				 e.description,
-				 e.currency,
-				 e.created_at,
-				 e.updated_at
-			 FROM
-				 expenses e
-		 ),
-		 monthly_income AS (
-			 SELECT
-				 DATE_TRUNC('month', pv.created_at) AS month,
-				 SUM(pv.price) AS total_income
-			 FROM
-				 product_variations pv
-			 GROUP BY
-				 month
-		 ),
-		 cop_expenses AS (
-			 SELECT
-				 DATE_TRUNC('month', e.created_at) AS month,
-				 SUM(CASE WHEN e.currency = 'COP' THEN e.price ELSE 0 END) AS total_cop_expense
-			 FROM
-				 expenses e
-			 GROUP BY
-				 month
-		 ),
-		 sales_count AS (
-			 SELECT
-				 DATE_TRUNC('month', s.created_at) AS month,
-				 COUNT(*) AS total_sales_in_month
-			 FROM
-				 sales s
-			 GROUP BY
-				 DATE_TRUNC('month', s.created_at)
-		 ),
-		 total_product_variations AS (
-			 SELECT
-				 DATE_TRUNC('month', pv.created_at) AS month,
-				 COUNT(*) AS total_variations
-			 FROM
-				 product_variations pv
-			 GROUP BY
-				 DATE_TRUNC('month', pv.created_at)
-		 ),
-		 total_sales_by_city AS (
-			 SELECT
-				 DATE_TRUNC('month', s.created_at) AS month,
-				 s.customer_city AS city,
-				 COUNT(*) AS sales
-			 FROM
-				 sales s
-			 GROUP BY
-				 DATE_TRUNC('month', s.created_at), s.customer_city
-		 ),
-		 total_sales_by_department AS (
-			 SELECT
-				 DATE_TRUNC('month', s.created_at) AS month,
-				 s.customer_department AS department,
-				 COUNT(*) AS sales
-			 FROM
-				 sales s
-			 GROUP BY
-				 DATE_TRUNC('month', s.created_at), s.customer_department
-		 ),
-	-- Generate a distinct list of months from all sources
-		 distinct_months AS (
-			 SELECT DISTINCT month FROM (
-											SELECT month FROM monthly_expenses
-											UNION
-											SELECT month FROM monthly_income
-											UNION
-											SELECT month FROM cop_expenses
-											UNION
-											SELECT month FROM sales_count
-										) AS all_months
-		 ),
-	-- Aggregate purchased products for each month
-		 purchased_products AS (
-			 SELECT
-				 DATE_TRUNC('month', pv.created_at) AS month,
-				 p.name AS name,
-				 p.id,
-				 pv.color,
-				 pv.price,
-				 p.image,
-				 COUNT(*) AS quantity
-			 FROM
-				 product_variations pv
-					 JOIN
-				 products p ON pv.product_id = p.id
-			 GROUP BY
-				 DATE_TRUNC('month', pv.created_at), p.name, pv.color, pv.price, p.image, p.id
-		 )
-	SELECT
-		dm.month AS sort_by_month,
-		COALESCE(
-				(
-					SELECT JSON_AGG(jsonb_build_object('currency', me.currency, 'value', me.total_expense))
-					FROM (
-							 SELECT DISTINCT ON (currency) currency, total_expense
-							 FROM monthly_expenses
-							 WHERE month = dm.month
-							 ORDER BY currency
-						 ) AS me
-				),
-				'[]'
-		) AS expenses_summary,
-		COALESCE(
-				(
-					SELECT JSON_AGG(jsonb_build_object(
-							'id', aem.id,
-							'name', aem.name,
-							'price', aem.price,
-							'type', aem.type,
-							'description', aem.description,
-							'currency', aem.currency,
-							'created_at', aem.created_at,
-							'updated_at', aem.updated_at
-									))
-					FROM all_expenses_in_month aem
-					WHERE DATE_TRUNC('month', aem.created_at) = dm.month
-				),
-				'[]'
-		) AS all_expenses_in_month,
-		COALESCE(mi.total_income, 0) AS income,
-		COALESCE(ce.total_cop_expense, 0) AS cop_expense,
-		CASE WHEN COALESCE(mi.total_income, 0) - COALESCE(ce.total_cop_expense, 0) < 0 THEN 0
-			 ELSE COALESCE(mi.total_income, 0) - COALESCE(ce.total_cop_expense, 0)
-			END AS earnings,
-		COALESCE(sc.total_sales_in_month, 0) AS total_sales_in_month,
-		COALESCE(tpv.total_variations, 0) AS total_product_variations_in_month,
-		(
-			SELECT JSON_AGG(jsonb_build_object('name', city, 'sales', sales))
-			FROM total_sales_by_city
-			WHERE DATE_TRUNC('month', total_sales_by_city.month) = dm.month
-		) AS cities,
-		(
-			SELECT JSON_AGG(jsonb_build_object('name', department, 'sales', sales))
-			FROM total_sales_by_department
-			WHERE DATE_TRUNC('month', total_sales_by_department.month) = dm.month
-		) AS departments,
-		(
-			SELECT JSON_AGG(jsonb_build_object(
-					'name', pp.name,
-					'id', pp.id,
-					'color', pp.color,
-					'price', pp.price,
-					'image', pp.image,
-					'quantity', pp.quantity
-							))
-			FROM purchased_products pp
-			WHERE DATE_TRUNC('month', pp.month) = dm.month
-		) AS purchased_products
-	FROM
-		distinct_months dm
-			LEFT JOIN
-		monthly_income mi ON dm.month = mi.month
-			LEFT JOIN
-		cop_expenses ce ON dm.month = ce.month
-			LEFT JOIN
-		sales_count sc ON dm.month = sc.month
-			LEFT JOIN
-		total_product_variations tpv ON dm.month = tpv.month
-	ORDER BY
-		dm.month;
-
-
-	`)
+			s.id
+		ORDER BY
+			s.created_at`,
+		opts.PaidOnly, pq.Array(paidSaleStatuses), opts.Location.String(), opts.From, opts.To)
 	if err != nil {
 		return nil, err
 	}
@@ -204,67 +78,184 @@ func (s *PostgresStore) GetEarnings() ([]*Earnings, error) {
 		}
 	}(rows)
 
-	var earnings []*Earnings
+	var sales []EarningsSale
 	for rows.Next() {
-		earning, err := scanIntoEarnings(rows)
+		var sale EarningsSale
+		err := rows.Scan(
+			&sale.ID,
+			&sale.CustomerID,
+			&sale.Status,
+			&sale.CreatedAt,
+			&sale.City,
+			&sale.Department,
+			&sale.Discount,
+			&sale.ShippingCharged,
+			&sale.ShippingCost,
+		)
 		if err != nil {
 			return nil, err
 		}
 
-		earnings = append(earnings, earning)
+		sales = append(sales, sale)
 	}
 
-	return earnings, nil
+	return sales, rows.Err()
 }
 
-func scanIntoEarnings(rows *sql.Rows) (*Earnings, error) {
-	earning := new(Earnings)
-	var expensesSummaryJSON []byte
-	var allExpensesInMonthJSON []byte
-	var citiesJSON []byte
-	var departmentsJSON []byte
-	var purchasedProductsJSON []byte
-	err := rows.Scan(
-		&earning.SortByMonth,
-		&expensesSummaryJSON,
-		&allExpensesInMonthJSON,
-		&earning.Income,
-		&earning.CopExpense,
-		&earning.Earnings,
-		&earning.TotalSalesInMonth,
-		&earning.TotalProductVariationsInMonth,
-		&citiesJSON,
-		&departmentsJSON,
-		&purchasedProductsJSON,
-	)
+// getEarningsLines loads the lines of the counted sales, dated on their sale.
+func (s *PostgresStore) getEarningsLines(opts EarningsOptions) ([]EarningsLine, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			s.id,
+			s.created_at,
+			p.id,
+			p.name,
+			p.image,
+			c.id,
+			c.name,
+			pv.color,
+			pv.price,
+			pv.quantity,
+			pv.discount_amount,
+			pv.unit_cost
+		FROM
+			product_variations pv
+		JOIN
+			sale_products sp ON sp.product_variation_id = pv.id
+		JOIN
+			sales s ON s.id = sp.sale_id
+		JOIN
+			products p ON p.id = pv.product_id
+		LEFT JOIN
+			categories c ON c.id = p.category_id
+		WHERE
+			`+countedSalesSQL+`
+		ORDER BY
+			s.created_at`,
+		opts.PaidOnly, pq.Array(paidSaleStatuses), opts.Location.String(), opts.From, opts.To)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(expensesSummaryJSON, &earning.ExpensesSummary)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling expensesSummaryJSON JSON: %v", err)
-	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
 
-	err = json.Unmarshal(allExpensesInMonthJSON, &earning.AllExpensesInMonth)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling allExpensesInMonthJSON JSON: %v", err)
-	}
+	var lines []EarningsLine
+	for rows.Next() {
+		var line EarningsLine
+		var categoryID, categoryName sql.NullString
+		err := rows.Scan(
+			&line.SaleID,
+			&line.SoldAt,
+			&line.ProductID,
+			&line.ProductName,
+			&line.ProductImage,
+			&categoryID,
+			&categoryName,
+			&line.Color,
+			&line.Price,
+			&line.Quantity,
+			&line.Discount,
+			&line.UnitCost,
+		)
+		if err != nil {
+			return nil, err
+		}
 
-	err = json.Unmarshal(citiesJSON, &earning.Cities)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling citiesJSON JSON: %v", err)
+		line.CategoryID = nullStringToPtr(categoryID)
+		line.CategoryName = nullStringToPtr(categoryName)
+
+		lines = append(lines, line)
 	}
 
-	err = json.Unmarshal(departmentsJSON, &earning.Departments)
+	return lines, rows.Err()
+}
+
+// getEarningsExpenses loads the expenses converted to COP with the latest
+// rate on or before their date in the time zone, rounded to centavos.
+func (s *PostgresStore) getEarningsExpenses(opts EarningsOptions) ([]EarningsExpense, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			e.id,
+			e.name,
+			e.price,
+			e.type,
+			e.description,
+			e.currency,
+			CASE WHEN e.currency = 'COP' THEN e.price ELSE ROUND(e.price * er.rate, 2) END,
+			e.category_id,
+			ec.name,
+			e.created_at,
+			e.updated_at
+		FROM
+			expenses e
+		LEFT JOIN
+			expense_categories ec ON ec.id = e.category_id
+		LEFT JOIN LATERAL (
+			SELECT rate
+			FROM exchange_rates er
+			WHERE er.currency = e.currency AND er.rate_date <= (e.created_at AT TIME ZONE $1)::DATE
+			ORDER BY er.rate_date DESC
+			LIMIT 1
+		) er ON TRUE
+		WHERE
+			`+dateRangeSQL("e.created_at", 2, 3, 1)+`
+		ORDER BY
+			e.created_at`,
+		opts.Location.String(), opts.From, opts.To)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling departmentsJSON JSON: %v", err)
+		return nil, err
 	}
 
-	err = json.Unmarshal(purchasedProductsJSON, &earning.PurchasedProducts)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling purchasedProductsJSON JSON: %v", err)
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var expenses []EarningsExpense
+	for rows.Next() {
+		var expense EarningsExpense
+		var price string
+		var convertedPrice, categoryID, categoryName sql.NullString
+		err := rows.Scan(
+			&expense.ID,
+			&expense.Name,
+			&price,
+			&expense.Type,
+			&expense.Description,
+			&expense.Currency,
+			&convertedPrice,
+			&categoryID,
+			&categoryName,
+			&expense.CreatedAt,
+			&expense.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		expense.Price, err = ParseMoney(price, expense.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if convertedPrice.Valid {
+			converted, err := ParseMoney(convertedPrice.String, baseCurrency)
+			if err != nil {
+				return nil, err
+			}
+			expense.ConvertedPrice = &converted
+		}
+		expense.CategoryID = nullStringToPtr(categoryID)
+		expense.CategoryName = nullStringToPtr(categoryName)
+
+		expenses = append(expenses, expense)
 	}
 
-	return earning, nil
+	return expenses, rows.Err()
 }
@@ -1,10 +1,18 @@
 package main
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
+// ExpensesSummary totals the expenses of a month in one currency. The
+// converted value is in COP and leaves out the WithoutRate expenses that
+// have no exchange rate for their date.
 type ExpensesSummary struct {
-	Currency string  `json:"currency"`
-	Value    float64 `json:"value"`
+	Currency       string `json:"currency"`
+	Value          Money  `json:"value"`
+	ConvertedValue Money  `json:"converted_value"`
+	WithoutRate    int    `json:"without_rate"`
 }
 
 type CitiesSummary struct {
@@ -17,34 +25,505 @@ type DepartmentsSummary struct {
 	Sales int    `json:"sales"`
 }
 
+type CategoriesSummary struct {
+	ID       *string `json:"id"`
+	Name     string  `json:"name"`
+	Income   Money   `json:"income"`
+	Quantity int     `json:"quantity"`
+}
+
+// PurchasedProductsSummary groups the units of a product sold in a color at a
+// price. GrossMargin is what they were charged after line discounts minus
+// their COGS, and MarginPercentage its share of that revenue.
 type PurchasedProductsSummary struct {
-	Name     string `json:"name"`
-	ID       string `json:"id"`
-	Color    string `json:"color"`
-	Price    int    `json:"price"`
-	Image    string `json:"image"`
-	Quantity int    `json:"quantity"`
+	Name             string   `json:"name"`
+	ID               string   `json:"id"`
+	Color            string   `json:"color"`
+	Price            Money    `json:"price"`
+	Image            string   `json:"image"`
+	Quantity         int      `json:"quantity"`
+	COGS             Money    `json:"cogs"`
+	GrossMargin      Money    `json:"gross_margin"`
+	MarginPercentage *float64 `json:"margin_percentage"`
+	UnitsWithoutCost int      `json:"units_without_cost"`
+}
+
+// ExpenseCategorySummary totals the expenses of a month in an expense
+// category, converted to COP. Expenses without a category are grouped under a
+// nil ID.
+type ExpenseCategorySummary struct {
+	ID             *string `json:"id"`
+	Name           string  `json:"name"`
+	ConvertedValue Money   `json:"converted_value"`
+	Expenses       int     `json:"expenses"`
+	WithoutRate    int     `json:"without_rate"`
+}
+
+// EarningsExpense is an expense of the period, converted to COP with the
+// latest exchange rate on or before its date. ConvertedPrice is nil when there
+// is no exchange rate for the expense.
+type EarningsExpense struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Price          Money     `json:"price"`
+	Type           string    `json:"type"`
+	Description    string    `json:"description"`
+	Currency       string    `json:"currency"`
+	ConvertedPrice *Money    `json:"converted_price"`
+	CategoryID     *string   `json:"category_id"`
+	CategoryName   *string   `json:"category_name"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// EarningsSale is a sale with the shipping of its shipments.
+type EarningsSale struct {
+	ID              string
+	CustomerID      string
+	Status          string
+	CreatedAt       time.Time
+	City            string
+	Department      string
+	Discount        Money
+	ShippingCharged Money
+	ShippingCost    Money
 }
 
+// EarningsLine is a line of a sale, dated on its sale.
+type EarningsLine struct {
+	SaleID       string
+	SoldAt       time.Time
+	ProductID    string
+	ProductName  string
+	ProductImage string
+	CategoryID   *string
+	CategoryName *string
+	Color        string
+	Price        Money
+	Quantity     int
+	Discount     Money
+	UnitCost     *Money
+}
+
+// EarningsFacts are the sales, lines and expenses the earnings are built
+// from.
+type EarningsFacts struct {
+	Sales    []EarningsSale
+	Lines    []EarningsLine
+	Expenses []EarningsExpense
+}
+
+// counted are the facts without the sales that don't count with paidOnly,
+// see isCountedSaleStatus, and without their lines.
+func (facts *EarningsFacts) counted(paidOnly bool) *EarningsFacts {
+	counted := &EarningsFacts{Expenses: facts.Expenses}
+	skipped := make(map[string]bool)
+	for _, sale := range facts.Sales {
+		if !isCountedSaleStatus(sale.Status, paidOnly) {
+			skipped[sale.ID] = true
+			continue
+		}
+		counted.Sales = append(counted.Sales, sale)
+	}
+	for _, line := range facts.Lines {
+		if !skipped[line.SaleID] {
+			counted.Lines = append(counted.Lines, line)
+		}
+	}
+	return counted
+}
+
+// Granularities of the earnings periods. Weeks start on Monday.
+const (
+	GranularityDay     = "day"
+	GranularityWeek    = "week"
+	GranularityMonth   = "month"
+	GranularityQuarter = "quarter"
+	GranularityYear    = "year"
+)
+
+// EarningsOptions changes which sales are counted as income and how they are
+// grouped. PaidOnly leaves out the sales that were not paid yet. Periods of
+// Granularity start at midnight in Location, and From and To are inclusive
+// days in it, ignored when nil.
+type EarningsOptions struct {
+	PaidOnly    bool
+	Granularity string
+	Location    *time.Location
+	From        *time.Time
+	To          *time.Time
+}
+
+func isValidGranularity(granularity string) bool {
+	switch granularity {
+	case GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter, GranularityYear:
+		return true
+	}
+	return false
+}
+
+// periodEnd is the start of the period after the one starting at start.
+func periodEnd(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityDay:
+		return start.AddDate(0, 0, 1)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityQuarter:
+		return start.AddDate(0, 3, 0)
+	case GranularityYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// Earnings summarizes a period, from PeriodStart up to PeriodEnd. SortByMonth
+// is the start of the period too, it is kept for older clients.
+//
+// COGS is the unit cost of the units sold, as snapshotted on each sale line,
+// and GrossMargin is the income after discounts minus COGS. UnitsWithoutCost
+// counts the units sold without a unit cost, which are left out of COGS.
+// Earnings doesn't subtract COGS, stock purchases are already counted as
+// expenses.
 type Earnings struct {
-	SortByMonth        time.Time         `json:"sort_by_month"`
-	ExpensesSummary    []ExpensesSummary `json:"expenses_summary"`
-	AllExpensesInMonth []struct {
-		ID          string  `json:"id"`
-		Name        string  `json:"name"`
-		Price       float64 `json:"price"`
-		Type        string  `json:"type"`
-		Description string  `json:"description"`
-		Currency    string  `json:"currency"`
-		CreatedAt   string  `json:"created_at"` // FIXME: fix this
-		UpdatedAt   string  `json:"updated_at"` // FIXME: fix this
-	} `json:"all_expenses_in_month"`
-	Income                        float64                    `json:"income"`
-	CopExpense                    float64                    `json:"cop_expense"`
-	Earnings                      float64                    `json:"earnings"`
+	SortByMonth                   time.Time                  `json:"sort_by_month"`
+	PeriodStart                   time.Time                  `json:"period_start"`
+	PeriodEnd                     time.Time                  `json:"period_end"`
+	ExpensesSummary               []ExpensesSummary          `json:"expenses_summary"`
+	AllExpensesInMonth            []EarningsExpense          `json:"all_expenses_in_month"`
+	Income                        Money                      `json:"income"`
+	CopExpense                    Money                      `json:"cop_expense"`
+	ConvertedExpense              Money                      `json:"converted_expense"`
+	ExpensesWithoutRate           int                        `json:"expenses_without_rate"`
+	ShippingCharged               Money                      `json:"shipping_charged"`
+	ShippingCost                  Money                      `json:"shipping_cost"`
+	Discounts                     Money                      `json:"discounts"`
+	Earnings                      Money                      `json:"earnings"`
+	COGS                          Money                      `json:"cogs"`
+	GrossMargin                   Money                      `json:"gross_margin"`
+	MarginPercentage              *float64                   `json:"margin_percentage"`
+	UnitsWithoutCost              int                        `json:"units_without_cost"`
 	TotalSalesInMonth             int                        `json:"total_sales_in_month"`
 	TotalProductVariationsInMonth int                        `json:"total_product_variations_in_month"`
 	Cities                        []CitiesSummary            `json:"cities"`
 	Departments                   []DepartmentsSummary       `json:"departments"`
+	Categories                    []CategoriesSummary        `json:"categories"`
 	PurchasedProducts             []PurchasedProductsSummary `json:"purchased_products"`
+	ExpenseCategories             []ExpenseCategorySummary   `json:"expense_categories"`
+}
+
+// uncategorizedName groups the products and expenses without a category.
+const uncategorizedName = "Sin categoría"
+
+// periodStart is the start of the period of granularity t falls in, at
+// midnight in loc.
+func periodStart(t time.Time, granularity string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	year, month, day := t.Date()
+	switch granularity {
+	case GranularityDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case GranularityWeek:
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case GranularityQuarter:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, loc)
+	case GranularityYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+}
+
+// earningsPeriod accumulates the facts of a period.
+type earningsPeriod struct {
+	earnings          *Earnings
+	expenseCurrencies map[string]*ExpensesSummary
+	cities            map[string]*CitiesSummary
+	departments       map[string]*DepartmentsSummary
+	categories        map[string]*CategoriesSummary
+	products          map[string]*PurchasedProductsSummary
+	productRevenue    map[string]Money
+	expenseCategories map[string]*ExpenseCategorySummary
+}
+
+func newEarningsPeriod(start time.Time, granularity string) *earningsPeriod {
+	zero := NewMoney(0, baseCurrency)
+	return &earningsPeriod{
+		earnings: &Earnings{
+			SortByMonth:        start,
+			PeriodStart:        start,
+			PeriodEnd:          periodEnd(start, granularity),
+			ExpensesSummary:    []ExpensesSummary{},
+			AllExpensesInMonth: []EarningsExpense{},
+			Income:             zero,
+			CopExpense:         zero,
+			ConvertedExpense:   zero,
+			ShippingCharged:    zero,
+			ShippingCost:       zero,
+			Discounts:          zero,
+			Earnings:           zero,
+			COGS:               zero,
+			GrossMargin:        zero,
+			Cities:             []CitiesSummary{},
+			Departments:        []DepartmentsSummary{},
+			Categories:         []CategoriesSummary{},
+			PurchasedProducts:  []PurchasedProductsSummary{},
+			ExpenseCategories:  []ExpenseCategorySummary{},
+		},
+		expenseCurrencies: make(map[string]*ExpensesSummary),
+		cities:            make(map[string]*CitiesSummary),
+		departments:       make(map[string]*DepartmentsSummary),
+		categories:        make(map[string]*CategoriesSummary),
+		products:          make(map[string]*PurchasedProductsSummary),
+		productRevenue:    make(map[string]Money),
+		expenseCategories: make(map[string]*ExpenseCategorySummary),
+	}
+}
+
+// BuildEarnings groups the facts into the periods of opts, oldest first.
+// Sales and their lines are counted in the period of the sale and expenses in
+// their own period, only periods with a sale or an expense are returned.
+// Cancelled sales, and with opts.PaidOnly the unpaid ones, are left out.
+func BuildEarnings(facts *EarningsFacts, opts EarningsOptions) []*Earnings {
+	facts = facts.counted(opts.PaidOnly)
+	periods := make(map[int64]*earningsPeriod)
+	periodOf := func(t time.Time) *earningsPeriod {
+		start := periodStart(t, opts.Granularity, opts.Location)
+		period, ok := periods[start.Unix()]
+		if !ok {
+			period = newEarningsPeriod(start, opts.Granularity)
+			periods[start.Unix()] = period
+		}
+		return period
+	}
+
+	for _, sale := range facts.Sales {
+		periodOf(sale.CreatedAt).addSale(sale)
+	}
+	for _, line := range facts.Lines {
+		periodOf(line.SoldAt).addLine(line)
+	}
+	for _, expense := range facts.Expenses {
+		periodOf(expense.CreatedAt).addExpense(expense)
+	}
+
+	earnings := make([]*Earnings, 0, len(periods))
+	for _, period := range periods {
+		earnings = append(earnings, period.summarize())
+	}
+	sort.Slice(earnings, func(i, j int) bool {
+		return earnings[i].PeriodStart.Before(earnings[j].PeriodStart)
+	})
+
+	return earnings
+}
+
+func (p *earningsPeriod) addSale(sale EarningsSale) {
+	e := p.earnings
+	e.TotalSalesInMonth++
+	e.Discounts = e.Discounts.Add(sale.Discount)
+	e.ShippingCharged = e.ShippingCharged.Add(sale.ShippingCharged)
+	e.ShippingCost = e.ShippingCost.Add(sale.ShippingCost)
+
+	city, ok := p.cities[sale.City]
+	if !ok {
+		city = &CitiesSummary{Name: sale.City}
+		p.cities[sale.City] = city
+	}
+	city.Sales++
+
+	department, ok := p.departments[sale.Department]
+	if !ok {
+		department = &DepartmentsSummary{Name: sale.Department}
+		p.departments[sale.Department] = department
+	}
+	department.Sales++
+}
+
+func (p *earningsPeriod) addLine(line EarningsLine) {
+	e := p.earnings
+	gross := line.Price.Mul(line.Quantity)
+	e.Income = e.Income.Add(gross)
+	e.Discounts = e.Discounts.Add(line.Discount)
+	e.TotalProductVariationsInMonth += line.Quantity
+
+	var cogs Money
+	if line.UnitCost != nil {
+		cogs = line.UnitCost.Mul(line.Quantity)
+		e.COGS = e.COGS.Add(cogs)
+	} else {
+		cogs = NewMoney(0, baseCurrency)
+		e.UnitsWithoutCost += line.Quantity
+	}
+
+	categoryKey := ""
+	if line.CategoryID != nil {
+		categoryKey = *line.CategoryID
+	}
+	category, ok := p.categories[categoryKey]
+	if !ok {
+		category = &CategoriesSummary{ID: line.CategoryID, Name: uncategorizedName, Income: NewMoney(0, baseCurrency)}
+		if line.CategoryName != nil {
+			category.Name = *line.CategoryName
+		}
+		p.categories[categoryKey] = category
+	}
+	category.Income = category.Income.Add(gross)
+	category.Quantity += line.Quantity
+
+	productKey := line.ProductID + "|" + line.Color + "|" + line.Price.String()
+	product, ok := p.products[productKey]
+	if !ok {
+		product = &PurchasedProductsSummary{
+			Name:  line.ProductName,
+			ID:    line.ProductID,
+			Color: line.Color,
+			Price: line.Price,
+			Image: line.ProductImage,
+			COGS:  NewMoney(0, baseCurrency),
+		}
+		p.products[productKey] = product
+		p.productRevenue[productKey] = NewMoney(0, baseCurrency)
+	}
+	product.Quantity += line.Quantity
+	product.COGS = product.COGS.Add(cogs)
+	if line.UnitCost == nil {
+		product.UnitsWithoutCost += line.Quantity
+	}
+	p.productRevenue[productKey] = p.productRevenue[productKey].Add(gross.Sub(line.Discount))
+}
+
+func (p *earningsPeriod) addExpense(expense EarningsExpense) {
+	e := p.earnings
+	e.AllExpensesInMonth = append(e.AllExpensesInMonth, expense)
+
+	summary, ok := p.expenseCurrencies[expense.Currency]
+	if !ok {
+		summary = &ExpensesSummary{
+			Currency:       expense.Currency,
+			Value:          NewMoney(0, expense.Currency),
+			ConvertedValue: NewMoney(0, baseCurrency),
+		}
+		p.expenseCurrencies[expense.Currency] = summary
+	}
+	summary.Value = summary.Value.Add(expense.Price)
+
+	if expense.Currency == baseCurrency {
+		e.CopExpense = e.CopExpense.Add(expense.Price)
+	}
+
+	categoryKey := ""
+	if expense.CategoryID != nil {
+		categoryKey = *expense.CategoryID
+	}
+	category, ok := p.expenseCategories[categoryKey]
+	if !ok {
+		category = &ExpenseCategorySummary{ID: expense.CategoryID, Name: uncategorizedName, ConvertedValue: NewMoney(0, baseCurrency)}
+		if expense.CategoryName != nil {
+			category.Name = *expense.CategoryName
+		}
+		p.expenseCategories[categoryKey] = category
+	}
+	category.Expenses++
+
+	if expense.ConvertedPrice == nil {
+		summary.WithoutRate++
+		category.WithoutRate++
+		e.ExpensesWithoutRate++
+		return
+	}
+	summary.ConvertedValue = summary.ConvertedValue.Add(*expense.ConvertedPrice)
+	category.ConvertedValue = category.ConvertedValue.Add(*expense.ConvertedPrice)
+	e.ConvertedExpense = e.ConvertedExpense.Add(*expense.ConvertedPrice)
+}
+
+// netEarnings are the income after discounts and the shipping charged, minus
+// the expenses and the shipping absorbed. They are negative when the period
+// lost money.
+func (p *earningsPeriod) netEarnings() Money {
+	e := p.earnings
+	return e.Income.Sub(e.Discounts).Add(e.ShippingCharged).Sub(e.ConvertedExpense).Sub(e.ShippingCost)
+}
+
+// summarize computes the totals of the period and sorts its breakdowns.
+func (p *earningsPeriod) summarize() *Earnings {
+	e := p.earnings
+
+	netIncome := e.Income.Sub(e.Discounts)
+	e.GrossMargin = netIncome.Sub(e.COGS)
+	e.MarginPercentage = marginPercentage(e.GrossMargin, netIncome)
+
+	// The earnings of a period never go below zero
+	e.Earnings = p.netEarnings()
+	if e.Earnings.IsNegative() {
+		e.Earnings = NewMoney(0, baseCurrency)
+	}
+
+	for _, summary := range p.expenseCurrencies {
+		e.ExpensesSummary = append(e.ExpensesSummary, *summary)
+	}
+	sort.Slice(e.ExpensesSummary, func(i, j int) bool {
+		return e.ExpensesSummary[i].Currency < e.ExpensesSummary[j].Currency
+	})
+
+	for _, city := range p.cities {
+		e.Cities = append(e.Cities, *city)
+	}
+	sort.Slice(e.Cities, func(i, j int) bool {
+		if e.Cities[i].Sales != e.Cities[j].Sales {
+			return e.Cities[i].Sales > e.Cities[j].Sales
+		}
+		return e.Cities[i].Name < e.Cities[j].Name
+	})
+
+	for _, department := range p.departments {
+		e.Departments = append(e.Departments, *department)
+	}
+	sort.Slice(e.Departments, func(i, j int) bool {
+		if e.Departments[i].Sales != e.Departments[j].Sales {
+			return e.Departments[i].Sales > e.Departments[j].Sales
+		}
+		return e.Departments[i].Name < e.Departments[j].Name
+	})
+
+	for _, category := range p.categories {
+		e.Categories = append(e.Categories, *category)
+	}
+	sort.Slice(e.Categories, func(i, j int) bool {
+		if cmp := e.Categories[i].Income.Cmp(e.Categories[j].Income); cmp != 0 {
+			return cmp > 0
+		}
+		return e.Categories[i].Name < e.Categories[j].Name
+	})
+
+	for key, product := range p.products {
+		revenue := p.productRevenue[key]
+		product.GrossMargin = revenue.Sub(product.COGS)
+		product.MarginPercentage = marginPercentage(product.GrossMargin, revenue)
+		e.PurchasedProducts = append(e.PurchasedProducts, *product)
+	}
+	sort.Slice(e.PurchasedProducts, func(i, j int) bool {
+		a, b := e.PurchasedProducts[i], e.PurchasedProducts[j]
+		if a.Quantity != b.Quantity {
+			return a.Quantity > b.Quantity
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Color != b.Color {
+			return a.Color < b.Color
+		}
+		return a.Price.Cmp(b.Price) < 0
+	})
+
+	for _, category := range p.expenseCategories {
+		e.ExpenseCategories = append(e.ExpenseCategories, *category)
+	}
+	sort.Slice(e.ExpenseCategories, func(i, j int) bool {
+		if cmp := e.ExpenseCategories[i].ConvertedValue.Cmp(e.ExpenseCategories[j].ConvertedValue); cmp != 0 {
+			return cmp > 0
+		}
+		return e.ExpenseCategories[i].Name < e.ExpenseCategories[j].Name
+	})
+
+	return e
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func cop(pesos int) Money {
+	return MoneyFromUnits(pesos, baseCurrency)
+}
+
+func copPtr(pesos int) *Money {
+	amount := cop(pesos)
+	return &amount
+}
+
+// earningsFixture has sales of every status around the end of February 2026,
+// with sale and line discounts, shipping, costed and uncosted lines and
+// expenses in COP and USD.
+func earningsFixture() *EarningsFacts {
+	return &EarningsFacts{
+		Sales: []EarningsSale{
+			// Tuesday 10 February in Bogotá
+			{ID: "s1", Status: SaleStatusPaid, CreatedAt: time.Date(2026, 2, 10, 15, 0, 0, 0, time.UTC),
+				Discount: cop(5000), ShippingCharged: cop(10000), ShippingCost: cop(12000)},
+			{ID: "s2", Status: SaleStatusCancelled, CreatedAt: time.Date(2026, 2, 12, 15, 0, 0, 0, time.UTC),
+				Discount: cop(0), ShippingCharged: cop(0), ShippingCost: cop(0)},
+			// Saturday 28 February at 22:00 in Bogotá, already March in UTC
+			{ID: "s3", Status: SaleStatusPending, CreatedAt: time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC),
+				Discount: cop(0), ShippingCharged: cop(0), ShippingCost: cop(0)},
+			// Monday 2 March in Bogotá
+			{ID: "s4", Status: SaleStatusDelivered, CreatedAt: time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC),
+				Discount: cop(0), ShippingCharged: cop(0), ShippingCost: cop(0)},
+		},
+		Lines: []EarningsLine{
+			{SaleID: "s1", SoldAt: time.Date(2026, 2, 10, 15, 0, 0, 0, time.UTC), ProductID: "p1",
+				Price: cop(50000), Quantity: 2, Discount: cop(10000), UnitCost: copPtr(20000)},
+			{SaleID: "s2", SoldAt: time.Date(2026, 2, 12, 15, 0, 0, 0, time.UTC), ProductID: "p1",
+				Price: cop(80000), Quantity: 1, Discount: cop(0), UnitCost: copPtr(20000)},
+			{SaleID: "s3", SoldAt: time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC), ProductID: "p2",
+				Price: cop(30000), Quantity: 1, Discount: cop(0)},
+			{SaleID: "s4", SoldAt: time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC), ProductID: "p3",
+				Price: cop(40000), Quantity: 1, Discount: cop(0), UnitCost: copPtr(15000)},
+		},
+		Expenses: []EarningsExpense{
+			{ID: "e1", Currency: baseCurrency, Price: cop(20000), ConvertedPrice: copPtr(20000),
+				CreatedAt: time.Date(2026, 2, 11, 15, 0, 0, 0, time.UTC)},
+			{ID: "e2", Currency: "USD", Price: NewMoney(500, "USD"),
+				CreatedAt: time.Date(2026, 2, 20, 15, 0, 0, 0, time.UTC)},
+			{ID: "e3", Currency: "USD", Price: NewMoney(1250, "USD"), ConvertedPrice: copPtr(50000),
+				CreatedAt: time.Date(2026, 3, 5, 15, 0, 0, 0, time.UTC)},
+		},
+	}
+}
+
+// earningsWant are the figures expected of a period, amounts in pesos.
+type earningsWant struct {
+	start            string
+	sales            int
+	units            int
+	income           int
+	discounts        int
+	convertedExpense int
+	withoutRate      int
+	cogs             int
+	grossMargin      int
+	earnings         int
+}
+
+func TestBuildEarnings(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts EarningsOptions
+		want []earningsWant
+	}{
+		{
+			name: "months leave out cancelled sales",
+			opts: EarningsOptions{Granularity: GranularityMonth, Location: bogota},
+			want: []earningsWant{
+				// 130000 - 15000 discounts + 10000 shipping charged - 20000 expenses - 12000 shipping cost
+				{start: "2026-02-01", sales: 2, units: 3, income: 130000, discounts: 15000,
+					convertedExpense: 20000, withoutRate: 1, cogs: 40000, grossMargin: 75000, earnings: 93000},
+				// 40000 - 50000 expenses never goes below zero
+				{start: "2026-03-01", sales: 1, units: 1, income: 40000,
+					convertedExpense: 50000, cogs: 15000, grossMargin: 25000, earnings: 0},
+			},
+		},
+		{
+			name: "paid only leaves out pending sales",
+			opts: EarningsOptions{Granularity: GranularityMonth, Location: bogota, PaidOnly: true},
+			want: []earningsWant{
+				{start: "2026-02-01", sales: 1, units: 2, income: 100000, discounts: 15000,
+					convertedExpense: 20000, withoutRate: 1, cogs: 40000, grossMargin: 45000, earnings: 63000},
+				{start: "2026-03-01", sales: 1, units: 1, income: 40000,
+					convertedExpense: 50000, cogs: 15000, grossMargin: 25000, earnings: 0},
+			},
+		},
+		{
+			name: "months in UTC move the late sale to March",
+			opts: EarningsOptions{Granularity: GranularityMonth, Location: time.UTC},
+			want: []earningsWant{
+				{start: "2026-02-01", sales: 1, units: 2, income: 100000, discounts: 15000,
+					convertedExpense: 20000, withoutRate: 1, cogs: 40000, grossMargin: 45000, earnings: 63000},
+				{start: "2026-03-01", sales: 2, units: 2, income: 70000,
+					convertedExpense: 50000, cogs: 15000, grossMargin: 55000, earnings: 20000},
+			},
+		},
+		{
+			name: "weeks start on Monday",
+			opts: EarningsOptions{Granularity: GranularityWeek, Location: bogota},
+			want: []earningsWant{
+				{start: "2026-02-09", sales: 1, units: 2, income: 100000, discounts: 15000,
+					convertedExpense: 20000, cogs: 40000, grossMargin: 45000, earnings: 63000},
+				{start: "2026-02-16", withoutRate: 1},
+				{start: "2026-02-23", sales: 1, units: 1, income: 30000, grossMargin: 30000, earnings: 30000},
+				{start: "2026-03-02", sales: 1, units: 1, income: 40000,
+					convertedExpense: 50000, cogs: 15000, grossMargin: 25000, earnings: 0},
+			},
+		},
+		{
+			name: "days keep the late sale on its local day",
+			opts: EarningsOptions{Granularity: GranularityDay, Location: bogota},
+			want: []earningsWant{
+				{start: "2026-02-10", sales: 1, units: 2, income: 100000, discounts: 15000,
+					cogs: 40000, grossMargin: 45000, earnings: 83000},
+				{start: "2026-02-11", convertedExpense: 20000, earnings: 0},
+				{start: "2026-02-20", withoutRate: 1},
+				{start: "2026-02-28", sales: 1, units: 1, income: 30000, grossMargin: 30000, earnings: 30000},
+				{start: "2026-03-02", sales: 1, units: 1, income: 40000, cogs: 15000, grossMargin: 25000, earnings: 40000},
+				{start: "2026-03-05", convertedExpense: 50000, earnings: 0},
+			},
+		},
+		{
+			name: "years",
+			opts: EarningsOptions{Granularity: GranularityYear, Location: bogota},
+			want: []earningsWant{
+				{start: "2026-01-01", sales: 3, units: 4, income: 170000, discounts: 15000,
+					convertedExpense: 70000, withoutRate: 1, cogs: 55000, grossMargin: 100000, earnings: 83000},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildEarnings(earningsFixture(), tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d periods, want %d", len(got), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				e := got[i]
+				start, err := time.ParseInLocation("2006-01-02", want.start, tt.opts.Location)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !e.PeriodStart.Equal(start) {
+					t.Fatalf("period %d starts at %s, want %s", i, e.PeriodStart, start)
+				}
+
+				checks := []struct {
+					field     string
+					got, want any
+				}{
+					{"sales", e.TotalSalesInMonth, want.sales},
+					{"units", e.TotalProductVariationsInMonth, want.units},
+					{"income", e.Income, cop(want.income)},
+					{"discounts", e.Discounts, cop(want.discounts)},
+					{"converted expense", e.ConvertedExpense, cop(want.convertedExpense)},
+					{"expenses without rate", e.ExpensesWithoutRate, want.withoutRate},
+					{"cogs", e.COGS, cop(want.cogs)},
+					{"gross margin", e.GrossMargin, cop(want.grossMargin)},
+					{"earnings", e.Earnings, cop(want.earnings)},
+				}
+				for _, check := range checks {
+					if check.got != check.want {
+						t.Errorf("%s: %s is %v, want %v", want.start, check.field, check.got, check.want)
+					}
+				}
+			}
+		})
+	}
+}
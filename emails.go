@@ -23,7 +23,7 @@ func SendSaleEmail(sale *SaleWithProducts, s *PostgresStore, customer *Customer,
 	sdSender := os.Getenv("SENDGRID_CUSTOM_SENDER")
 
 	from := mail.NewEmail("Dashboard API", sdSender)
-	subject := fmt.Sprintf("🛍️ Nueva venta para %v", customer.Name)
+	subject := fmt.Sprintf("🛍️ Nueva venta %s para %v", sale.OrderNumber, customer.Name)
 	plainTextContent := "Se ha generado una nueva venta"
 
 	htmlContent := fmt.Sprintf(`
@@ -53,9 +53,7 @@ func SendSaleEmail(sale *SaleWithProducts, s *PostgresStore, customer *Customer,
 		customer.Name,
 	)
 
-	var totalEarningsHTML int
 	for _, pv := range sale.Products {
-		totalEarningsHTML += pv.Price
 		bgColor, textColor := ColorFromLocalConstants(pv.Color)
 		htmlContent += fmt.Sprintf(`
 		  <tr>
@@ -66,7 +64,7 @@ func SendSaleEmail(sale *SaleWithProducts, s *PostgresStore, customer *Customer,
 			<img src="%s" alt="%s" width="42" height="42" style="border-radius: 50%%; display: block; margin-right: 6px;">
 			</td>
 			<td style="width: 70%%;">
-			<p style="margin: 0 0 10px;">%s</p>
+			<p style="margin: 0 0 10px;">%s x%d</p>
 			<span style="background-color: %s; color: %s; padding: 5px 10px; border-radius: 5px; font-size: 14px;">%s</span>
 			</td>
 			<td style="width: 20%%;">
@@ -82,10 +80,11 @@ func SendSaleEmail(sale *SaleWithProducts, s *PostgresStore, customer *Customer,
 			findProductVariation(products, pv.ProductID).Image,
 			findProductVariation(products, pv.ProductID).Name,
 			findProductVariation(products, pv.ProductID).Name,
+			pv.Quantity,
 			bgColor,
 			textColor,
 			pv.Color,
-			formatCurrency(pv.Price, "COP"),
+			formatCurrency(pv.LineTotal()),
 		)
 	}
 
@@ -112,7 +111,7 @@ func SendSaleEmail(sale *SaleWithProducts, s *PostgresStore, customer *Customer,
 		</body>
 	</html>	
 	`,
-		formatCurrency(totalEarningsHTML, "COP"),
+		formatCurrency(sale.Total()),
 		customer.Name,
 		customer.Address,
 		customer.City,
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func (server *APIServer) handleCreateExchangeRate(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateExchangeRateRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	rate, err := NewExchangeRate(req.Currency, req.Date, req.Rate, ExchangeRateSourceManual)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.SaveExchangeRates([]*ExchangeRate{rate}); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, rate)
+}
+
+func (server *APIServer) handleGetExchangeRates(w http.ResponseWriter, r *http.Request) error {
+	currency := r.URL.Query().Get("currency")
+	if currency != "" {
+		var err error
+		if currency, err = normalizeCurrency(currency); err != nil {
+			return err
+		}
+	}
+
+	rates, err := server.store.GetExchangeRates(currency)
+	if err != nil {
+		return err
+	}
+	if rates == nil {
+		rates = []*ExchangeRate{}
+	}
+
+	return WriteJSON(w, http.StatusOK, rates)
+}
+
+// handleImportExchangeRates loads a CSV file of rates with the columns
+// currency, date and rate, e.g. "USD,2024-05-01,3890.50". A header row is
+// skipped. Nothing is saved when a row is invalid.
+func (server *APIServer) handleImportExchangeRates(w http.ResponseWriter, r *http.Request) error {
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxExchangeRatesImportSize))
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []*ExchangeRate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "currency") {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid rate [%s]", line, record[2])
+		}
+
+		rate, err := NewExchangeRate(record[0], strings.TrimSpace(record[1]), value, ExchangeRateSourceImport)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if len(rates) == 0 {
+		return fmt.Errorf("the file has no exchange rates")
+	}
+
+	if err := server.store.SaveExchangeRates(rates); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]int{"imported": len(rates)})
+}
+
+func (server *APIServer) handleDeleteExchangeRate(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	currency, err := normalizeCurrency(vars["currency"])
+	if err != nil {
+		return err
+	}
+
+	date, err := time.Parse(time.DateOnly, vars["date"])
+	if err != nil {
+		return fmt.Errorf("invalid date [%s], use YYYY-MM-DD", vars["date"])
+	}
+
+	if err := server.store.DeleteExchangeRate(currency, date); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": currency + " " + vars["date"]})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (s *PostgresStore) CreateExchangeRatesTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS exchange_rates (
+            currency CHAR(3) NOT NULL,
+            rate_date DATE NOT NULL,
+            rate NUMERIC(20, 6) NOT NULL CHECK (rate > 0),
+            source VARCHAR(20) NOT NULL DEFAULT 'manual',
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (currency, rate_date)
+        );
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("exchange_rates")
+}
+
+// SaveExchangeRates stores the given rates, replacing the rates of the same
+// currency and date. Either every rate is saved or none is.
+func (s *PostgresStore) SaveExchangeRates(rates []*ExchangeRate) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, rate := range rates {
+			_, err := tx.Exec(`
+				INSERT INTO exchange_rates (currency, rate_date, rate, source)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (currency, rate_date)
+				DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source`,
+				rate.Currency, rate.Date, rate.Rate, rate.Source)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetExchangeRates lists the rates of a currency, or of every currency when
+// currency is empty, newest first.
+func (s *PostgresStore) GetExchangeRates(currency string) ([]*ExchangeRate, error) {
+	rows, err := s.db.Query(`
+		SELECT currency, rate_date, rate, source, created_at, updated_at
+		FROM exchange_rates
+		WHERE $1 = '' OR currency = $1
+		ORDER BY rate_date DESC, currency`, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var rates []*ExchangeRate
+	for rows.Next() {
+		rate := new(ExchangeRate)
+		err := rows.Scan(
+			&rate.Currency,
+			&rate.Date,
+			&rate.Rate,
+			&rate.Source,
+			&rate.CreatedAt,
+			&rate.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+func (s *PostgresStore) DeleteExchangeRate(currency string, date time.Time) error {
+	result, err := s.db.Exec(
+		"DELETE FROM exchange_rates WHERE currency = $1 AND rate_date = $2", currency, date)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("exchange rate [%s %s] not found", currency, date.Format(time.DateOnly))
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExchangeRate is how many COP one unit of Currency was worth on Date.
+// Amounts are converted with the latest rate on or before their date.
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Date      time.Time `json:"date"`
+	Rate      float64   `json:"rate"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateExchangeRateRequest takes the date as YYYY-MM-DD.
+type CreateExchangeRateRequest struct {
+	Currency string  `json:"currency"`
+	Date     string  `json:"date"`
+	Rate     float64 `json:"rate"`
+}
+
+// Sources of exchange rates.
+const (
+	ExchangeRateSourceManual = "manual"
+	ExchangeRateSourceImport = "import"
+)
+
+// maxExchangeRatesImportSize limits the size of an imported rates file.
+const maxExchangeRatesImportSize = 1 << 20
+
+func NewExchangeRate(currency, date string, rate float64, source string) (*ExchangeRate, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if currency == baseCurrency {
+		return nil, fmt.Errorf("rates are given in %s, %s doesn't need one", baseCurrency, baseCurrency)
+	}
+
+	parsedDate, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date [%s], use YYYY-MM-DD", date)
+	}
+
+	if rate <= 0 {
+		return nil, fmt.Errorf("rate must be greater than zero")
+	}
+
+	return &ExchangeRate{
+		Currency: currency,
+		Date:     parsedDate,
+		Rate:     rate,
+		Source:   source,
+	}, nil
+}
@@ -13,15 +13,29 @@ func (server *APIServer) handleCreateExpense(w http.ResponseWriter, r *http.Requ
 
 	expense, err := NewExpense(
 		req.Name,
-		req.Price,
+		req.Price.String(),
 		req.Type,
 		req.Description,
 		req.Currency,
+		req.CategoryID,
+		req.VendorID,
 	)
 	if err != nil {
 		return err
 	}
 
+	if err := server.validateExpenseRelations(expense.CategoryID, expense.VendorID, &expense.Type); err != nil {
+		return err
+	}
+
+	if req.Receipt != nil && *req.Receipt != "" {
+		receiptURL, err := BucketBasics.UploadPrivateFile(BucketBasics{S3Client: server.s3Client}, *req.Receipt)
+		if err != nil {
+			return err
+		}
+		expense.ReceiptURL = &receiptURL
+	}
+
 	err = server.store.CreateExpense(expense)
 	if err != nil {
 		return err
@@ -32,15 +46,26 @@ func (server *APIServer) handleCreateExpense(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		return err
 	}
+	if err := server.presignExpenseReceipts(createdExpense); err != nil {
+		return err
+	}
 
 	return WriteJSON(w, http.StatusOK, createdExpense)
 }
 
-func (server *APIServer) handleGetExpenses(w http.ResponseWriter, _ *http.Request) error {
-	expenses, err := server.store.GetExpenses()
+func (server *APIServer) handleGetExpenses(w http.ResponseWriter, r *http.Request) error {
+	filter := ExpenseFilter{
+		CategoryID: r.URL.Query().Get("category_id"),
+		VendorID:   r.URL.Query().Get("vendor_id"),
+	}
+
+	expenses, err := server.store.GetExpenses(filter)
 	if err != nil {
 		return err
 	}
+	if err := server.presignExpenseReceipts(expenses...); err != nil {
+		return err
+	}
 	return WriteJSON(w, http.StatusOK, expenses)
 }
 
@@ -54,6 +79,9 @@ func (server *APIServer) handleGetExpenseByID(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		return err
 	}
+	if err := server.presignExpenseReceipts(expense); err != nil {
+		return err
+	}
 
 	return WriteJSON(w, http.StatusOK, expense)
 }
@@ -64,19 +92,54 @@ func (server *APIServer) handleUpdateExpense(w http.ResponseWriter, r *http.Requ
 		return err
 	}
 
-	_, err = server.store.GetExpenseByID(id)
+	oldExpense, err := server.store.GetExpenseByID(id)
 	if err != nil {
 		return err
 	}
 
-	var expense Expense
-	if err := json.NewDecoder(r.Body).Decode(&expense); err != nil {
+	req := new(CreateExpenseRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	expense, err := NewExpense(
+		req.Name,
+		req.Price.String(),
+		req.Type,
+		req.Description,
+		req.Currency,
+		req.CategoryID,
+		req.VendorID,
+	)
+	if err != nil {
 		return err
 	}
 
 	expense.ID = id
+	if err := server.validateExpenseRelations(expense.CategoryID, expense.VendorID, &expense.Type); err != nil {
+		return err
+	}
 
-	if err := server.store.UpdateExpense(&expense); err != nil {
+	// A new receipt replaces the old file, an empty one removes it
+	expense.ReceiptURL = oldExpense.ReceiptURL
+	if req.Receipt != nil {
+		if oldExpense.ReceiptURL != nil {
+			err = BucketBasics.DeleteFile(BucketBasics{S3Client: server.s3Client}, *oldExpense.ReceiptURL)
+			if err != nil {
+				return err
+			}
+			expense.ReceiptURL = nil
+		}
+		if *req.Receipt != "" {
+			receiptURL, err := BucketBasics.UploadPrivateFile(BucketBasics{S3Client: server.s3Client}, *req.Receipt)
+			if err != nil {
+				return err
+			}
+			expense.ReceiptURL = &receiptURL
+		}
+	}
+
+	if err := server.store.UpdateExpense(expense); err != nil {
 		return err
 	}
 
@@ -85,6 +148,9 @@ func (server *APIServer) handleUpdateExpense(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		return err
 	}
+	if err := server.presignExpenseReceipts(updatedExpense); err != nil {
+		return err
+	}
 
 	return WriteJSON(w, http.StatusOK, updatedExpense)
 }
@@ -95,14 +161,59 @@ func (server *APIServer) handleDeleteExpense(w http.ResponseWriter, r *http.Requ
 		return err
 	}
 
-	_, err = server.store.GetExpenseByID(id)
+	expense, err := server.store.GetExpenseByID(id)
 	if err != nil {
 		return err
 	}
 
+	if expense.ReceiptURL != nil {
+		err = BucketBasics.DeleteFile(BucketBasics{S3Client: server.s3Client}, *expense.ReceiptURL)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err := server.store.DeleteExpense(id); err != nil {
 		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
 }
+
+// validateExpenseRelations checks the category and the vendor of an expense
+// exist. An empty expense type takes the name of the category.
+func (server *APIServer) validateExpenseRelations(categoryID, vendorID *string, expenseType *string) error {
+	if categoryID != nil {
+		category, err := server.store.GetExpenseCategoryByID(*categoryID)
+		if err != nil {
+			return err
+		}
+		if *expenseType == "" {
+			*expenseType = category.Name
+		}
+	}
+
+	if vendorID != nil {
+		if _, err := server.store.GetVendorByID(*vendorID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// presignExpenseReceipts replaces the receipt URLs of the expenses with
+// temporary links, receipts are not publicly readable.
+func (server *APIServer) presignExpenseReceipts(expenses ...*Expense) error {
+	for _, expense := range expenses {
+		if expense.ReceiptURL == nil {
+			continue
+		}
+		receiptURL, err := BucketBasics.PresignURL(BucketBasics{S3Client: server.s3Client}, *expense.ReceiptURL)
+		if err != nil {
+			return err
+		}
+		expense.ReceiptURL = &receiptURL
+	}
+	return nil
+}
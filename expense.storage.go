@@ -12,18 +12,46 @@ func (s *PostgresStore) CreateExpensesTable() error {
         CREATE TABLE IF NOT EXISTS expenses (
             id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
             name VARCHAR(255) NOT NULL,
-            price DECIMAL(15, 2) NOT NULL,
+            price NUMERIC(18, 3) NOT NULL,
             type VARCHAR(255) NOT NULL,
             description VARCHAR(255) NOT NULL,
             currency VARCHAR(255) NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
         )
     `)
 	if err != nil {
 		return err
 	}
 
+	// Prices used to have two decimals, currencies such as KWD have three
+	_, err = s.db.Exec(`
+		DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_name = 'expenses' AND column_name = 'price' AND numeric_scale != 3
+			) THEN
+				ALTER TABLE expenses ALTER COLUMN price TYPE NUMERIC(18, 3);
+			END IF;
+		END $$;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Managed categories, vendors and the scanned supplier invoice
+	_, err = s.db.Exec(`
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL;
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL;
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS receipt_url TEXT;
+		CREATE INDEX IF NOT EXISTS expenses_category_id_idx ON expenses (category_id);
+		CREATE INDEX IF NOT EXISTS expenses_vendor_id_idx ON expenses (vendor_id);
+	`)
+	if err != nil {
+		return err
+	}
+
 	// Check if the trigger already exists
 	var triggerExists bool
 	err = s.db.QueryRow(`
@@ -102,10 +130,13 @@ func (s *PostgresStore) CreateExpense(expense *Expense) error {
 			type, 
 			description, 
 			currency, 
+			category_id,
+			vendor_id,
+			receipt_url,
 			created_at, 
 			updated_at 
         ) 
-        VALUES ($1, $2, $3, $4, $5, $6, $7) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
         RETURNING id
     `
 
@@ -117,6 +148,9 @@ func (s *PostgresStore) CreateExpense(expense *Expense) error {
 		expense.Type,
 		expense.Description,
 		expense.Currency,
+		expense.CategoryID,
+		expense.VendorID,
+		expense.ReceiptURL,
 		expense.CreatedAt,
 		expense.UpdatedAt,
 	).Scan(&id)
@@ -130,8 +164,32 @@ func (s *PostgresStore) CreateExpense(expense *Expense) error {
 	return nil
 }
 
+// expensesQuery selects expenses with the names of their category and vendor.
+const expensesQuery = `
+	SELECT
+		e.id,
+		e.name,
+		e.price,
+		e.type,
+		e.description,
+		e.currency,
+		e.category_id,
+		ec.name,
+		e.vendor_id,
+		v.name,
+		e.receipt_url,
+		e.recurring_expense_id,
+		e.created_at,
+		e.updated_at
+	FROM
+		expenses e
+			LEFT JOIN
+		expense_categories ec ON ec.id = e.category_id
+			LEFT JOIN
+		vendors v ON v.id = e.vendor_id`
+
 func (s *PostgresStore) GetExpenseByID(id string) (*Expense, error) {
-	rows, err := s.db.Query("SELECT * FROM expenses WHERE id = $1", id)
+	rows, err := s.db.Query(expensesQuery+" WHERE e.id = $1", id)
 	if err != nil {
 		return nil, err
 	}
@@ -152,70 +210,47 @@ func (s *PostgresStore) GetExpenseByID(id string) (*Expense, error) {
 
 func scanIntoExpenses(rows *sql.Rows) (*Expense, error) {
 	expense := new(Expense)
+	var price string
+	var categoryID, categoryName, vendorID, vendorName, receiptURL, recurringExpenseID sql.NullString
 	err := rows.Scan(
 		&expense.ID,
 		&expense.Name,
-		&expense.Price,
+		&price,
 		&expense.Type,
 		&expense.Description,
 		&expense.Currency,
+		&categoryID,
+		&categoryName,
+		&vendorID,
+		&vendorName,
+		&receiptURL,
+		&recurringExpenseID,
 		&expense.CreatedAt,
 		&expense.UpdatedAt,
 	)
-
-	return expense, err
-}
-
-func (s *PostgresStore) GetExpenses() ([]*Expense, error) {
-	rows, err := s.db.Query("SELECT * FROM expenses")
 	if err != nil {
 		return nil, err
 	}
 
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(rows)
-
-	var expenses []*Expense
-	for rows.Next() {
-		expense, err := scanIntoExpenses(rows)
-		if err != nil {
-			return nil, err
-		}
+	expense.CategoryID = nullStringToPtr(categoryID)
+	expense.CategoryName = nullStringToPtr(categoryName)
+	expense.VendorID = nullStringToPtr(vendorID)
+	expense.VendorName = nullStringToPtr(vendorName)
+	expense.ReceiptURL = nullStringToPtr(receiptURL)
+	expense.RecurringExpenseID = nullStringToPtr(recurringExpenseID)
 
-		expenses = append(expenses, expense)
-	}
+	// The price is only exact once the currency of the expense is known
+	expense.Price, err = ParseMoney(price, expense.Currency)
 
-	return expenses, nil
+	return expense, err
 }
 
-func (s *PostgresStore) GetExpensesByMonth() ([]*Expense, error) {
-	rows, err := s.db.Query(`
-		SELECT
-		    DATE_TRUNC('month', e.created_at) AS sort_by_month,
-			e.id,
-			e.name,
-			e.price,
-			e.type,
-			e.description,
-			e.currency
-		FROM
-			expenses e
-		GROUP BY 
-		    sort_by_month,
-		    e.id,
-			e.name,
-			e.price,
-			e.type,
-			e.description,
-			e.currency
-		ORDER BY
-			created_at DESC,
-			e.created_at DESC;
-	`)
+// GetExpenses lists the expenses matching the filter, newest first.
+func (s *PostgresStore) GetExpenses(filter ExpenseFilter) ([]*Expense, error) {
+	rows, err := s.db.Query(expensesQuery+`
+		WHERE ($1 = '' OR e.category_id::text = $1) AND ($2 = '' OR e.vendor_id::text = $2)
+		ORDER BY e.created_at DESC`,
+		filter.CategoryID, filter.VendorID)
 	if err != nil {
 		return nil, err
 	}
@@ -248,8 +283,11 @@ func (s *PostgresStore) UpdateExpense(expense *Expense) error {
 		    price = $2, 
 		    type = $3, 
 		    description = $4,
-		    currency = $5
-		WHERE id = $6
+		    currency = $5,
+		    category_id = $6,
+		    vendor_id = $7,
+		    receipt_url = $8
+		WHERE id = $9
 	`
 
 	_, err := s.db.Exec(
@@ -259,6 +297,9 @@ func (s *PostgresStore) UpdateExpense(expense *Expense) error {
 		expense.Type,
 		expense.Description,
 		expense.Currency,
+		expense.CategoryID,
+		expense.VendorID,
+		expense.ReceiptURL,
 		expense.ID,
 	)
 	if err != nil {
@@ -1,39 +1,84 @@
 package main
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
+// Expense is money spent running the store. Type is the free-form kind of
+// expense used before categories, CategoryName and VendorName are filled
+// from the category and the vendor.
 type Expense struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Price       float64   `json:"price"`
-	Type        string    `json:"type"`
-	Description string    `json:"description"`
-	Currency    string    `json:"currency"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Price        Money   `json:"price"`
+	Type         string  `json:"type"`
+	Description  string  `json:"description"`
+	Currency     string  `json:"currency"`
+	CategoryID   *string `json:"category_id"`
+	CategoryName *string `json:"category_name"`
+	VendorID     *string `json:"vendor_id"`
+	VendorName   *string `json:"vendor_name"`
+	ReceiptURL   *string `json:"receipt_url"`
+	// RecurringExpenseID is set on the expenses created by a recurring expense
+	RecurringExpenseID *string   `json:"recurring_expense_id"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 type CreateExpenseRequest struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Type        string  `json:"type"`
-	Description string  `json:"description"`
-	Currency    string  `json:"currency"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Price       json.Number `json:"price"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Currency    string      `json:"currency"`
+	CategoryID  *string     `json:"category_id"`
+	VendorID    *string     `json:"vendor_id"`
+	// Receipt is a base64 data URL of the supplier invoice, an image or a PDF.
+	// On updates a missing receipt keeps the current one and an empty one
+	// removes it.
+	Receipt *string `json:"receipt"`
+}
+
+// ExpenseFilter narrows expense listings, empty fields match every expense.
+type ExpenseFilter struct {
+	CategoryID string
+	VendorID   string
 }
 
 func NewExpense(
 	name string,
-	price float64,
+	price string,
 	exType string,
 	description string,
 	currency string,
+	categoryID *string,
+	vendorID *string,
 ) (*Expense, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// The price is read in the currency of the expense, so its decimals are
+	// checked against that currency
+	amount, err := ParseMoney(price, currency)
+	if err != nil {
+		return nil, err
+	}
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("price of expense [%s] can't be negative", name)
+	}
+
 	return &Expense{
 		Name:        name,
-		Price:       price,
+		Price:       amount,
 		Type:        exType,
 		Description: description,
 		Currency:    currency,
+		CategoryID:  categoryID,
+		VendorID:    vendorID,
 	}, nil
 }
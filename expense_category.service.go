@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (server *APIServer) handleCreateExpenseCategory(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateExpenseCategoryRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	category, err := NewExpenseCategory(req.Name, req.Description)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateExpenseCategory(category); err != nil {
+		return err
+	}
+
+	// Recovering category from DB
+	createdCategory, err := server.store.GetExpenseCategoryByID(category.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdCategory)
+}
+
+func (server *APIServer) handleGetExpenseCategories(w http.ResponseWriter, _ *http.Request) error {
+	categories, err := server.store.GetExpenseCategories()
+	if err != nil {
+		return err
+	}
+	if categories == nil {
+		categories = []*ExpenseCategory{}
+	}
+	return WriteJSON(w, http.StatusOK, categories)
+}
+
+func (server *APIServer) handleGetExpenseCategoryByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	category, err := server.store.GetExpenseCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, category)
+}
+
+func (server *APIServer) handleUpdateExpenseCategory(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetExpenseCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	var category ExpenseCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		return err
+	}
+
+	category.ID = id
+	if err := category.Validate(); err != nil {
+		return err
+	}
+
+	if err := server.store.UpdateExpenseCategory(&category); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedCategory, err := server.store.GetExpenseCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedCategory)
+}
+
+func (server *APIServer) handleDeleteExpenseCategory(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetExpenseCategoryByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteExpenseCategory(id); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+func (s *PostgresStore) CreateExpenseCategoriesTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS expense_categories (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            name VARCHAR(255) NOT NULL UNIQUE,
+            description TEXT,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("expense_categories")
+}
+
+const expenseCategoryColumns = `
+	id, name, description, created_at, updated_at`
+
+func (s *PostgresStore) CreateExpenseCategory(category *ExpenseCategory) error {
+	query := `
+        INSERT INTO expense_categories (
+            name,
+            description
+        )
+        VALUES ($1, $2)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		category.Name,
+		category.Description,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted category
+	category.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetExpenseCategoryByID(id string) (*ExpenseCategory, error) {
+	rows, err := s.db.Query(`
+		SELECT `+expenseCategoryColumns+`
+		FROM expense_categories WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoExpenseCategories(rows)
+	}
+
+	return nil, fmt.Errorf("expense category [%s] not found", id)
+}
+
+func (s *PostgresStore) GetExpenseCategories() ([]*ExpenseCategory, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + expenseCategoryColumns + `
+		FROM expense_categories ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var categories []*ExpenseCategory
+	for rows.Next() {
+		category, err := scanIntoExpenseCategories(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+func scanIntoExpenseCategories(rows *sql.Rows) (*ExpenseCategory, error) {
+	category := new(ExpenseCategory)
+	var description sql.NullString
+
+	err := rows.Scan(
+		&category.ID,
+		&category.Name,
+		&description,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Description = nullStringToPtr(description)
+
+	return category, nil
+}
+
+func (s *PostgresStore) UpdateExpenseCategory(category *ExpenseCategory) error {
+	query := `
+		UPDATE expense_categories
+		SET
+		    name = $1,
+		    description = $2
+		WHERE id = $3
+	`
+
+	_, err := s.db.Exec(
+		query,
+		category.Name,
+		category.Description,
+		category.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpenseCategory deletes a category, its expenses are left without one.
+func (s *PostgresStore) DeleteExpenseCategory(id string) error {
+	_, err := s.db.Exec("DELETE FROM expense_categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExpenseCategory groups expenses, e.g. rent, advertising or packaging.
+type ExpenseCategory struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateExpenseCategoryRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+func NewExpenseCategory(name string, description *string) (*ExpenseCategory, error) {
+	category := &ExpenseCategory{
+		Name:        name,
+		Description: description,
+	}
+
+	if err := category.Validate(); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (c *ExpenseCategory) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("expense category name is required")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// handleGetGeography reports the sales by department and city between the
+// optional ?from= and ?to= days. With ?format=geojson the departments, or the
+// cities with ?level=city, are downloaded as GeoJSON features.
+func (server *APIServer) handleGetGeography(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	opts := EarningsOptions{PaidOnly: query.Get("paid_only") == "true"}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "geojson" {
+		return fmt.Errorf("invalid format [%s], use json or geojson", format)
+	}
+	level := query.Get("level")
+	if level == "" {
+		level = GeographyLevelDepartment
+	}
+	if !isValidGeographyLevel(level) {
+		return fmt.Errorf("invalid level [%s], use department or city", level)
+	}
+
+	var err error
+	if opts.Location, err = loadTimeZone(query.Get("tz")); err != nil {
+		return err
+	}
+	if opts.From, err = parseReportDate(query.Get("from"), "from"); err != nil {
+		return err
+	}
+	if opts.To, err = parseReportDate(query.Get("to"), "to"); err != nil {
+		return err
+	}
+	if opts.From != nil && opts.To != nil && opts.To.Before(*opts.From) {
+		return fmt.Errorf("to can't be before from")
+	}
+
+	facts, err := server.store.GetEarningsFacts(opts)
+	if err != nil {
+		return err
+	}
+	report := NewGeographyReport(facts, opts)
+
+	if format != "geojson" {
+		return WriteJSON(w, http.StatusOK, report)
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="ventas-por-%s.geojson"`, level))
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(report.GeoJSON(level))
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Levels of the features of the geography GeoJSON.
+const (
+	GeographyLevelDepartment = "department"
+	GeographyLevelCity       = "city"
+)
+
+// GeographyTotals are the sales of a place. Revenue is what was charged for
+// the products after the line and sale discounts, and AverageTicket is the
+// revenue per sale.
+type GeographyTotals struct {
+	Sales         int   `json:"sales"`
+	Units         int   `json:"units"`
+	Revenue       Money `json:"revenue"`
+	AverageTicket Money `json:"average_ticket"`
+}
+
+// GeographyCity is a city as written on the sales, DaneCode is nil when it is
+// not a known municipality.
+type GeographyCity struct {
+	DaneCode *string `json:"dane_code"`
+	Name     string  `json:"name"`
+	GeographyTotals
+}
+
+// GeographyDepartment is a department as written on the sales with its
+// cities, DaneCode is nil when it is not a known department.
+type GeographyDepartment struct {
+	DaneCode *string `json:"dane_code"`
+	Name     string  `json:"name"`
+	GeographyTotals
+	Cities []GeographyCity `json:"cities"`
+}
+
+// GeographyUnmapped are the sales of the places without a DANE code, which
+// can't be drawn on a map. With them the places of a map add up to the
+// totals of the report.
+type GeographyUnmapped struct {
+	GeographyTotals
+	Places []GeographyUnmappedPlace `json:"places"`
+}
+
+// GeographyUnmappedPlace is a place without a DANE code as written on the
+// sales, Department is only set on cities.
+type GeographyUnmappedPlace struct {
+	Name       string  `json:"name"`
+	Department *string `json:"department,omitempty"`
+	GeographyTotals
+}
+
+// GeographyReport lists every department and city. Unmapped are the cities
+// without a DANE code, which are also listed under their department.
+type GeographyReport struct {
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+	GeographyTotals
+	Departments []GeographyDepartment `json:"departments"`
+	Unmapped    GeographyUnmapped     `json:"unmapped"`
+}
+
+// geographyPlace accumulates the sales of a department or a city.
+type geographyPlace struct {
+	code   string
+	name   string
+	totals GeographyTotals
+	cities map[string]*geographyPlace
+}
+
+func newGeographyPlace(code, name string) *geographyPlace {
+	return &geographyPlace{
+		code:   code,
+		name:   name,
+		totals: GeographyTotals{Revenue: NewMoney(0, baseCurrency)},
+		cities: make(map[string]*geographyPlace),
+	}
+}
+
+func (p *geographyPlace) add(units int, revenue Money) {
+	p.totals.Sales++
+	p.totals.Units += units
+	p.totals.Revenue = p.totals.Revenue.Add(revenue)
+}
+
+// NewGeographyReport totals the sales of the facts by the department and the
+// city they were sent to. Places are matched to their DANE code by name, see
+// daneCityCode, and the unknown ones are grouped by their name.
+func NewGeographyReport(facts *EarningsFacts, opts EarningsOptions) *GeographyReport {
+	facts = facts.counted(opts.PaidOnly)
+	report := &GeographyReport{
+		From:            opts.From,
+		To:              opts.To,
+		GeographyTotals: GeographyTotals{Revenue: NewMoney(0, baseCurrency)},
+		Departments:     []GeographyDepartment{},
+	}
+
+	units := make(map[string]int)
+	revenue := make(map[string]Money)
+	for _, line := range facts.Lines {
+		if _, ok := revenue[line.SaleID]; !ok {
+			revenue[line.SaleID] = NewMoney(0, baseCurrency)
+		}
+		units[line.SaleID] += line.Quantity
+		revenue[line.SaleID] = revenue[line.SaleID].Add(line.Price.Mul(line.Quantity).Sub(line.Discount))
+	}
+
+	departments := make(map[string]*geographyPlace)
+	for _, sale := range facts.Sales {
+		saleRevenue, ok := revenue[sale.ID]
+		if !ok {
+			saleRevenue = NewMoney(0, baseCurrency)
+		}
+		saleRevenue = saleRevenue.Sub(sale.Discount)
+
+		departmentCode := daneDepartmentCode(sale.Department)
+		cityCode := daneCityCode(departmentCode, sale.City)
+		if cityCode != "" {
+			departmentCode = cityCode[:2]
+		}
+
+		department := placeOf(departments, departmentCode, sale.Department, daneDepartments)
+		city := placeOf(department.cities, cityCode, sale.City, daneCities)
+
+		department.add(units[sale.ID], saleRevenue)
+		city.add(units[sale.ID], saleRevenue)
+
+		report.Sales++
+		report.Units += units[sale.ID]
+		report.Revenue = report.Revenue.Add(saleRevenue)
+	}
+	report.AverageTicket = averageTicket(report.GeographyTotals)
+
+	for _, department := range departments {
+		row := GeographyDepartment{
+			DaneCode:        codePtr(department.code),
+			Name:            department.name,
+			GeographyTotals: department.totals,
+			Cities:          []GeographyCity{},
+		}
+		row.AverageTicket = averageTicket(row.GeographyTotals)
+
+		for _, city := range department.cities {
+			cityRow := GeographyCity{
+				DaneCode:        codePtr(city.code),
+				Name:            city.name,
+				GeographyTotals: city.totals,
+			}
+			cityRow.AverageTicket = averageTicket(cityRow.GeographyTotals)
+			row.Cities = append(row.Cities, cityRow)
+		}
+		sort.Slice(row.Cities, func(i, j int) bool {
+			return lessGeography(row.Cities[i].GeographyTotals, row.Cities[j].GeographyTotals, row.Cities[i].Name, row.Cities[j].Name)
+		})
+
+		report.Departments = append(report.Departments, row)
+	}
+	sort.Slice(report.Departments, func(i, j int) bool {
+		a, b := report.Departments[i], report.Departments[j]
+		return lessGeography(a.GeographyTotals, b.GeographyTotals, a.Name, b.Name)
+	})
+	report.Unmapped = report.unmapped(GeographyLevelCity)
+
+	return report
+}
+
+// unmapped totals the departments, or the cities, without a DANE code.
+func (report *GeographyReport) unmapped(level string) GeographyUnmapped {
+	unmapped := GeographyUnmapped{
+		GeographyTotals: GeographyTotals{Revenue: NewMoney(0, baseCurrency)},
+		Places:          []GeographyUnmappedPlace{},
+	}
+	add := func(place GeographyUnmappedPlace) {
+		unmapped.Sales += place.Sales
+		unmapped.Units += place.Units
+		unmapped.Revenue = unmapped.Revenue.Add(place.Revenue)
+		unmapped.Places = append(unmapped.Places, place)
+	}
+
+	for _, department := range report.Departments {
+		if level == GeographyLevelDepartment {
+			if department.DaneCode == nil {
+				add(GeographyUnmappedPlace{Name: department.Name, GeographyTotals: department.GeographyTotals})
+			}
+			continue
+		}
+
+		for _, city := range department.Cities {
+			if city.DaneCode == nil {
+				name := department.Name
+				add(GeographyUnmappedPlace{Name: city.Name, Department: &name, GeographyTotals: city.GeographyTotals})
+			}
+		}
+	}
+	unmapped.AverageTicket = averageTicket(unmapped.GeographyTotals)
+
+	return unmapped
+}
+
+// placeOf finds the place of a DANE code, or of the name when the code is
+// empty, adding it when missing. Known places take their DANE name.
+func placeOf(places map[string]*geographyPlace, code, name string, names map[string]string) *geographyPlace {
+	key := code
+	if key == "" {
+		key = "?" + normalizePlaceName(name)
+	}
+
+	place, ok := places[key]
+	if !ok {
+		name = strings.TrimSpace(name)
+		if code != "" {
+			name = names[code]
+		}
+		place = newGeographyPlace(code, name)
+		places[key] = place
+	}
+	return place
+}
+
+func averageTicket(totals GeographyTotals) Money {
+	if totals.Sales == 0 {
+		return NewMoney(0, baseCurrency)
+	}
+	return totals.Revenue.Div(totals.Sales)
+}
+
+// lessGeography sorts places by revenue, then by name.
+func lessGeography(a, b GeographyTotals, aName, bName string) bool {
+	if cmp := a.Revenue.Cmp(b.Revenue); cmp != 0 {
+		return cmp > 0
+	}
+	return aName < bName
+}
+
+func codePtr(code string) *string {
+	if code == "" {
+		return nil
+	}
+	return &code
+}
+
+// GeoJSONFeatureCollection is a GeoJSON document (RFC 7946). Totals and
+// Unmapped are foreign members, the features and Unmapped add up to Totals.
+type GeoJSONFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []GeoJSONFeature  `json:"features"`
+	Totals   GeographyTotals   `json:"totals"`
+	Unmapped GeographyUnmapped `json:"unmapped"`
+}
+
+// GeoJSONFeature is a place keyed by its DANE code. It carries no geometry,
+// which is always null: the features are the properties of the places, to be
+// joined by id with boundaries such as the DANE MGN layers.
+type GeoJSONFeature struct {
+	Type       string                     `json:"type"`
+	ID         string                     `json:"id"`
+	Geometry   *struct{}                  `json:"geometry"`
+	Properties GeographyFeatureProperties `json:"properties"`
+}
+
+// GeographyFeatureProperties are the properties of a GeoJSON feature, the
+// department is only set on cities.
+type GeographyFeatureProperties struct {
+	DaneCode           string  `json:"dane_code"`
+	Name               string  `json:"name"`
+	DepartmentDaneCode *string `json:"department_dane_code,omitempty"`
+	Department         *string `json:"department,omitempty"`
+	GeographyTotals
+}
+
+// GeoJSON returns the departments or the cities of the report, as level says,
+// as GeoJSON features. Places without a DANE code can't be drawn, they are
+// totalled in Unmapped instead.
+func (report *GeographyReport) GeoJSON(level string) *GeoJSONFeatureCollection {
+	collection := &GeoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []GeoJSONFeature{},
+		Totals:   report.GeographyTotals,
+		Unmapped: report.unmapped(level),
+	}
+
+	for _, department := range report.Departments {
+		if department.DaneCode == nil {
+			continue
+		}
+
+		if level == GeographyLevelDepartment {
+			collection.Features = append(collection.Features, GeoJSONFeature{
+				Type: "Feature",
+				ID:   *department.DaneCode,
+				Properties: GeographyFeatureProperties{
+					DaneCode:        *department.DaneCode,
+					Name:            department.Name,
+					GeographyTotals: department.GeographyTotals,
+				},
+			})
+			continue
+		}
+
+		for _, city := range department.Cities {
+			if city.DaneCode == nil {
+				continue
+			}
+			collection.Features = append(collection.Features, GeoJSONFeature{
+				Type: "Feature",
+				ID:   *city.DaneCode,
+				Properties: GeographyFeatureProperties{
+					DaneCode:           *city.DaneCode,
+					Name:               city.Name,
+					DepartmentDaneCode: department.DaneCode,
+					Department:         &department.Name,
+					GeographyTotals:    city.GeographyTotals,
+				},
+			})
+		}
+	}
+
+	return collection
+}
+
+func isValidGeographyLevel(level string) bool {
+	return level == GeographyLevelDepartment || level == GeographyLevelCity
+}
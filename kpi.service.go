@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleGetKPIs compares the figures of the ?period= granularity, month by
+// default, holding ?date=, today by default, with the previous period and the
+// same period of last year, see KPIRanges.
+func (server *APIServer) handleGetKPIs(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+
+	granularity := query.Get("period")
+	if granularity == "" {
+		granularity = GranularityMonth
+	}
+	if !isValidGranularity(granularity) {
+		return fmt.Errorf("invalid period [%s], use day, week, month, quarter or year", granularity)
+	}
+
+	loc, err := loadTimeZone(query.Get("tz"))
+	if err != nil {
+		return err
+	}
+	date, err := parseReportDate(query.Get("date"), "date")
+	if err != nil {
+		return err
+	}
+	if date == nil {
+		today := localDate(time.Now(), loc)
+		date = &today
+	}
+
+	current, previous, lastYear := KPIRanges(*date, granularity)
+	ranges := [3]KPIRange{current, previous, lastYear}
+	paidOnly := query.Get("paid_only") == "true"
+
+	var facts [3]*EarningsFacts
+	var customerIDs []string
+	for i, kpiRange := range ranges {
+		opts := EarningsOptions{
+			PaidOnly:    paidOnly,
+			Granularity: granularity,
+			Location:    loc,
+			From:        &kpiRange.From,
+			To:          &kpiRange.To,
+		}
+		if facts[i], err = server.store.GetEarningsFacts(opts); err != nil {
+			return err
+		}
+		for _, sale := range facts[i].Sales {
+			if sale.CustomerID != "" {
+				customerIDs = append(customerIDs, sale.CustomerID)
+			}
+		}
+	}
+
+	firstPurchases, err := server.store.GetFirstPurchases(paidOnly, customerIDs)
+	if err != nil {
+		return err
+	}
+
+	var values [3]kpiValues
+	for i, kpiRange := range ranges {
+		values[i] = newKPIValues(kpiRange, facts[i], firstPurchases, loc)
+	}
+
+	return WriteJSON(w, http.StatusOK, NewKPIs(granularity, current, previous, lastYear, values))
+}
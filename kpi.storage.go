@@ -0,0 +1,52 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetFirstPurchases returns when each of the given customers made their first
+// counted sale, following the same rules as the earnings.
+func (s *PostgresStore) GetFirstPurchases(paidOnly bool, customerIDs []string) (map[string]time.Time, error) {
+	firstPurchases := make(map[string]time.Time)
+	if len(customerIDs) == 0 {
+		return firstPurchases, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT
+			s.customer_id,
+			MIN(s.created_at)
+		FROM
+			sales s
+		WHERE
+			s.status != 'cancelled' AND (NOT $1 OR s.status = ANY($2))
+			AND s.customer_id = ANY($3::UUID[])
+		GROUP BY
+			s.customer_id`,
+		paidOnly, pq.Array(paidSaleStatuses), pq.Array(customerIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var customerID string
+		var firstPurchase time.Time
+		if err := rows.Scan(&customerID, &firstPurchase); err != nil {
+			return nil, err
+		}
+		firstPurchases[customerID] = firstPurchase
+	}
+
+	return firstPurchases, rows.Err()
+}
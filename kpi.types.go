@@ -0,0 +1,202 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// KPIRange is a range of inclusive days in the time zone of the KPIs.
+type KPIRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// MoneyKPI compares an amount of the current period with the previous period
+// and the same period of last year. The percentages are nil when the amount
+// they compare with is zero.
+type MoneyKPI struct {
+	Current                  Money    `json:"current"`
+	Previous                 Money    `json:"previous"`
+	LastYear                 Money    `json:"last_year"`
+	PreviousChange           Money    `json:"previous_change"`
+	PreviousChangePercentage *float64 `json:"previous_change_percentage"`
+	LastYearChange           Money    `json:"last_year_change"`
+	LastYearChangePercentage *float64 `json:"last_year_change_percentage"`
+}
+
+// CountKPI is a MoneyKPI for counts.
+type CountKPI struct {
+	Current                  int      `json:"current"`
+	Previous                 int      `json:"previous"`
+	LastYear                 int      `json:"last_year"`
+	PreviousChange           int      `json:"previous_change"`
+	PreviousChangePercentage *float64 `json:"previous_change_percentage"`
+	LastYearChange           int      `json:"last_year_change"`
+	LastYearChangePercentage *float64 `json:"last_year_change_percentage"`
+}
+
+// KPIs are the figures of the dashboard home, see NewKPIs.
+type KPIs struct {
+	Period             string   `json:"period"`
+	Current            KPIRange `json:"current"`
+	Previous           KPIRange `json:"previous"`
+	LastYear           KPIRange `json:"last_year"`
+	Income             MoneyKPI `json:"income"`
+	Expenses           MoneyKPI `json:"expenses"`
+	Earnings           MoneyKPI `json:"earnings"`
+	Sales              CountKPI `json:"sales"`
+	AverageOrderValue  MoneyKPI `json:"average_order_value"`
+	NewCustomers       CountKPI `json:"new_customers"`
+	ReturningCustomers CountKPI `json:"returning_customers"`
+	UnitsSold          CountKPI `json:"units_sold"`
+}
+
+// kpiValues are the figures of a single range.
+type kpiValues struct {
+	income             Money
+	expenses           Money
+	earnings           Money
+	averageOrderValue  Money
+	sales              int
+	newCustomers       int
+	returningCustomers int
+	unitsSold          int
+}
+
+// KPIRanges are the ranges compared by the KPIs of the period of granularity
+// that date falls in. The current range goes from the start of the period up
+// to date, and the previous period and the same period of last year are cut
+// to the same number of days, so a period in progress is compared with the
+// same part of the others, and a finished one with the whole of them. Weeks
+// of last year are 52 weeks back to start on the same weekday.
+func KPIRanges(date time.Time, granularity string) (current, previous, lastYear KPIRange) {
+	start := periodStart(date, granularity, time.UTC)
+	current = KPIRange{From: start, To: date}
+	days := int(date.Sub(start).Hours() / 24)
+	if date.Equal(periodEnd(start, granularity).AddDate(0, 0, -1)) {
+		days = -1
+	}
+
+	previousStart := periodStart(start.AddDate(0, 0, -1), granularity, time.UTC)
+	previous = cutKPIRange(previousStart, days, granularity)
+
+	lastYearStart := start.AddDate(-1, 0, 0)
+	if granularity == GranularityWeek {
+		lastYearStart = start.AddDate(0, 0, -52*7)
+	}
+	lastYear = cutKPIRange(periodStart(lastYearStart, granularity, time.UTC), days, granularity)
+
+	return current, previous, lastYear
+}
+
+// cutKPIRange is the range of the first days+1 days of the period starting
+// at start, or the whole period when it is shorter or days is negative.
+func cutKPIRange(start time.Time, days int, granularity string) KPIRange {
+	to := periodEnd(start, granularity).AddDate(0, 0, -1)
+	if cut := start.AddDate(0, 0, days); days >= 0 && cut.Before(to) {
+		to = cut
+	}
+	return KPIRange{From: start, To: to}
+}
+
+// newKPIValues computes the figures of a range from its facts, the same way
+// the earnings do, except that earnings can be negative so losses compare
+// with other periods. The average order value is the income after discounts
+// of each sale. Customers are new when their first purchase, as given in
+// firstPurchases, is in the range, and returning when they bought before.
+func newKPIValues(r KPIRange, facts *EarningsFacts, firstPurchases map[string]time.Time, loc *time.Location) kpiValues {
+	period := newEarningsPeriod(r.From, GranularityDay)
+	for _, sale := range facts.Sales {
+		period.addSale(sale)
+	}
+	for _, line := range facts.Lines {
+		period.addLine(line)
+	}
+	for _, expense := range facts.Expenses {
+		period.addExpense(expense)
+	}
+	earnings := period.summarize()
+
+	values := kpiValues{
+		income:            earnings.Income,
+		expenses:          earnings.ConvertedExpense,
+		earnings:          period.netEarnings(),
+		averageOrderValue: NewMoney(0, baseCurrency),
+		sales:             earnings.TotalSalesInMonth,
+		unitsSold:         earnings.TotalProductVariationsInMonth,
+	}
+	if values.sales > 0 {
+		values.averageOrderValue = earnings.Income.Sub(earnings.Discounts).Div(values.sales)
+	}
+
+	start := startOfDay(r.From, loc)
+	customers := make(map[string]bool)
+	for _, sale := range facts.Sales {
+		if sale.CustomerID == "" || customers[sale.CustomerID] {
+			continue
+		}
+		customers[sale.CustomerID] = true
+
+		if first, ok := firstPurchases[sale.CustomerID]; ok && first.Before(start) {
+			values.returningCustomers++
+		} else {
+			values.newCustomers++
+		}
+	}
+
+	return values
+}
+
+// NewKPIs compares the figures of the current range with the previous and
+// last year ones.
+func NewKPIs(granularity string, current, previous, lastYear KPIRange, values [3]kpiValues) *KPIs {
+	cur, prev, last := values[0], values[1], values[2]
+	return &KPIs{
+		Period:             granularity,
+		Current:            current,
+		Previous:           previous,
+		LastYear:           lastYear,
+		Income:             newMoneyKPI(cur.income, prev.income, last.income),
+		Expenses:           newMoneyKPI(cur.expenses, prev.expenses, last.expenses),
+		Earnings:           newMoneyKPI(cur.earnings, prev.earnings, last.earnings),
+		Sales:              newCountKPI(cur.sales, prev.sales, last.sales),
+		AverageOrderValue:  newMoneyKPI(cur.averageOrderValue, prev.averageOrderValue, last.averageOrderValue),
+		NewCustomers:       newCountKPI(cur.newCustomers, prev.newCustomers, last.newCustomers),
+		ReturningCustomers: newCountKPI(cur.returningCustomers, prev.returningCustomers, last.returningCustomers),
+		UnitsSold:          newCountKPI(cur.unitsSold, prev.unitsSold, last.unitsSold),
+	}
+}
+
+func newMoneyKPI(current, previous, lastYear Money) MoneyKPI {
+	return MoneyKPI{
+		Current:                  current,
+		Previous:                 previous,
+		LastYear:                 lastYear,
+		PreviousChange:           current.Sub(previous),
+		PreviousChangePercentage: changePercentage(current.Amount, previous.Amount),
+		LastYearChange:           current.Sub(lastYear),
+		LastYearChangePercentage: changePercentage(current.Amount, lastYear.Amount),
+	}
+}
+
+func newCountKPI(current, previous, lastYear int) CountKPI {
+	return CountKPI{
+		Current:                  current,
+		Previous:                 previous,
+		LastYear:                 lastYear,
+		PreviousChange:           current - previous,
+		PreviousChangePercentage: changePercentage(int64(current), int64(previous)),
+		LastYearChange:           current - lastYear,
+		LastYearChangePercentage: changePercentage(int64(current), int64(lastYear)),
+	}
+}
+
+// changePercentage is the change from before to now as a percentage of
+// before, rounded to two decimals. It is nil when before is zero.
+func changePercentage(now, before int64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	percentage := math.Round(float64(now-before)*10000/math.Abs(float64(before))) / 100
+	return &percentage
+}
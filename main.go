@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/joho/godotenv"
 	"log"
+	"time"
 )
 
 func main() {
@@ -25,6 +26,9 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Background jobs
+	go StartScheduler(store, time.Minute)
+
 	// AWS setup & init
 	sdkConfig, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
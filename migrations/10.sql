@@ -0,0 +1,16 @@
+-- Shipments of sales, with carrier, tracking number and shipping cost
+CREATE TABLE IF NOT EXISTS shipments (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    sale_id UUID NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+    carrier VARCHAR(100) NOT NULL,
+    tracking_number VARCHAR(100),
+    cost BIGINT NOT NULL DEFAULT 0,
+    charge_customer BOOLEAN NOT NULL DEFAULT FALSE,
+    shipped_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS shipments_sale_id_idx ON shipments (sale_id);
@@ -0,0 +1,37 @@
+-- Payments of sales, a sale can be paid in several instalments
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    sale_id UUID NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+    method VARCHAR(30) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    paid_at TIMESTAMPTZ NOT NULL,
+    reference VARCHAR(255),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS payments_sale_id_idx ON payments (sale_id);
+
+-- Sales that were paid before payments were tracked are settled in full
+INSERT INTO payments (sale_id, method, amount, paid_at, reference)
+SELECT t.sale_id, 'other', t.total, t.created_at, 'Recorded before payment tracking'
+FROM (
+    SELECT
+        s.id AS sale_id,
+        s.created_at,
+        (
+            SELECT COALESCE(SUM(pv.price), 0)
+            FROM sale_products sp
+            JOIN product_variations pv ON sp.product_variation_id = pv.id
+            WHERE sp.sale_id = s.id
+        ) + (
+            SELECT COALESCE(SUM(sh.cost), 0)
+            FROM shipments sh
+            WHERE sh.sale_id = s.id AND sh.charge_customer
+        ) AS total
+    FROM sales s
+    WHERE s.status IN ('paid', 'shipped', 'delivered')
+      AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.sale_id = s.id)
+) t
+WHERE t.total > 0;
@@ -0,0 +1,28 @@
+-- Discounts of each line and of the whole sale
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS discount_type VARCHAR(20);
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS discount_value BIGINT;
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_type VARCHAR(20);
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_value BIGINT;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+
+-- Reusable discount codes
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    code VARCHAR(40) NOT NULL UNIQUE,
+    description TEXT,
+    discount_type VARCHAR(20) NOT NULL,
+    discount_value BIGINT NOT NULL,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    max_uses INTEGER,
+    max_uses_per_customer INTEGER,
+    times_used INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The code is kept on the sale in case the coupon is deleted
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS coupon_id UUID REFERENCES coupons(id) ON DELETE SET NULL;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(40);
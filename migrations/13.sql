@@ -0,0 +1,5 @@
+-- Price of the product when sold, next to the price charged
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS list_price BIGINT;
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS price_override_reason TEXT;
+UPDATE product_variations SET list_price = price WHERE list_price IS NULL;
+ALTER TABLE product_variations ALTER COLUMN list_price SET NOT NULL;
@@ -0,0 +1,2 @@
+-- Units sold in each line, older sales have a line per unit
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0);
@@ -0,0 +1,13 @@
+-- Named sequences for numbers that must not have gaps
+CREATE TABLE IF NOT EXISTS counters (
+    name VARCHAR(50) PRIMARY KEY,
+    value BIGINT NOT NULL DEFAULT 0
+);
+
+-- Receipts of sales, numbered from the receipts counter
+CREATE TABLE IF NOT EXISTS receipts (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    sale_id UUID NOT NULL UNIQUE REFERENCES sales(id) ON DELETE RESTRICT,
+    number BIGINT NOT NULL UNIQUE,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
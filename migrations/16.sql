@@ -0,0 +1,21 @@
+-- Human-readable number of each sale, existing sales are numbered by date
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS order_number BIGINT;
+
+UPDATE sales s SET order_number = numbered.order_number
+FROM (
+    SELECT
+        id,
+        (SELECT COALESCE(MAX(order_number), 0) FROM sales)
+            + ROW_NUMBER() OVER (ORDER BY created_at, id) AS order_number
+    FROM sales
+    WHERE order_number IS NULL
+) AS numbered
+WHERE s.id = numbered.id;
+
+-- New sales continue after the last number
+INSERT INTO counters (name, value)
+SELECT 'sales', COALESCE(MAX(order_number), 0) FROM sales
+ON CONFLICT (name) DO UPDATE SET value = GREATEST(counters.value, EXCLUDED.value);
+
+ALTER TABLE sales ALTER COLUMN order_number SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS sales_order_number_idx ON sales (order_number);
@@ -0,0 +1,13 @@
+-- Expense currencies are ISO 4217 codes
+UPDATE expenses SET currency = UPPER(TRIM(currency)) WHERE currency != UPPER(TRIM(currency));
+
+-- COP value of one unit of a currency on a date
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(20, 6) NOT NULL CHECK (rate > 0),
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (currency, rate_date)
+);
@@ -0,0 +1,2 @@
+-- Expense prices keep three decimals for currencies such as KWD
+ALTER TABLE expenses ALTER COLUMN price TYPE NUMERIC(18, 3);
@@ -0,0 +1,34 @@
+-- Managed expense categories and vendors
+CREATE TABLE IF NOT EXISTS expense_categories (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vendors (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    tax_id VARCHAR(50) UNIQUE,
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS receipt_url TEXT;
+CREATE INDEX IF NOT EXISTS expenses_category_id_idx ON expenses (category_id);
+CREATE INDEX IF NOT EXISTS expenses_vendor_id_idx ON expenses (vendor_id);
+
+-- Existing expense types become categories
+INSERT INTO expense_categories (name)
+SELECT DISTINCT TRIM(type) FROM expenses WHERE TRIM(type) != ''
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE expenses e SET category_id = ec.id
+FROM expense_categories ec
+WHERE e.category_id IS NULL AND ec.name = TRIM(e.type);
@@ -0,0 +1,39 @@
+-- Catalog variants move from products.catalog_variants (JSONB) to their own table
+CREATE TABLE IF NOT EXISTS catalog_variants (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    color_hex VARCHAR(20) NOT NULL DEFAULT '',
+    color_name VARCHAR(255) NOT NULL DEFAULT '',
+    image VARCHAR(255) NOT NULL DEFAULT '',
+    price BIGINT,
+    sku VARCHAR(64),
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS catalog_variants_product_id_idx ON catalog_variants (product_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS catalog_variants_sku_idx ON catalog_variants (sku) WHERE sku IS NOT NULL;
+
+CREATE TRIGGER catalog_variants_updated_at_trigger
+    BEFORE UPDATE ON catalog_variants
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+-- Copy the existing JSONB variants, keeping their IDs and order
+INSERT INTO catalog_variants (id, product_id, color_hex, color_name, image, position, created_at, updated_at)
+SELECT
+    (v.value->>'id')::UUID,
+    p.id,
+    COALESCE(v.value->>'color_hex', ''),
+    COALESCE(v.value->>'color_name', ''),
+    COALESCE(v.value->>'image', ''),
+    (v.ordinality - 1)::INTEGER,
+    COALESCE((v.value->>'created_at')::TIMESTAMPTZ AT TIME ZONE 'UTC', CURRENT_TIMESTAMP),
+    COALESCE((v.value->>'updated_at')::TIMESTAMPTZ AT TIME ZONE 'UTC', CURRENT_TIMESTAMP)
+FROM products p,
+     JSONB_ARRAY_ELEMENTS(p.catalog_variants) WITH ORDINALITY AS v(value, ordinality)
+WHERE JSONB_TYPEOF(p.catalog_variants) = 'array'
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE products DROP COLUMN IF EXISTS catalog_variants;
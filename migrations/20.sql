@@ -0,0 +1,32 @@
+-- Templates the scheduler turns into expenses on every occurrence
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    price NUMERIC(18, 3) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL,
+    vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL,
+    cadence VARCHAR(20) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    generated_until DATE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Skipped and overridden occurrences
+CREATE TABLE IF NOT EXISTS recurring_expense_exceptions (
+    recurring_expense_id UUID NOT NULL REFERENCES recurring_expenses(id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    price NUMERIC(18, 3),
+    description VARCHAR(255),
+    PRIMARY KEY (recurring_expense_id, occurrence_date)
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_expense_id UUID REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+CREATE UNIQUE INDEX IF NOT EXISTS expenses_recurring_occurrence_idx ON expenses (recurring_expense_id, occurrence_date);
@@ -0,0 +1,9 @@
+-- Unit cost of products and of the variants that cost something else
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
+ALTER TABLE catalog_variants ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
+
+-- Unit cost changes of a variant are kept in the price history of its product
+ALTER TABLE product_price_changes ADD COLUMN IF NOT EXISTS variant_id UUID REFERENCES catalog_variants(id) ON DELETE CASCADE;
+
+-- Unit cost of the product when sold, lines sold before it existed have none
+ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
@@ -0,0 +1,30 @@
+-- Timestamps are stored with their time zone so reports can be computed in
+-- any of them. Older databases stored them without one, in the time zone they
+-- were written in. Every timestamp column still without a time zone is
+-- converted, whether it comes from the first tables or from an older version
+-- of the scripts after them, products.archived_at included. Both the column
+-- defaults (the TimeZone of the database) and the values written by the
+-- server (its local time) were UTC in every deployment so far, change
+-- source_zone below before running this against a database that was set up
+-- otherwise.
+DO $$
+DECLARE
+    source_zone CONSTANT TEXT := 'UTC';
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT c.table_name, c.column_name
+        FROM information_schema.columns c
+        JOIN information_schema.tables t
+            ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+        WHERE
+            c.table_schema = current_schema()
+            AND t.table_type = 'BASE TABLE'
+            AND c.data_type = 'timestamp without time zone'
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE %L',
+            col.table_name, col.column_name, col.column_name, source_zone
+        );
+    END LOOP;
+END $$;
@@ -0,0 +1,2 @@
+-- Lines keep the color name of the variant sold, as long as catalog_variants.color_name
+ALTER TABLE product_variations ALTER COLUMN color TYPE VARCHAR(255);
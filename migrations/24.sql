@@ -0,0 +1,9 @@
+-- Public order attempts, failed ones included, count against the rate limit
+CREATE TABLE IF NOT EXISTS order_request_attempts (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    ip_address VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS order_request_attempts_ip_idx ON order_request_attempts (ip_address, created_at);
+CREATE INDEX IF NOT EXISTS order_request_attempts_created_at_idx ON order_request_attempts (created_at);
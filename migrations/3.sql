@@ -0,0 +1,8 @@
+-- SKU and barcode (EAN-8, UPC-A, EAN-13) for products and catalog variants
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_idx ON products (sku) WHERE sku IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS products_barcode_idx ON products (barcode) WHERE barcode IS NOT NULL;
+
+ALTER TABLE catalog_variants ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+CREATE UNIQUE INDEX IF NOT EXISTS catalog_variants_barcode_idx ON catalog_variants (barcode) WHERE barcode IS NOT NULL;
@@ -0,0 +1,42 @@
+-- Hierarchical product categories, free-form tags and curated collections
+CREATE TABLE IF NOT EXISTS categories (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    parent_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER categories_updated_at_trigger
+    BEFORE UPDATE ON categories
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tags VARCHAR(50)[] NOT NULL DEFAULT '{}'::VARCHAR(50)[];
+CREATE INDEX IF NOT EXISTS products_category_id_idx ON products (category_id);
+CREATE INDEX IF NOT EXISTS products_tags_idx ON products USING GIN (tags);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER collections_updated_at_trigger
+    BEFORE UPDATE ON collections
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE TABLE IF NOT EXISTS collection_products (
+    collection_id UUID REFERENCES collections(id) ON DELETE CASCADE,
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (collection_id, product_id)
+);
@@ -0,0 +1,35 @@
+-- Product price history, scheduled price changes and sale prices
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sale_price BIGINT;
+
+CREATE TABLE IF NOT EXISTS product_price_schedules (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    price BIGINT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_price_schedules_status_idx ON product_price_schedules (status, starts_at);
+
+CREATE TRIGGER product_price_schedules_updated_at_trigger
+    BEFORE UPDATE ON product_price_schedules
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE TABLE IF NOT EXISTS product_price_changes (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    field VARCHAR(20) NOT NULL,
+    old_price BIGINT,
+    new_price BIGINT,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    schedule_id UUID REFERENCES product_price_schedules(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_price_changes_product_id_idx ON product_price_changes (product_id, created_at);
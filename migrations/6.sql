@@ -0,0 +1,7 @@
+-- Products are archived instead of deleted once they have sales
+ALTER TABLE products ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+ALTER TABLE product_variations DROP CONSTRAINT IF EXISTS product_variations_product_id_fkey;
+ALTER TABLE product_variations DROP CONSTRAINT IF EXISTS fk_product_variation_product_id;
+ALTER TABLE product_variations ADD CONSTRAINT fk_product_variation_product_id
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
@@ -0,0 +1,2 @@
+-- Optional stock per catalog variant, NULL means the stock is not tracked
+ALTER TABLE catalog_variants ADD COLUMN IF NOT EXISTS stock INTEGER;
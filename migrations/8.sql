@@ -0,0 +1,25 @@
+-- Orders placed from the public catalog, reviewed by staff before becoming sales
+CREATE TABLE IF NOT EXISTS order_requests (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    name VARCHAR(255) NOT NULL,
+    instagram_account VARCHAR(255),
+    phone BIGINT NOT NULL,
+    email VARCHAR(255),
+    address VARCHAR(255) NOT NULL,
+    city VARCHAR(255) NOT NULL,
+    department VARCHAR(255) NOT NULL,
+    comments VARCHAR(255),
+    cc VARCHAR(255),
+    items JSONB NOT NULL DEFAULT '[]'::JSONB,
+    ip_address VARCHAR(64),
+    customer_id UUID REFERENCES customers(id) ON DELETE SET NULL,
+    sale_id UUID REFERENCES sales(id) ON DELETE SET NULL,
+    rejection_reason VARCHAR(255),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS order_requests_status_idx ON order_requests (status, created_at);
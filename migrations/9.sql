@@ -0,0 +1,19 @@
+-- Sale statuses, sales recorded before statuses existed were already delivered
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS status VARCHAR(20);
+UPDATE sales SET status = 'delivered' WHERE status IS NULL;
+ALTER TABLE sales ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE sales ALTER COLUMN status SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS sales_status_idx ON sales (status);
+
+CREATE TABLE IF NOT EXISTS sale_status_changes (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    sale_id UUID NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    note VARCHAR(255),
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sale_status_changes_sale_id_idx ON sale_status_changes (sale_id, created_at);
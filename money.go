@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount of a currency, kept in the minor units of the
+// currency (cents, centavos...) so no arithmetic goes through floats.
+//
+// Money is encoded in JSON as {"amount": "45000.00", "currency": "COP"}.
+// A bare number or string such as 45000 or "45000.50" is also accepted and
+// taken as COP. In the database, amounts are stored in major units.
+//
+// Add, Sub and Cmp panic when the currencies differ, amounts are always
+// converted explicitly.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney returns the amount of minor units of currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// MoneyFromUnits returns the amount of major units of currency, e.g. pesos.
+func MoneyFromUnits(units int, currency string) Money {
+	return Money{Amount: int64(units) * minorUnitsPerUnit(currency), Currency: currency}
+}
+
+// ParseMoney reads a decimal amount in major units such as "45000",
+// "12.5" or "-3.25". More decimals than the currency has are only accepted
+// when they are zeros.
+func ParseMoney(value, currency string) (Money, error) {
+	value = strings.TrimSpace(value)
+	invalid := fmt.Errorf("invalid amount [%s] of %s", value, currency)
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(fraction, "0123456789") != "" {
+		return Money{}, invalid
+	}
+
+	digits := currencyExponent(currency)
+	if len(fraction) > digits {
+		if strings.Trim(fraction[digits:], "0") != "" {
+			return Money{}, fmt.Errorf("amount [%s] has more decimals than %s allows", value, currency)
+		}
+		fraction = fraction[:digits]
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, invalid
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func (m Money) mustMatch(other Money) {
+	if m.Currency != other.Currency {
+		panic(fmt.Sprintf("money: mixing %s and %s", m.Currency, other.Currency))
+	}
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}
+}
+
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Div splits the amount in n parts, rounded half away from zero to a minor
+// unit.
+func (m Money) Div(n int) Money {
+	amount, rest := m.Amount/int64(n), m.Amount%int64(n)
+	if rest < 0 {
+		rest = -rest
+	}
+	if rest*2 >= int64(n) {
+		if m.Amount < 0 {
+			amount--
+		} else {
+			amount++
+		}
+	}
+	return Money{Amount: amount, Currency: m.Currency}
+}
+
+// Percent returns percent percent of the amount, rounded down to a minor unit.
+func (m Money) Percent(percent int) Money {
+	return Money{Amount: m.Amount * int64(percent) / 100, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or 1 when m is less than, equal to or greater than other.
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	}
+	return 0
+}
+
+// IsWholeUnits reports whether the amount has no fraction of a major unit.
+func (m Money) IsWholeUnits() bool {
+	return m.Amount%minorUnitsPerUnit(m.Currency) == 0
+}
+
+// TruncateUnits drops the fraction of a major unit of the amount.
+func (m Money) TruncateUnits() Money {
+	perUnit := minorUnitsPerUnit(m.Currency)
+	return Money{Amount: m.Amount / perUnit * perUnit, Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// String is the amount in major units with every decimal of the currency,
+// e.g. 45000.00.
+func (m Money) String() string {
+	digits := currencyExponent(m.Currency)
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	text := strconv.FormatInt(amount, 10)
+	if digits == 0 {
+		return sign + text
+	}
+	if len(text) <= digits {
+		text = strings.Repeat("0", digits-len(text)+1) + text
+	}
+	return sign + text[:len(text)-digits] + "." + text[len(text)-digits:]
+}
+
+// Format formats the amount for people, e.g. "COP 45.000" for es-CO or
+// "USD 1,250.50" for en-US. Decimals are only shown when there are any.
+func (m Money) Format(locale string) string {
+	separators, ok := moneyLocales[locale]
+	if !ok {
+		separators = moneyLocales[defaultMoneyLocale]
+	}
+
+	sign := ""
+	text := m.String()
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+		text = text[1:]
+	}
+	whole, fraction, _ := strings.Cut(text, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(separators.thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	formatted := m.Currency + " " + sign + grouped.String()
+	if strings.Trim(fraction, "0") != "" {
+		formatted += separators.decimal + fraction
+	}
+	return formatted
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var value struct {
+			Amount   json.Number `json:"amount"`
+			Currency string      `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+
+		currency := baseCurrency
+		if value.Currency != "" {
+			var err error
+			if currency, err = normalizeCurrency(value.Currency); err != nil {
+				return err
+			}
+		}
+
+		parsed, err := ParseMoney(value.Amount.String(), currency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	var value json.Number
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid amount %s", data)
+	}
+	parsed, err := ParseMoney(value.String(), baseCurrency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores whole amounts as integers so they fit BIGINT columns, and
+// other amounts as exact decimals.
+func (m Money) Value() (driver.Value, error) {
+	perUnit := minorUnitsPerUnit(m.Currency)
+	if m.Amount%perUnit == 0 {
+		return m.Amount / perUnit, nil
+	}
+	return m.String(), nil
+}
+
+// Scan reads an amount in major units. The currency is kept when it was set
+// before scanning and is COP otherwise.
+func (m *Money) Scan(src any) error {
+	if m.Currency == "" {
+		m.Currency = baseCurrency
+	}
+
+	var text string
+	switch value := src.(type) {
+	case int64:
+		text = strconv.FormatInt(value, 10)
+	case []byte:
+		text = string(value)
+	case string:
+		text = value
+	default:
+		return fmt.Errorf("can't scan %T into money", src)
+	}
+
+	parsed, err := ParseMoney(text, m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func minorUnitsPerUnit(currency string) int64 {
+	return int64(math.Pow10(currencyExponent(currency)))
+}
+
+type moneySeparators struct {
+	thousands string
+	decimal   string
+}
+
+const defaultMoneyLocale = "es-CO"
+
+var moneyLocales = map[string]moneySeparators{
+	"es-CO": {thousands: ".", decimal: ","},
+	"es-ES": {thousands: ".", decimal: ","},
+	"es-MX": {thousands: ",", decimal: "."},
+	"en-US": {thousands: ",", decimal: "."},
+	"pt-BR": {thousands: ".", decimal: ","},
+}
+
+// moneyLocale is the locale amounts are formatted with, set with the
+// MONEY_LOCALE env var.
+func moneyLocale() string {
+	if locale := os.Getenv("MONEY_LOCALE"); locale != "" {
+		return locale
+	}
+	return defaultMoneyLocale
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxOrderRequestsPerIP is how many orders a visitor can try to place
+	// within orderRequestsWindow before being rate limited.
+	maxOrderRequestsPerIP = 5
+	orderRequestsWindow   = time.Hour
+	// maxOrderRequestBodySize caps the size of a public order body in bytes.
+	maxOrderRequestBodySize = 64 << 10
+)
+
+func (server *APIServer) handleCreatePublicOrder(w http.ResponseWriter, r *http.Request) error {
+	// Every attempt counts against the limit, so failed captchas or invalid
+	// orders can't be retried endlessly
+	ip := getClientIP(r)
+	count, err := server.store.CountRecentOrderRequestAttempts(ip, time.Now().Add(-orderRequestsWindow))
+	if err != nil {
+		return err
+	}
+	if count >= maxOrderRequestsPerIP {
+		return WriteJSON(w, http.StatusTooManyRequests, apiError{Error: "too many orders, please try again later"})
+	}
+	if err := server.store.RecordOrderRequestAttempt(ip); err != nil {
+		return err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOrderRequestBodySize)
+
+	req := new(CreateOrderRequestRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	// Bots filling the honeypot get the same answer as everyone else
+	if req.Website != "" {
+		return WriteJSON(w, http.StatusAccepted, map[string]string{"status": OrderRequestStatusPending})
+	}
+
+	if captchaEnabled() {
+		if err := verifyCaptcha(req.CaptchaToken, ip); err != nil {
+			return err
+		}
+	}
+
+	items, err := server.resolveOrderRequestItems(req.Items)
+	if err != nil {
+		return err
+	}
+
+	order, err := NewOrderRequest(
+		req.Name,
+		req.InstagramAccount,
+		req.Phone,
+		req.Email,
+		req.Address,
+		req.City,
+		req.Department,
+		req.Comments,
+		req.Cc,
+		items,
+		ip,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateOrderRequest(order); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusAccepted, map[string]string{
+		"id":     order.ID,
+		"status": order.Status,
+	})
+}
+
+// resolveOrderRequestItems checks that every ordered product is in the catalog
+// and offered in the chosen color, and snapshots its name and current price.
+func (server *APIServer) resolveOrderRequestItems(items []OrderRequestItem) ([]OrderRequestItem, error) {
+	if len(items) > maxOrderRequestItems {
+		return nil, fmt.Errorf("an order can't have more than %d products", maxOrderRequestItems)
+	}
+
+	resolved := make([]OrderRequestItem, 0, len(items))
+	for _, item := range items {
+		product, err := server.store.GetCatalogProductByID(item.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("product [%s] is not available", item.ProductID)
+		}
+
+		// Catalog products hide their available colors, so look them up again
+		product, err = server.store.GetProductByID(product.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		color := strings.TrimSpace(item.Color)
+		if !product.HasColor(color) {
+			return nil, fmt.Errorf("product [%s] is not available in color [%s]", product.Name, color)
+		}
+
+		if variant := product.FindVariant(color); variant != nil && !variant.InStock {
+			return nil, fmt.Errorf("product [%s] is out of stock in color [%s]", product.Name, color)
+		}
+
+		resolved = append(resolved, OrderRequestItem{
+			ProductID: product.ID,
+			Color:     color,
+			Name:      product.Name,
+			Price:     product.PriceForColor(color),
+		})
+	}
+
+	return resolved, nil
+}
+
+func (server *APIServer) handleGetOrderRequests(w http.ResponseWriter, r *http.Request) error {
+	orders, err := server.store.GetOrderRequests(r.URL.Query().Get("status"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, orders)
+}
+
+func (server *APIServer) handleGetOrderRequestByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	order, err := server.store.GetOrderRequestByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, order)
+}
+
+// handleConvertOrderRequest turns a pending order request into a sale, going
+// through the same validations as a sale created from the dashboard.
+func (server *APIServer) handleConvertOrderRequest(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	order, err := server.store.GetOrderRequestByID(id)
+	if err != nil {
+		return err
+	}
+	if order.Status != OrderRequestStatusPending {
+		return fmt.Errorf("order request [%s] is already %s", id, order.Status)
+	}
+
+	req := new(ConvertOrderRequestRequest)
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return err
+		}
+	}
+
+	customer, err := server.getOrderRequestCustomer(order, req.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	// The customer pays the price quoted when the order was placed
+	overrideReason := fmt.Sprintf("Price quoted in order request [%s]", order.ID)
+	products := make([]ProductVariations, 0, len(order.Items))
+	for _, item := range order.Items {
+		products = append(products, ProductVariations{
+			ProductID:           item.ProductID,
+			Color:               item.Color,
+			Price:               item.Price,
+			PriceOverride:       true,
+			PriceOverrideReason: &overrideReason,
+		})
+	}
+
+	// The order request is claimed and marked converted with the sale, so
+	// concurrent conversions create a single sale
+	_, err = server.createSale(&CreateSaleRequest{
+		CustomerID:     customer.ID,
+		Products:       products,
+		OrderRequestID: &order.ID,
+	}, getAuthUserID(r))
+	if err != nil {
+		return err
+	}
+
+	updatedOrder, err := server.store.GetOrderRequestByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedOrder)
+}
+
+// getOrderRequestCustomer returns the given customer, or the customer with the
+// phone of the order request, creating it from the order details if needed.
+func (server *APIServer) getOrderRequestCustomer(order *OrderRequest, customerID *string) (*Customer, error) {
+	if customerID != nil {
+		return server.store.GetCustomerByID(*customerID)
+	}
+
+	customer, err := server.store.GetCustomerByPhone(order.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if customer != nil {
+		return customer, nil
+	}
+
+	customer, err = NewCustomer(
+		order.Name,
+		order.InstagramAccount,
+		order.Phone,
+		order.Address,
+		order.City,
+		order.Department,
+		order.Comments,
+		order.Cc,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.store.CreateCustomer(customer); err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+func (server *APIServer) handleRejectOrderRequest(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetOrderRequestByID(id)
+	if err != nil {
+		return err
+	}
+
+	req := new(RejectOrderRequestRequest)
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return err
+		}
+	}
+
+	if err := server.store.RejectOrderRequest(id, strings.TrimSpace(req.Reason), getAuthUserID(r)); err != nil {
+		return err
+	}
+
+	updatedOrder, err := server.store.GetOrderRequestByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedOrder)
+}
+
+func (server *APIServer) handlePublicOrders(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodPost {
+		return server.handleCreatePublicOrder(w, r)
+	}
+	return WriteJSON(w, http.StatusMethodNotAllowed, apiError{Error: "unsupported method: " + r.Method})
+}
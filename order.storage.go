@@ -0,0 +1,300 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (s *PostgresStore) CreateOrderRequestsTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS order_requests (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            status VARCHAR(20) NOT NULL DEFAULT 'pending',
+            name VARCHAR(255) NOT NULL,
+            instagram_account VARCHAR(255),
+            phone BIGINT NOT NULL,
+            email VARCHAR(255),
+            address VARCHAR(255) NOT NULL,
+            city VARCHAR(255) NOT NULL,
+            department VARCHAR(255) NOT NULL,
+            comments VARCHAR(255),
+            cc VARCHAR(255),
+            items JSONB NOT NULL DEFAULT '[]'::JSONB,
+            ip_address VARCHAR(64),
+            customer_id UUID REFERENCES customers(id) ON DELETE SET NULL,
+            sale_id UUID REFERENCES sales(id) ON DELETE SET NULL,
+            rejection_reason VARCHAR(255),
+            reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+            reviewed_at TIMESTAMPTZ,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE INDEX IF NOT EXISTS order_requests_status_idx ON order_requests (status, created_at);
+
+        -- Every public order attempt, stored or not, counts against the rate limit
+        CREATE TABLE IF NOT EXISTS order_request_attempts (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            ip_address VARCHAR(64) NOT NULL,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE INDEX IF NOT EXISTS order_request_attempts_ip_idx ON order_request_attempts (ip_address, created_at);
+        CREATE INDEX IF NOT EXISTS order_request_attempts_created_at_idx ON order_request_attempts (created_at);
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("order_requests")
+}
+
+const orderRequestColumns = `
+	id, status, name, instagram_account, phone, email, address, city, department,
+	comments, cc, items, ip_address, customer_id, sale_id, rejection_reason,
+	reviewed_by, reviewed_at, created_at, updated_at`
+
+func (s *PostgresStore) CreateOrderRequest(order *OrderRequest) error {
+	query := `
+        INSERT INTO order_requests (
+            status,
+            name,
+            instagram_account,
+            phone,
+            email,
+            address,
+            city,
+            department,
+            comments,
+            cc,
+            items,
+            ip_address
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id
+    `
+
+	items, err := json.Marshal(order.Items)
+	if err != nil {
+		return err
+	}
+
+	var id string
+	err = s.db.QueryRow(
+		query,
+		order.Status,
+		order.Name,
+		order.InstagramAccount,
+		order.Phone,
+		order.Email,
+		order.Address,
+		order.City,
+		order.Department,
+		order.Comments,
+		order.Cc,
+		items,
+		order.IPAddress,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted order request
+	order.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetOrderRequestByID(id string) (*OrderRequest, error) {
+	rows, err := s.db.Query(`
+		SELECT `+orderRequestColumns+`
+		FROM order_requests WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoOrderRequests(rows)
+	}
+
+	return nil, fmt.Errorf("order request [%s] not found", id)
+}
+
+// GetOrderRequests lists order requests, newest first. An empty status lists
+// all of them.
+func (s *PostgresStore) GetOrderRequests(status string) ([]*OrderRequest, error) {
+	rows, err := s.db.Query(`
+		SELECT `+orderRequestColumns+`
+		FROM order_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC`, status)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var orders []*OrderRequest
+	for rows.Next() {
+		order, err := scanIntoOrderRequests(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+func scanIntoOrderRequests(rows *sql.Rows) (*OrderRequest, error) {
+	order := new(OrderRequest)
+	var instagramAccount, email, comments, cc, ipAddress sql.NullString
+	var customerID, saleID, rejectionReason, reviewedBy sql.NullString
+	var reviewedAt sql.NullTime
+	var items []byte
+
+	err := rows.Scan(
+		&order.ID,
+		&order.Status,
+		&order.Name,
+		&instagramAccount,
+		&order.Phone,
+		&email,
+		&order.Address,
+		&order.City,
+		&order.Department,
+		&comments,
+		&cc,
+		&items,
+		&ipAddress,
+		&customerID,
+		&saleID,
+		&rejectionReason,
+		&reviewedBy,
+		&reviewedAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(items, &order.Items); err != nil {
+		return nil, err
+	}
+
+	order.InstagramAccount = instagramAccount.String
+	order.Email = email.String
+	order.Comments = comments.String
+	order.Cc = cc.String
+	order.IPAddress = ipAddress.String
+	order.CustomerID = nullStringToPtr(customerID)
+	order.SaleID = nullStringToPtr(saleID)
+	order.RejectionReason = nullStringToPtr(rejectionReason)
+	order.ReviewedBy = nullStringToPtr(reviewedBy)
+	if reviewedAt.Valid {
+		order.ReviewedAt = &reviewedAt.Time
+	}
+
+	return order, nil
+}
+
+// RecordOrderRequestAttempt stores a public order attempt from an IP address
+// and forgets the attempts older than a day.
+func (s *PostgresStore) RecordOrderRequestAttempt(ipAddress string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO order_request_attempts (ip_address) VALUES ($1)`, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		DELETE FROM order_request_attempts WHERE created_at < $1`, time.Now().Add(-24*time.Hour).UTC())
+	return err
+}
+
+// CountRecentOrderRequestAttempts counts the public order attempts made from
+// an IP address since the given time, failed ones included.
+func (s *PostgresStore) CountRecentOrderRequestAttempts(ipAddress string, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM order_request_attempts
+		WHERE ip_address = $1 AND created_at >= $2`, ipAddress, since.UTC()).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// claimOrderRequest locks the order request a sale is created from in the
+// transaction storing the sale, so it can only be converted once.
+func claimOrderRequest(tx *sql.Tx, id string) error {
+	var status string
+	err := tx.QueryRow(`
+		SELECT status FROM order_requests WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order request [%s] not found", id)
+	}
+	if err != nil {
+		return err
+	}
+	if status != OrderRequestStatusPending {
+		return fmt.Errorf("order request [%s] is already %s", id, status)
+	}
+	return nil
+}
+
+// markOrderRequestConverted links a claimed order request to the sale created
+// from it.
+func markOrderRequestConverted(tx *sql.Tx, sale *SaleWithProducts) error {
+	_, err := tx.Exec(`
+		UPDATE order_requests
+		SET status = $1, customer_id = $2, sale_id = $3, reviewed_by = $4, reviewed_at = $5
+		WHERE id = $6`,
+		OrderRequestStatusConverted, sale.CustomerID, sale.ID, sale.CreatedBy, time.Now().UTC(), *sale.OrderRequestID)
+	return err
+}
+
+func (s *PostgresStore) RejectOrderRequest(id, reason string, reviewedBy *string) error {
+	return s.reviewOrderRequest(`
+		UPDATE order_requests
+		SET status = $1, rejection_reason = NULLIF($2, ''), reviewed_by = $3, reviewed_at = $4
+		WHERE id = $5 AND status = $6`,
+		OrderRequestStatusRejected, reason, reviewedBy, time.Now().UTC(), id, OrderRequestStatusPending)
+}
+
+// reviewOrderRequest runs an update that only applies to pending order
+// requests, so an order request can't be reviewed twice.
+func (s *PostgresStore) reviewOrderRequest(query string, args ...any) error {
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("order request is no longer pending")
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	OrderRequestStatusPending   = "pending"
+	OrderRequestStatusConverted = "converted"
+	OrderRequestStatusRejected  = "rejected"
+)
+
+// maxOrderRequestItems limits how many items a visitor can order at once.
+const maxOrderRequestItems = 20
+
+// OrderRequestItem is a product a catalog visitor wants to buy. Name and Price
+// are a snapshot taken when the order request is received.
+type OrderRequestItem struct {
+	ProductID string `json:"product_id"`
+	Color     string `json:"color"`
+	Name      string `json:"name"`
+	Price     Money  `json:"price"`
+}
+
+// OrderRequest is an order submitted from the public catalog. It stays pending
+// until staff convert it into a sale or reject it.
+type OrderRequest struct {
+	ID               string             `json:"id"`
+	Status           string             `json:"status"`
+	Name             string             `json:"name"`
+	InstagramAccount string             `json:"instagram_account"`
+	Phone            int                `json:"phone"`
+	Email            string             `json:"email"`
+	Address          string             `json:"address"`
+	City             string             `json:"city"`
+	Department       string             `json:"department"`
+	Comments         string             `json:"comments"`
+	Cc               string             `json:"cc"`
+	Items            []OrderRequestItem `json:"items"`
+	IPAddress        string             `json:"ip_address"`
+	CustomerID       *string            `json:"customer_id"`
+	SaleID           *string            `json:"sale_id"`
+	RejectionReason  *string            `json:"rejection_reason,omitempty"`
+	ReviewedBy       *string            `json:"reviewed_by"`
+	ReviewedAt       *time.Time         `json:"reviewed_at"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// CreateOrderRequestRequest is the body of a public order. Website is a
+// honeypot field that is hidden from visitors, bots tend to fill it in.
+type CreateOrderRequestRequest struct {
+	Name             string             `json:"name"`
+	InstagramAccount string             `json:"instagram_account"`
+	Phone            int                `json:"phone"`
+	Email            string             `json:"email"`
+	Address          string             `json:"address"`
+	City             string             `json:"city"`
+	Department       string             `json:"department"`
+	Comments         string             `json:"comments"`
+	Cc               string             `json:"cc"`
+	Items            []OrderRequestItem `json:"items"`
+	Website          string             `json:"website"`
+	CaptchaToken     string             `json:"captcha_token"`
+}
+
+// ConvertOrderRequestRequest optionally links the sale to an existing
+// customer, otherwise a customer is found by phone or created.
+type ConvertOrderRequestRequest struct {
+	CustomerID *string `json:"customer_id"`
+}
+
+type RejectOrderRequestRequest struct {
+	Reason string `json:"reason"`
+}
+
+func NewOrderRequest(
+	name string,
+	instagramAccount string,
+	phone int,
+	email string,
+	address string,
+	city string,
+	department string,
+	comments string,
+	cc string,
+	items []OrderRequestItem,
+	ipAddress string,
+) (*OrderRequest, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if phone <= 0 {
+		return nil, fmt.Errorf("phone is required")
+	}
+	if strings.TrimSpace(address) == "" || strings.TrimSpace(city) == "" || strings.TrimSpace(department) == "" {
+		return nil, fmt.Errorf("address, city and department are required")
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("an order needs at least one product")
+	}
+	if len(items) > maxOrderRequestItems {
+		return nil, fmt.Errorf("an order can't have more than %d products", maxOrderRequestItems)
+	}
+
+	return &OrderRequest{
+		Status:           OrderRequestStatusPending,
+		Name:             name,
+		InstagramAccount: strings.TrimSpace(instagramAccount),
+		Phone:            phone,
+		Email:            strings.TrimSpace(email),
+		Address:          strings.TrimSpace(address),
+		City:             strings.TrimSpace(city),
+		Department:       strings.TrimSpace(department),
+		Comments:         strings.TrimSpace(comments),
+		Cc:               strings.TrimSpace(cc),
+		Items:            items,
+		IPAddress:        ipAddress,
+	}, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func getPaymentID(r *http.Request) (string, error) {
+	return getRouteUUID(r, "paymentID")
+}
+
+func (server *APIServer) handleCreatePayment(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	sale, err := server.store.GetSaleByID(saleID)
+	if err != nil {
+		return err
+	}
+	if sale.Status == SaleStatusCancelled {
+		return fmt.Errorf("sale [%s] is cancelled", saleID)
+	}
+
+	req := new(CreatePaymentRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	payment, err := NewPayment(
+		saleID,
+		req.Method,
+		req.Amount,
+		req.PaidAt,
+		req.Reference,
+		getAuthUserID(r),
+	)
+	if err != nil {
+		return err
+	}
+
+	if payment.Amount.Cmp(sale.Balance) > 0 {
+		return fmt.Errorf("amount [%s] is greater than the balance of the sale [%s]", payment.Amount, sale.Balance)
+	}
+
+	if err := server.store.CreatePayment(payment); err != nil {
+		return err
+	}
+
+	// A pending sale is paid once nothing is owed
+	if sale.Status == SaleStatusPending && payment.Amount.Cmp(sale.Balance) == 0 {
+		err := server.store.UpdateSaleStatus(saleID, sale.Status, SaleStatusPaid, "Paid in full", getAuthUserID(r))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Recovering payment from DB
+	createdPayment, err := server.store.GetPaymentByID(saleID, payment.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdPayment)
+}
+
+func (server *APIServer) handleGetPayments(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := server.store.GetSaleByID(saleID); err != nil {
+		return err
+	}
+
+	payments, err := server.store.GetPayments(saleID)
+	if err != nil {
+		return err
+	}
+	if payments == nil {
+		payments = []Payment{}
+	}
+
+	return WriteJSON(w, http.StatusOK, payments)
+}
+
+func (server *APIServer) handleGetPaymentByID(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	paymentID, err := getPaymentID(r)
+	if err != nil {
+		return err
+	}
+
+	payment, err := server.store.GetPaymentByID(saleID, paymentID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, payment)
+}
+
+func (server *APIServer) handleDeletePayment(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	paymentID, err := getPaymentID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetPaymentByID(saleID, paymentID)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeletePayment(saleID, paymentID); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": paymentID})
+}
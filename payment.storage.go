@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+func (s *PostgresStore) CreatePaymentsTable() error {
+	var tableExists bool
+	err := s.db.QueryRow(`SELECT to_regclass('payments') IS NOT NULL`).Scan(&tableExists)
+	if err != nil {
+		return err
+	}
+
+	// Create the table if it doesn't exist
+	_, err = s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS payments (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            sale_id UUID NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+            method VARCHAR(30) NOT NULL,
+            amount BIGINT NOT NULL CHECK (amount > 0),
+            paid_at TIMESTAMPTZ NOT NULL,
+            reference VARCHAR(255),
+            created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE INDEX IF NOT EXISTS payments_sale_id_idx ON payments (sale_id);
+    `)
+	if err != nil {
+		return err
+	}
+
+	// Sales that were paid before payments were tracked are settled in full
+	if !tableExists {
+		_, err = s.db.Exec(`
+			INSERT INTO payments (sale_id, method, amount, paid_at, reference)
+			SELECT s.id, $1, `+saleTotalSQL+`, s.created_at, 'Recorded before payment tracking'
+			FROM sales s
+			WHERE s.status = ANY($2) AND `+saleTotalSQL+` > 0`,
+			PaymentMethodOther, pq.Array(paidSaleStatuses))
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.ensureUpdatedAtTrigger("payments")
+}
+
+const paymentColumns = `
+	id, sale_id, method, amount, paid_at, reference, created_by, created_at, updated_at`
+
+func (s *PostgresStore) CreatePayment(payment *Payment) error {
+	query := `
+        INSERT INTO payments (
+            sale_id,
+            method,
+            amount,
+            paid_at,
+            reference,
+            created_by
+        )
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		payment.SaleID,
+		payment.Method,
+		payment.Amount,
+		payment.PaidAt,
+		payment.Reference,
+		payment.CreatedBy,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted payment
+	payment.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetPaymentByID(saleID, id string) (*Payment, error) {
+	rows, err := s.db.Query(`
+		SELECT `+paymentColumns+`
+		FROM payments WHERE sale_id = $1 AND id = $2`, saleID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoPayments(rows)
+	}
+
+	return nil, fmt.Errorf("payment [%s] not found", id)
+}
+
+func (s *PostgresStore) GetPayments(saleID string) ([]Payment, error) {
+	paymentsBySale, err := s.getPaymentsBySaleIDs([]string{saleID})
+	if err != nil {
+		return nil, err
+	}
+	return paymentsBySale[saleID], nil
+}
+
+func (s *PostgresStore) getPaymentsBySaleIDs(saleIDs []string) (map[string][]Payment, error) {
+	paymentsBySale := make(map[string][]Payment)
+	if len(saleIDs) == 0 {
+		return paymentsBySale, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+paymentColumns+`
+		FROM payments
+		WHERE sale_id = ANY($1)
+		ORDER BY paid_at`, pq.Array(saleIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		payment, err := scanIntoPayments(rows)
+		if err != nil {
+			return nil, err
+		}
+		paymentsBySale[payment.SaleID] = append(paymentsBySale[payment.SaleID], *payment)
+	}
+
+	return paymentsBySale, rows.Err()
+}
+
+func scanIntoPayments(rows *sql.Rows) (*Payment, error) {
+	payment := new(Payment)
+	var reference, createdBy sql.NullString
+
+	err := rows.Scan(
+		&payment.ID,
+		&payment.SaleID,
+		&payment.Method,
+		&payment.Amount,
+		&payment.PaidAt,
+		&reference,
+		&createdBy,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.Reference = nullStringToPtr(reference)
+	payment.CreatedBy = nullStringToPtr(createdBy)
+
+	return payment, nil
+}
+
+func (s *PostgresStore) DeletePayment(saleID, id string) error {
+	_, err := s.db.Exec("DELETE FROM payments WHERE sale_id = $1 AND id = $2", saleID, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
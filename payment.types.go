@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Payment methods. Other is used for payments recorded before payment
+// tracking existed.
+const (
+	PaymentMethodBankTransfer   = "bank_transfer"
+	PaymentMethodNequi          = "nequi"
+	PaymentMethodCashOnDelivery = "cash_on_delivery"
+	PaymentMethodCard           = "card"
+	PaymentMethodOther          = "other"
+)
+
+func isValidPaymentMethod(method string) bool {
+	switch method {
+	case PaymentMethodBankTransfer, PaymentMethodNequi, PaymentMethodCashOnDelivery, PaymentMethodCard, PaymentMethodOther:
+		return true
+	}
+	return false
+}
+
+// Payment is an amount paid towards a sale, a sale can be paid in several
+// instalments and with different methods.
+type Payment struct {
+	ID        string    `json:"id"`
+	SaleID    string    `json:"sale_id"`
+	Method    string    `json:"method"`
+	Amount    Money     `json:"amount"`
+	PaidAt    time.Time `json:"paid_at"`
+	Reference *string   `json:"reference"`
+	CreatedBy *string   `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreatePaymentRequest struct {
+	Method    string     `json:"method"`
+	Amount    Money      `json:"amount"`
+	PaidAt    *time.Time `json:"paid_at"`
+	Reference *string    `json:"reference"`
+}
+
+// NewPayment creates a payment, paid now unless paidAt is given.
+func NewPayment(
+	saleID string,
+	method string,
+	amount Money,
+	paidAt *time.Time,
+	reference *string,
+	createdBy *string,
+) (*Payment, error) {
+	method = strings.TrimSpace(method)
+	if !isValidPaymentMethod(method) {
+		return nil, fmt.Errorf("invalid payment method [%s]", method)
+	}
+	if err := validatePrice(amount); err != nil {
+		return nil, fmt.Errorf("amount: %w", err)
+	}
+	if amount.IsNegative() || amount.IsZero() {
+		return nil, fmt.Errorf("amount must be greater than zero")
+	}
+
+	payment := &Payment{
+		SaleID:    saleID,
+		Method:    method,
+		Amount:    amount,
+		PaidAt:    time.Now().UTC(),
+		Reference: reference,
+		CreatedBy: createdBy,
+	}
+	if paidAt != nil {
+		payment.PaidAt = paidAt.UTC()
+	}
+
+	return payment, nil
+}
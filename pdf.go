@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Letter size in points, the unit of every coordinate. The origin is the
+// bottom left corner of the page.
+const (
+	pdfPageWidth  = 612
+	pdfPageHeight = 792
+)
+
+// pdfDocument is a minimal PDF writer for text documents such as receipts.
+// It only knows the standard Helvetica fonts, so no font is embedded and text
+// is limited to the Windows-1252 characters, which cover Spanish.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{}
+	doc.AddPage()
+	return doc
+}
+
+func (doc *pdfDocument) AddPage() {
+	doc.pages = append(doc.pages, new(bytes.Buffer))
+}
+
+func (doc *pdfDocument) page() *bytes.Buffer {
+	return doc.pages[len(doc.pages)-1]
+}
+
+// Text writes s with its baseline starting at x, y. Colors are RGB values
+// between 0 and 1.
+func (doc *pdfDocument) Text(x, y, size float64, bold bool, color [3]float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(doc.page(), "BT /%s %.2f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+		font, size, color[0], color[1], color[2], x, y, pdfEscape(s))
+}
+
+// TextRight writes s so that it ends at x.
+func (doc *pdfDocument) TextRight(x, y, size float64, bold bool, color [3]float64, s string) {
+	doc.Text(x-pdfTextWidth(s, size, bold), y, size, bold, color, s)
+}
+
+func (doc *pdfDocument) FillRect(x, y, width, height float64, color [3]float64) {
+	fmt.Fprintf(doc.page(), "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		color[0], color[1], color[2], x, y, width, height)
+}
+
+func (doc *pdfDocument) Line(x1, y1, x2, y2 float64, color [3]float64) {
+	fmt.Fprintf(doc.page(), "%.3f %.3f %.3f RG 0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		color[0], color[1], color[2], x1, y1, x2, y2)
+}
+
+// Bytes renders the document.
+func (doc *pdfDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	startObject := func() int {
+		offsets = append(offsets, out.Len())
+		id := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n", id)
+		return id
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and the fonts, pages
+	// and their contents follow
+	pageIDs := make([]string, len(doc.pages))
+	for i := range doc.pages {
+		pageIDs[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	startObject()
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	startObject()
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(pageIDs, " "), len(doc.pages))
+	startObject()
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+	startObject()
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	for _, content := range doc.pages {
+		id := startObject()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pdfPageWidth, pdfPageHeight, id+1)
+
+		startObject()
+		fmt.Fprintf(&out, "<< /Length %d >>\nstream\n", content.Len())
+		out.Write(content.Bytes())
+		out.WriteString("endstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// pdfEscape encodes s for a PDF string in the Windows-1252 encoding of the
+// fonts. Latin letters outside it lose their accents, e.g. Łukasz is written
+// Lukasz, and any other character is replaced with a question mark.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		case winAnsiSpecials[r] != 0:
+			b.WriteByte(winAnsiSpecials[r])
+		case r >= 0x100 && r < 0x180:
+			b.WriteByte(latinExtendedABase[r-0x100])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// winAnsiSpecials are the characters Windows-1252 has between 0x80 and 0x9f,
+// where Latin-1 has control characters.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// latinExtendedABase are the letters of U+0100 to U+017F without their
+// accents.
+const latinExtendedABase = "" +
+	"AaAaAaCcCcCcCcDd" +
+	"DdEeEeEeEeEeGgGg" +
+	"GgGgHhHhIiIiIiIi" +
+	"IiIiJjKkkLlLlLlL" +
+	"lLlNnNnNnnNnOoOo" +
+	"OoOoRrRrRrSsSsSs" +
+	"SsTtTtTtUuUuUuUu" +
+	"UuUuWwYyYZzZzZzs"
+
+// Widths of the Helvetica characters, in thousandths of the font size.
+// Characters not listed are taken as wide as a digit.
+var helveticaWidths = map[rune]float64{
+	' ': 278, ',': 278, '.': 278, '-': 333, ':': 278, '/': 278, '#': 556, '$': 556, '%': 889,
+	'A': 667, 'B': 667, 'C': 722, 'D': 722, 'E': 667, 'F': 611, 'G': 778, 'H': 722, 'I': 278,
+	'J': 500, 'K': 667, 'L': 556, 'M': 833, 'N': 722, 'O': 778, 'P': 667, 'Q': 778, 'R': 722,
+	'S': 667, 'T': 611, 'U': 722, 'V': 667, 'W': 944, 'X': 667, 'Y': 667, 'Z': 611,
+	'f': 278, 'i': 222, 'j': 222, 'l': 222, 'm': 833, 'r': 333, 't': 278, 'w': 722, 'x': 500,
+	'y': 500, 'z': 500, 'c': 500, 'k': 500, 's': 500, 'v': 500,
+}
+
+// pdfTextWidth approximates the width of s in points. Bold text is taken as
+// slightly wider, which is enough to right align amounts.
+func pdfTextWidth(s string, size float64, bold bool) float64 {
+	var width float64
+	for _, r := range s {
+		w, ok := helveticaWidths[r]
+		if !ok {
+			w = 556
+		}
+		width += w
+	}
+	if bold {
+		width *= 1.05
+	}
+	return width * size / 1000
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+func (server *APIServer) handleGetPriceHistory(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := server.store.GetPriceChanges(productID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, changes)
+}
+
+func (server *APIServer) handleCreatePriceSchedule(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	product, err := server.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+
+	req := new(CreatePriceScheduleRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	schedule, err := NewPriceSchedule(
+		productID,
+		req.Kind,
+		req.Price,
+		req.StartsAt,
+		req.EndsAt,
+		getAuthUserID(r),
+	)
+	if err != nil {
+		return err
+	}
+
+	if schedule.Kind == PriceScheduleKindSale {
+		if err := product.checkSalePrice(schedule.Price); err != nil {
+			return err
+		}
+	}
+
+	if err := server.store.CreatePriceSchedule(schedule); err != nil {
+		return err
+	}
+
+	// Schedules starting now or in the past are applied right away
+	if err := server.store.ApplyDuePriceSchedules(time.Now()); err != nil {
+		return err
+	}
+
+	// Recovering schedule from DB
+	createdSchedule, err := server.store.GetPriceScheduleByID(productID, schedule.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdSchedule)
+}
+
+func (server *APIServer) handleGetPriceSchedules(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+
+	schedules, err := server.store.GetPriceSchedules(productID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, schedules)
+}
+
+func (server *APIServer) handleCancelPriceSchedule(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	scheduleID, err := getRouteUUID(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	schedule, err := server.store.GetPriceScheduleByID(productID, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CancelPriceSchedule(schedule, getAuthUserID(r)); err != nil {
+		return err
+	}
+
+	cancelledSchedule, err := server.store.GetPriceScheduleByID(productID, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, cancelledSchedule)
+}
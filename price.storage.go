@@ -0,0 +1,415 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (s *PostgresStore) CreatePriceTables() error {
+	// Create the tables if they don't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS product_price_schedules (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+            kind VARCHAR(20) NOT NULL,
+            price BIGINT NOT NULL,
+            starts_at TIMESTAMPTZ NOT NULL,
+            ends_at TIMESTAMPTZ,
+            status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+            created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE INDEX IF NOT EXISTS product_price_schedules_status_idx ON product_price_schedules (status, starts_at);
+
+        CREATE TABLE IF NOT EXISTS product_price_changes (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+            variant_id UUID REFERENCES catalog_variants(id) ON DELETE CASCADE,
+            field VARCHAR(20) NOT NULL,
+            old_price BIGINT,
+            new_price BIGINT,
+            changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+            schedule_id UUID REFERENCES product_price_schedules(id) ON DELETE SET NULL,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        ALTER TABLE product_price_changes ADD COLUMN IF NOT EXISTS variant_id UUID REFERENCES catalog_variants(id) ON DELETE CASCADE;
+
+        CREATE INDEX IF NOT EXISTS product_price_changes_product_id_idx ON product_price_changes (product_id, created_at);
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("product_price_schedules")
+}
+
+func (s *PostgresStore) GetPriceChanges(productID string) ([]*PriceChange, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			pc.id,
+			pc.product_id,
+			pc.variant_id,
+			pc.field,
+			pc.old_price,
+			pc.new_price,
+			pc.changed_by,
+			NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''),
+			pc.schedule_id,
+			pc.created_at
+		FROM
+			product_price_changes pc
+		LEFT JOIN
+			users u ON pc.changed_by = u.id
+		WHERE
+			pc.product_id = $1
+		ORDER BY
+			pc.created_at DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var changes []*PriceChange
+	for rows.Next() {
+		change := new(PriceChange)
+		var variantID, changedBy, changedByName, scheduleID sql.NullString
+		err := rows.Scan(
+			&change.ID,
+			&change.ProductID,
+			&variantID,
+			&change.Field,
+			&change.OldPrice,
+			&change.NewPrice,
+			&changedBy,
+			&changedByName,
+			&scheduleID,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		change.VariantID = nullStringToPtr(variantID)
+		change.ChangedBy = nullStringToPtr(changedBy)
+		change.ChangedByName = nullStringToPtr(changedByName)
+		change.ScheduleID = nullStringToPtr(scheduleID)
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// CreatePriceSchedule stores a schedule. The sale windows of a product can't
+// overlap, the end of one would remove the sale price of the other.
+func (s *PostgresStore) CreatePriceSchedule(schedule *PriceSchedule) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if schedule.Kind == PriceScheduleKindSale {
+			// Locking the product serializes the sales created for it
+			if _, err := tx.Exec(`SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, schedule.ProductID); err != nil {
+				return err
+			}
+
+			var overlappingID string
+			err := tx.QueryRow(`
+				SELECT id
+				FROM product_price_schedules
+				WHERE
+					product_id = $1 AND kind = $2 AND status IN ($3, $4)
+					AND ($6::TIMESTAMPTZ IS NULL OR starts_at < $6)
+					AND (ends_at IS NULL OR ends_at > $5)
+				LIMIT 1`,
+				schedule.ProductID, PriceScheduleKindSale,
+				PriceScheduleStatusScheduled, PriceScheduleStatusActive,
+				schedule.StartsAt, schedule.EndsAt).Scan(&overlappingID)
+			if err == nil {
+				return fmt.Errorf("the sale overlaps the sale schedule [%s]", overlappingID)
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		query := `
+			INSERT INTO product_price_schedules (
+				product_id,
+				kind,
+				price,
+				starts_at,
+				ends_at,
+				status,
+				created_by
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`
+
+		return tx.QueryRow(
+			query,
+			schedule.ProductID,
+			schedule.Kind,
+			schedule.Price,
+			schedule.StartsAt,
+			schedule.EndsAt,
+			schedule.Status,
+			schedule.CreatedBy,
+		).Scan(&schedule.ID)
+	})
+}
+
+const priceScheduleColumns = `
+	id, product_id, kind, price, starts_at, ends_at,
+	status, created_by, created_at, updated_at`
+
+func (s *PostgresStore) GetPriceScheduleByID(productID, id string) (*PriceSchedule, error) {
+	rows, err := s.db.Query(`
+		SELECT `+priceScheduleColumns+`
+		FROM product_price_schedules WHERE product_id = $1 AND id = $2`, productID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoPriceSchedules(rows)
+	}
+
+	return nil, fmt.Errorf("price schedule [%s] not found", id)
+}
+
+func (s *PostgresStore) GetPriceSchedules(productID string) ([]*PriceSchedule, error) {
+	rows, err := s.db.Query(`
+		SELECT `+priceScheduleColumns+`
+		FROM product_price_schedules WHERE product_id = $1
+		ORDER BY starts_at DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var schedules []*PriceSchedule
+	for rows.Next() {
+		schedule, err := scanIntoPriceSchedules(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+func scanIntoPriceSchedules(rows *sql.Rows) (*PriceSchedule, error) {
+	schedule := new(PriceSchedule)
+	var endsAt sql.NullTime
+	var createdBy sql.NullString
+
+	err := rows.Scan(
+		&schedule.ID,
+		&schedule.ProductID,
+		&schedule.Kind,
+		&schedule.Price,
+		&schedule.StartsAt,
+		&endsAt,
+		&schedule.Status,
+		&createdBy,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if endsAt.Valid {
+		schedule.EndsAt = &endsAt.Time
+	}
+	schedule.CreatedBy = nullStringToPtr(createdBy)
+
+	return schedule, nil
+}
+
+// CancelPriceSchedule cancels a pending schedule. Cancelling an active sale
+// removes the sale price right away. The schedule is locked so the scheduler
+// can't apply it meanwhile.
+func (s *PostgresStore) CancelPriceSchedule(schedule *PriceSchedule, userID *string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRow(`
+			SELECT status FROM product_price_schedules WHERE id = $1 FOR UPDATE`, schedule.ID).Scan(&status)
+		if err != nil {
+			return err
+		}
+		if status != PriceScheduleStatusScheduled && status != PriceScheduleStatusActive {
+			return fmt.Errorf("price schedule [%s] is already %s", schedule.ID, status)
+		}
+
+		if status == PriceScheduleStatusActive {
+			err := setProductPriceTx(tx, schedule.ProductID, PriceFieldSalePrice, nil, userID, &schedule.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE product_price_schedules SET status = $1 WHERE id = $2`,
+			PriceScheduleStatusCancelled, schedule.ID)
+		return err
+	})
+}
+
+// ApplyDuePriceSchedules applies every scheduled price change and sale price
+// whose time has come, and ends the sales that are over. Each change is
+// recorded in the price history on behalf of the user who scheduled it.
+//
+// Schedules are locked one at a time in the transaction applying them and
+// the ones locked elsewhere are skipped, so the scheduler and the handlers
+// never apply a schedule twice.
+func (s *PostgresStore) ApplyDuePriceSchedules(now time.Time) error {
+	for {
+		var schedule *PriceSchedule
+		err := s.withTx(func(tx *sql.Tx) error {
+			rows, err := tx.Query(`
+				SELECT `+priceScheduleColumns+`
+				FROM product_price_schedules
+				WHERE
+					(status = $1 AND starts_at <= $3)
+					OR (status = $2 AND ends_at <= $3)
+				ORDER BY starts_at
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED`,
+				PriceScheduleStatusScheduled, PriceScheduleStatusActive, now.UTC())
+			if err != nil {
+				return err
+			}
+			if rows.Next() {
+				schedule, err = scanIntoPriceSchedules(rows)
+			} else {
+				err = rows.Err()
+			}
+			if closeErr := rows.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil || schedule == nil {
+				return err
+			}
+
+			return applyPriceScheduleTx(tx, schedule, now)
+		})
+		if err != nil {
+			if schedule != nil {
+				return fmt.Errorf("error applying price schedule [%s]: %v", schedule.ID, err)
+			}
+			return err
+		}
+		if schedule == nil {
+			return nil
+		}
+	}
+}
+
+func applyPriceScheduleTx(tx *sql.Tx, schedule *PriceSchedule, now time.Time) error {
+	price := schedule.Price
+	ended := schedule.EndsAt != nil && !schedule.EndsAt.After(now)
+	status := PriceScheduleStatusCompleted
+
+	var err error
+	switch {
+	case schedule.Kind == PriceScheduleKindPrice:
+		err = setProductPriceTx(tx, schedule.ProductID, PriceFieldPrice, &price, schedule.CreatedBy, &schedule.ID)
+	case schedule.Status == PriceScheduleStatusActive && ended:
+		err = setProductPriceTx(tx, schedule.ProductID, PriceFieldSalePrice, nil, schedule.CreatedBy, &schedule.ID)
+	case ended:
+		// The whole sale window passed while the scheduler was not running
+	default:
+		err = setProductPriceTx(tx, schedule.ProductID, PriceFieldSalePrice, &price, schedule.CreatedBy, &schedule.ID)
+		status = PriceScheduleStatusActive
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE product_price_schedules SET status = $1 WHERE id = $2`, status, schedule.ID)
+	return err
+}
+
+// setProductPriceTx sets the price, sale price or unit cost of a product and
+// records the change in the price history. Nothing is recorded when the value
+// is unchanged.
+func setProductPriceTx(tx *sql.Tx, productID, field string, newPrice *Money, changedBy, scheduleID *string) error {
+	var oldPrice *Money
+	err := tx.QueryRow(`
+		SELECT `+field+` FROM products WHERE id = $1 FOR UPDATE`, productID).Scan(&oldPrice)
+	if err != nil {
+		return err
+	}
+
+	if sameMoney(oldPrice, newPrice) {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE products SET `+field+` = $1 WHERE id = $2`, newPrice, productID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO product_price_changes (product_id, field, old_price, new_price, changed_by, schedule_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		productID, field, oldPrice, newPrice, changedBy, scheduleID)
+	return err
+}
+
+// setVariantPriceTx sets the price or unit cost of a catalog variant and
+// records the change in the price history of its product. Nothing is recorded
+// when the value is unchanged.
+func setVariantPriceTx(tx *sql.Tx, productID, variantID, field string, newPrice *Money, changedBy *string) error {
+	var oldPrice *Money
+	err := tx.QueryRow(`
+		SELECT `+field+` FROM catalog_variants
+		WHERE id = $1 AND product_id = $2 FOR UPDATE`, variantID, productID).Scan(&oldPrice)
+	if err != nil {
+		return err
+	}
+
+	if sameMoney(oldPrice, newPrice) {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE catalog_variants SET `+field+` = $1 WHERE id = $2`, newPrice, variantID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO product_price_changes (product_id, variant_id, field, old_price, new_price, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		productID, variantID, field, oldPrice, newPrice, changedBy)
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Fields of a product whose changes are kept in the price history. Unit cost
+// changes of a variant are kept with its VariantID.
+const (
+	PriceFieldPrice     = "price"
+	PriceFieldSalePrice = "sale_price"
+	PriceFieldUnitCost  = "unit_cost"
+)
+
+// Kinds of scheduled price changes. A "price" schedule replaces the list
+// price at StartsAt, a "sale" schedule sets a sale price between StartsAt and
+// EndsAt and restores the list price afterwards.
+const (
+	PriceScheduleKindPrice = "price"
+	PriceScheduleKindSale  = "sale"
+)
+
+const (
+	PriceScheduleStatusScheduled = "scheduled"
+	PriceScheduleStatusActive    = "active"
+	PriceScheduleStatusCompleted = "completed"
+	PriceScheduleStatusCancelled = "cancelled"
+)
+
+// PriceChange is an entry of a product's price history. OldPrice and NewPrice
+// are nil when a sale price or a unit cost is set or removed.
+type PriceChange struct {
+	ID            string    `json:"id"`
+	ProductID     string    `json:"product_id"`
+	VariantID     *string   `json:"variant_id,omitempty"`
+	Field         string    `json:"field"`
+	OldPrice      *Money    `json:"old_price"`
+	NewPrice      *Money    `json:"new_price"`
+	ChangedBy     *string   `json:"changed_by"`
+	ChangedByName *string   `json:"changed_by_name"`
+	ScheduleID    *string   `json:"schedule_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// sameMoney reports whether two optional amounts are equal.
+func sameMoney(a, b *Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+type PriceSchedule struct {
+	ID        string     `json:"id"`
+	ProductID string     `json:"product_id"`
+	Kind      string     `json:"kind"`
+	Price     Money      `json:"price"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	Status    string     `json:"status"`
+	CreatedBy *string    `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type CreatePriceScheduleRequest struct {
+	Kind     string     `json:"kind"`
+	Price    Money      `json:"price"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+func NewPriceSchedule(
+	productID string,
+	kind string,
+	price Money,
+	startsAt time.Time,
+	endsAt *time.Time,
+	createdBy *string,
+) (*PriceSchedule, error) {
+	if kind != PriceScheduleKindPrice && kind != PriceScheduleKindSale {
+		return nil, fmt.Errorf("invalid price schedule kind [%s]", kind)
+	}
+	if err := validatePrice(price); err != nil {
+		return nil, err
+	}
+	if price.IsNegative() || price.IsZero() {
+		return nil, fmt.Errorf("price must be greater than zero")
+	}
+	if startsAt.IsZero() {
+		return nil, fmt.Errorf("starts_at is required")
+	}
+	if kind == PriceScheduleKindPrice && endsAt != nil {
+		return nil, fmt.Errorf("ends_at is only allowed for sale prices")
+	}
+	if endsAt != nil && !endsAt.After(startsAt) {
+		return nil, fmt.Errorf("ends_at must be after starts_at")
+	}
+
+	return &PriceSchedule{
+		ProductID: productID,
+		Kind:      kind,
+		Price:     price,
+		StartsAt:  startsAt.UTC(),
+		EndsAt:    endsAt,
+		Status:    PriceScheduleStatusScheduled,
+		CreatedBy: createdBy,
+	}, nil
+}
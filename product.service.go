@@ -2,11 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
-	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 func (server *APIServer) handleCreateProduct(w http.ResponseWriter, r *http.Request) error {
@@ -25,6 +26,11 @@ func (server *APIServer) handleCreateProduct(w http.ResponseWriter, r *http.Requ
 		return err
 	}
 
+	if err := validateUnitCost(req.UnitCost); err != nil {
+		return err
+	}
+	product.UnitCost = req.UnitCost
+
 	imageUrl, err := BucketBasics.UploadFile(BucketBasics{S3Client: server.s3Client}, product.Image)
 	if err != nil {
 		return err
@@ -38,16 +44,34 @@ func (server *APIServer) handleCreateProduct(w http.ResponseWriter, r *http.Requ
 		product.IsCatalogReady = *req.IsCatalogReady
 	}
 
-	// Process catalog variants if provided
-	if len(req.CatalogVariants) > 0 {
-		processedVariants, err := server.processCatalogVariants(req.CatalogVariants)
+	product.SKU, product.Barcode, err = server.normalizeProductCodes("", "", req.SKU, req.Barcode)
+	if err != nil {
+		return err
+	}
+
+	product.CategoryID = req.CategoryID
+	if err := server.validateProductCategory(product.CategoryID); err != nil {
+		return err
+	}
+	if product.Tags, err = normalizeTags(req.Tags); err != nil {
+		return err
+	}
+
+	// Catalog variants are stored with the product, or not at all
+	var variants []*CatalogVariant
+	for i, variantReq := range req.CatalogVariants {
+		if variantReq.Position == nil {
+			position := i
+			variantReq.Position = &position
+		}
+		variant, err := server.newCatalogVariant("", variantReq)
 		if err != nil {
 			return err
 		}
-		product.CatalogVariants = processedVariants
+		variants = append(variants, variant)
 	}
 
-	if err := server.store.CreateProduct(product); err != nil {
+	if err := server.store.CreateProduct(product, variants); err != nil {
 		return err
 	}
 
@@ -61,8 +85,8 @@ func (server *APIServer) handleCreateProduct(w http.ResponseWriter, r *http.Requ
 	return WriteJSON(w, http.StatusOK, createdProduct)
 }
 
-func (server *APIServer) handleGetProducts(w http.ResponseWriter, _ *http.Request) error {
-	products, err := server.store.GetProducts()
+func (server *APIServer) handleGetProducts(w http.ResponseWriter, r *http.Request) error {
+	products, err := server.store.GetProducts(getProductFilter(r))
 	if err != nil {
 		return err
 	}
@@ -98,6 +122,12 @@ func (server *APIServer) handleUpdateProduct(w http.ResponseWriter, r *http.Requ
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
 		return err
 	}
+	if err := validatePrice(product.Price); err != nil {
+		return err
+	}
+	if err := validateUnitCost(product.UnitCost); err != nil {
+		return err
+	}
 
 	// Check if the image has changed, if it changed, it will always be a new base64 file
 	if oldProduct.Image != product.Image {
@@ -116,18 +146,23 @@ func (server *APIServer) handleUpdateProduct(w http.ResponseWriter, r *http.Requ
 
 	}
 
-	// Process catalog variants if provided
-	if len(product.CatalogVariants) > 0 {
-		processedVariants, err := server.processCatalogVariants(product.CatalogVariants)
-		if err != nil {
-			return err
-		}
-		product.CatalogVariants = processedVariants
+	// Catalog variants are managed through /api/products/{id}/variants
+	product.ID = id
+
+	product.SKU, product.Barcode, err = server.normalizeProductCodes(id, "", product.SKU, product.Barcode)
+	if err != nil {
+		return err
 	}
 
-	product.ID = id
+	if err := server.validateProductCategory(product.CategoryID); err != nil {
+		return err
+	}
+	if product.Tags, err = normalizeTags(product.Tags); err != nil {
+		return err
+	}
 
-	if err := server.store.UpdateProduct(&product); err != nil {
+	// Price and unit cost changes are kept in the price history
+	if err := server.store.UpdateProduct(&product, getAuthUserID(r)); err != nil {
 		return err
 	}
 
@@ -150,6 +185,16 @@ func (server *APIServer) handleDeleteProduct(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		return err
 	}
+
+	// Sold products are part of the earnings history, they can only be archived
+	hasSales, err := server.store.ProductHasSales(id)
+	if err != nil {
+		return err
+	}
+	if hasSales {
+		return fmt.Errorf("product [%s] has sales and can't be deleted, archive it instead", id)
+	}
+
 	// delete the old aws image
 	err = BucketBasics.DeleteFile(BucketBasics{S3Client: server.s3Client}, product.Image)
 	if err != nil {
@@ -163,37 +208,180 @@ func (server *APIServer) handleDeleteProduct(w http.ResponseWriter, r *http.Requ
 	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
 }
 
-func (server *APIServer) processCatalogVariants(variants []CatalogVariant) ([]CatalogVariant, error) {
-	now := time.Now()
-	for i := range variants {
-		// Generate UUID if empty
-		if variants[i].ID == "" {
-			variants[i].ID = uuid.New().String()
+// getProductFilter reads the category, tag and collection query parameters.
+func getProductFilter(r *http.Request) ProductFilter {
+	query := r.URL.Query()
+	return ProductFilter{
+		Search:          strings.TrimSpace(query.Get("q")),
+		Category:        query.Get("category"),
+		Tag:             strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Collection:      query.Get("collection"),
+		Color:           strings.TrimSpace(query.Get("color")),
+		InStockOnly:     query.Get("in_stock") == "true",
+		IncludeArchived: query.Get("include_archived") == "true",
+	}
+}
+
+// maxCatalogPageSize caps the page size of the public catalog.
+const maxCatalogPageSize = 100
+
+// setCatalogPagination reads the page and page_size query params into the
+// filter. Listings are only paginated when page_size is given.
+func setCatalogPagination(r *http.Request, filter *ProductFilter) error {
+	query := r.URL.Query()
+	if query.Get("page_size") == "" {
+		return nil
+	}
+
+	pageSize, err := strconv.Atoi(query.Get("page_size"))
+	if err != nil || pageSize <= 0 {
+		return fmt.Errorf("invalid page_size [%s]", query.Get("page_size"))
+	}
+	if pageSize > maxCatalogPageSize {
+		pageSize = maxCatalogPageSize
+	}
+
+	page := 1
+	if query.Get("page") != "" {
+		page, err = strconv.Atoi(query.Get("page"))
+		if err != nil || page <= 0 {
+			return fmt.Errorf("invalid page [%s]", query.Get("page"))
 		}
-		// Set timestamps
-		if variants[i].CreatedAt.IsZero() {
-			variants[i].CreatedAt = now
+	}
+
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+	return nil
+}
+
+// validateProductCategory checks that the category a product is assigned to exists.
+func (server *APIServer) validateProductCategory(categoryID *string) error {
+	if categoryID == nil {
+		return nil
+	}
+	_, err := server.store.GetCategoryByID(*categoryID)
+	return err
+}
+
+// normalizeProductCodes normalizes a SKU and barcode pair and checks that
+// neither is already used by another product or variant. productID and
+// variantID identify the current owner of the codes when updating.
+func (server *APIServer) normalizeProductCodes(productID, variantID string, sku, barcode *string) (*string, *string, error) {
+	sku = normalizeSKU(sku)
+	barcode, err := normalizeBarcode(barcode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, code := range []*string{sku, barcode} {
+		if code == nil {
+			continue
+		}
+		taken, err := server.store.IsProductCodeTaken(*code, productID, variantID)
+		if err != nil {
+			return nil, nil, err
 		}
-		variants[i].UpdatedAt = now
-
-		// Upload image to S3 if base64
-		if strings.HasPrefix(variants[i].Image, "data:image") {
-			url, err := BucketBasics.UploadFile(BucketBasics{S3Client: server.s3Client}, variants[i].Image)
-			if err != nil {
-				return nil, err
-			}
-			variants[i].Image = url
+		if taken {
+			return nil, nil, fmt.Errorf("code [%s] is already in use", *code)
 		}
 	}
-	return variants, nil
+
+	return sku, barcode, nil
+}
+
+func (server *APIServer) handleGetProductByCode(w http.ResponseWriter, r *http.Request) error {
+	code := strings.TrimSpace(mux.Vars(r)["code"])
+	if code == "" {
+		return fmt.Errorf("code is required")
+	}
+
+	// SKUs are stored upper-cased, barcodes are digits only
+	lookup, err := server.store.GetProductByCode(strings.ToUpper(code))
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, lookup)
 }
 
-func (server *APIServer) handleGetPublicProducts(w http.ResponseWriter, _ *http.Request) error {
-	products, err := server.store.GetCatalogProducts()
+// uploadImageIfBase64 uploads the image to S3 when it is a base64 data URL and
+// returns the resulting URL, any other value is returned untouched.
+func (server *APIServer) uploadImageIfBase64(image string) (string, error) {
+	if !strings.HasPrefix(image, "data:image") {
+		return image, nil
+	}
+	return BucketBasics.UploadFile(BucketBasics{S3Client: server.s3Client}, image)
+}
+
+func (server *APIServer) handleArchiveProduct(w http.ResponseWriter, r *http.Request) error {
+	return server.setProductArchived(w, r, true)
+}
+
+func (server *APIServer) handleUnarchiveProduct(w http.ResponseWriter, r *http.Request) error {
+	return server.setProductArchived(w, r, false)
+}
+
+// setProductArchived archives or restores the product in the request path and
+// writes it back.
+func (server *APIServer) setProductArchived(w http.ResponseWriter, r *http.Request, archived bool) error {
+	id, err := getID(r)
 	if err != nil {
 		return err
 	}
-	return WriteJSON(w, http.StatusOK, products)
+
+	_, err = server.store.GetProductByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.SetProductArchived(id, archived); err != nil {
+		return err
+	}
+
+	updatedProduct, err := server.store.GetProductByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedProduct)
+}
+
+func (server *APIServer) handleGetPublicProducts(w http.ResponseWriter, r *http.Request) error {
+	filter := getProductFilter(r)
+	filter.IncludeArchived = false
+	if err := setCatalogPagination(r, &filter); err != nil {
+		return err
+	}
+
+	products, err := server.store.GetCatalogProducts(filter)
+	if err != nil {
+		return err
+	}
+
+	total, err := server.store.CountCatalogProducts(filter)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if products == nil {
+		products = []*Product{}
+	}
+	return WriteCachedJSON(w, r, http.StatusOK, products)
+}
+
+func (server *APIServer) handleGetPublicProductByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	product, err := server.store.GetCatalogProductByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteCachedJSON(w, r, http.StatusOK, product)
 }
 
 func (server *APIServer) handlePublicProducts(w http.ResponseWriter, r *http.Request) error {
@@ -202,3 +390,10 @@ func (server *APIServer) handlePublicProducts(w http.ResponseWriter, r *http.Req
 	}
 	return WriteJSON(w, http.StatusMethodNotAllowed, apiError{Error: "unsupported method: " + r.Method})
 }
+
+func (server *APIServer) handlePublicProductWithID(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodGet {
+		return server.handleGetPublicProductByID(w, r)
+	}
+	return WriteJSON(w, http.StatusMethodNotAllowed, apiError{Error: "unsupported method: " + r.Method})
+}
@@ -2,9 +2,10 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
+
+	"github.com/lib/pq"
 )
 
 func (s *PostgresStore) CreateProductsTable() error {
@@ -14,14 +15,32 @@ func (s *PostgresStore) CreateProductsTable() error {
             id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
             name VARCHAR(255) NOT NULL,
             price BIGINT NOT NULL,
+            sale_price BIGINT,
+            unit_cost BIGINT,
             image VARCHAR(255) NOT NULL,
             available_colors VARCHAR(20)[] NOT NULL DEFAULT '{}'::VARCHAR(20)[],
+            sku VARCHAR(64),
+            barcode VARCHAR(14),
+            category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+            tags VARCHAR(50)[] NOT NULL DEFAULT '{}'::VARCHAR(50)[],
             description TEXT,
             is_catalog_ready BOOLEAN DEFAULT FALSE,
-            catalog_variants JSONB,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )
+            archived_at TIMESTAMPTZ,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS sale_price BIGINT;
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS tags VARCHAR(50)[] NOT NULL DEFAULT '{}'::VARCHAR(50)[];
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+        ALTER TABLE products ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
+        CREATE INDEX IF NOT EXISTS products_category_id_idx ON products (category_id);
+        CREATE INDEX IF NOT EXISTS products_tags_idx ON products USING GIN (tags);
+        CREATE UNIQUE INDEX IF NOT EXISTS products_sku_idx ON products (sku) WHERE sku IS NOT NULL;
+        CREATE UNIQUE INDEX IF NOT EXISTS products_barcode_idx ON products (barcode) WHERE barcode IS NOT NULL;
     `)
 	if err != nil {
 		return err
@@ -97,46 +116,60 @@ func (s *PostgresStore) CreateProductsTable() error {
 	return nil
 }
 
-func (s *PostgresStore) CreateProduct(product *Product) error {
+// CreateProduct stores a product with its catalog variants in a transaction,
+// so a failing variant leaves no product behind.
+func (s *PostgresStore) CreateProduct(product *Product, variants []*CatalogVariant) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if err := insertProduct(tx, product); err != nil {
+			return err
+		}
+		for _, variant := range variants {
+			variant.ProductID = product.ID
+			if err := insertCatalogVariant(tx, variant); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
+func insertProduct(db rowQueryer, product *Product) error {
 	query := `
         INSERT INTO products (
             name,
             price,
+            unit_cost,
             image,
             available_colors,
+            sku,
+            barcode,
+            category_id,
+            tags,
             description,
             is_catalog_ready,
-            catalog_variants,
             created_at,
             updated_at
         )
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
         RETURNING id
     `
 
 	availableColorsDB := ConvertToDBArray(product.AvailableColors)
 
-	var catalogVariantsJSON interface{}
-	if len(product.CatalogVariants) > 0 {
-		jsonBytes, err := json.Marshal(product.CatalogVariants)
-		if err != nil {
-			return err
-		}
-		catalogVariantsJSON = jsonBytes
-	}
-	// catalogVariantsJSON is nil if no variants, which translates to SQL NULL
-
 	var id string
-	err := s.db.QueryRow(
+	err := db.QueryRow(
 		query,
 		product.Name,
 		product.Price,
+		product.UnitCost,
 		product.Image,
 		availableColorsDB,
+		product.SKU,
+		product.Barcode,
+		product.CategoryID,
+		pq.Array(product.Tags),
 		product.Description,
 		product.IsCatalogReady,
-		catalogVariantsJSON,
 		product.CreatedAt,
 		product.UpdatedAt,
 	).Scan(&id)
@@ -152,9 +185,7 @@ func (s *PostgresStore) CreateProduct(product *Product) error {
 
 func (s *PostgresStore) GetProductByID(id string) (*Product, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, price, image, available_colors,
-		       description, is_catalog_ready, catalog_variants,
-		       created_at, updated_at
+		SELECT `+productColumns+`
 		FROM products WHERE id = $1`, id)
 	if err != nil {
 		return nil, err
@@ -168,27 +199,47 @@ func (s *PostgresStore) GetProductByID(id string) (*Product, error) {
 	}(rows)
 
 	for rows.Next() {
-		return scanIntoProducts(rows)
+		product, err := scanIntoProducts(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.attachCatalogVariants(product); err != nil {
+			return nil, err
+		}
+		return product, nil
 	}
 
 	return nil, fmt.Errorf("product [%s] not found", id)
 }
 
+const productColumns = `
+	id, name, price, sale_price, unit_cost, image, available_colors, sku, barcode, category_id, tags,
+	description, is_catalog_ready, archived_at, created_at, updated_at`
+
 func scanIntoProducts(rows *sql.Rows) (*Product, error) {
 	product := new(Product)
 	var availableColorsDB string
+	var sku sql.NullString
+	var barcode sql.NullString
+	var categoryID sql.NullString
 	var description sql.NullString
-	var catalogVariantsJSON []byte
+	var archivedAt sql.NullTime
 
 	err := rows.Scan(
 		&product.ID,
 		&product.Name,
 		&product.Price,
+		&product.SalePrice,
+		&product.UnitCost,
 		&product.Image,
 		&availableColorsDB,
+		&sku,
+		&barcode,
+		&categoryID,
+		pq.Array(&product.Tags),
 		&description,
 		&product.IsCatalogReady,
-		&catalogVariantsJSON,
+		&archivedAt,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
@@ -198,26 +249,128 @@ func scanIntoProducts(rows *sql.Rows) (*Product, error) {
 
 	product.AvailableColors = ConvertFromDBArray(availableColorsDB)
 
+	if sku.Valid {
+		product.SKU = &sku.String
+	}
+
+	if barcode.Valid {
+		product.Barcode = &barcode.String
+	}
+
+	if categoryID.Valid {
+		product.CategoryID = &categoryID.String
+	}
+
+	if product.Tags == nil {
+		product.Tags = []string{}
+	}
+
 	if description.Valid {
 		product.Description = &description.String
 	}
 
-	if catalogVariantsJSON != nil {
-		err = json.Unmarshal(catalogVariantsJSON, &product.CatalogVariants)
-		if err != nil {
-			return nil, err
-		}
+	if archivedAt.Valid {
+		product.ArchivedAt = &archivedAt.Time
 	}
 
 	return product, nil
 }
 
-func (s *PostgresStore) GetProducts() ([]*Product, error) {
+// productFilterClause builds the SQL conditions for the given filter, appending
+// its values to args. The returned clause starts with AND when not empty.
+func productFilterClause(filter ProductFilter, args []any) (string, []any) {
+	clause := ""
+
+	if !filter.IncludeArchived {
+		clause += `
+			AND archived_at IS NULL`
+	}
+
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		clause += fmt.Sprintf(`
+			AND category_id IN (
+				WITH RECURSIVE tree AS (
+					SELECT id FROM categories WHERE id::TEXT = $%d OR slug = $%d
+					UNION
+					SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+				)
+				SELECT id FROM tree
+			)`, len(args), len(args))
+	}
+
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		clause += fmt.Sprintf(`
+			AND $%d = ANY(tags)`, len(args))
+	}
+
+	if filter.Collection != "" {
+		args = append(args, filter.Collection)
+		clause += fmt.Sprintf(`
+			AND id IN (
+				SELECT cp.product_id FROM collection_products cp
+				JOIN collections c ON c.id = cp.collection_id
+				WHERE c.id::TEXT = $%d OR c.slug = $%d
+			)`, len(args), len(args))
+	}
+
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		clause += fmt.Sprintf(`
+			AND (
+				name ILIKE $%d
+				OR description ILIKE $%d
+				OR sku ILIKE $%d
+				OR ARRAY_TO_STRING(tags, ' ') ILIKE $%d
+			)`, len(args), len(args), len(args), len(args))
+	}
+
+	if filter.Color != "" {
+		args = append(args, filter.Color)
+		clause += fmt.Sprintf(`
+			AND (
+				EXISTS (
+					SELECT 1 FROM catalog_variants cv
+					WHERE cv.product_id = products.id
+					AND (LOWER(cv.color_name) = LOWER($%d) OR LOWER(cv.color_hex) = LOWER($%d))
+				)
+				OR LOWER($%d) = ANY(SELECT LOWER(color) FROM UNNEST(available_colors) AS color)
+			)`, len(args), len(args), len(args))
+	}
+
+	// A product is available when it has no variants or one of them has stock
+	if filter.InStockOnly {
+		clause += `
+			AND (
+				NOT EXISTS (SELECT 1 FROM catalog_variants cv WHERE cv.product_id = products.id)
+				OR EXISTS (
+					SELECT 1 FROM catalog_variants cv
+					WHERE cv.product_id = products.id AND (cv.stock IS NULL OR cv.stock > 0)
+				)
+			)`
+	}
+
+	return clause, args
+}
+
+// paginationClause builds the LIMIT and OFFSET of a listing, nothing is added
+// when the filter has no limit.
+func paginationClause(filter ProductFilter, args []any) (string, []any) {
+	if filter.Limit <= 0 {
+		return "", args
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	return fmt.Sprintf(`
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args
+}
+
+func (s *PostgresStore) GetProducts(filter ProductFilter) ([]*Product, error) {
+	clause, args := productFilterClause(filter, nil)
 	rows, err := s.db.Query(`
-		SELECT id, name, price, image, available_colors,
-		       description, is_catalog_ready, catalog_variants,
-		       created_at, updated_at
-		FROM products`)
+		SELECT `+productColumns+`
+		FROM products WHERE TRUE `+clause, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -239,46 +392,57 @@ func (s *PostgresStore) GetProducts() ([]*Product, error) {
 		products = append(products, product)
 	}
 
+	if err := s.attachCatalogVariants(products...); err != nil {
+		return nil, err
+	}
+
 	return products, nil
 }
 
-func (s *PostgresStore) UpdateProduct(product *Product) error {
+// UpdateProduct stores the changes of a product. Changes of its price and
+// unit cost are recorded in the price history in the same transaction, on
+// behalf of changedBy.
+func (s *PostgresStore) UpdateProduct(product *Product, changedBy *string) error {
 	query := `
 		UPDATE products
 		SET
 		    name = $1,
-		    price = $2,
-		    image = $3,
-		    available_colors = $4,
-		    description = $5,
-		    is_catalog_ready = $6,
-		    catalog_variants = $7
-		WHERE id = $8
+		    image = $2,
+		    available_colors = $3,
+		    sku = $4,
+		    barcode = $5,
+		    category_id = $6,
+		    tags = $7,
+		    description = $8,
+		    is_catalog_ready = $9
+		WHERE id = $10
 	`
 
 	availableColorsDB := ConvertToDBArray(product.AvailableColors)
 
-	var catalogVariantsJSON interface{}
-	if len(product.CatalogVariants) > 0 {
-		jsonBytes, err := json.Marshal(product.CatalogVariants)
+	err := s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			query,
+			product.Name,
+			product.Image,
+			&availableColorsDB,
+			product.SKU,
+			product.Barcode,
+			product.CategoryID,
+			pq.Array(product.Tags),
+			product.Description,
+			product.IsCatalogReady,
+			product.ID,
+		)
 		if err != nil {
 			return err
 		}
-		catalogVariantsJSON = jsonBytes
-	}
-	// catalogVariantsJSON is nil if no variants, which translates to SQL NULL
 
-	_, err := s.db.Exec(
-		query,
-		product.Name,
-		product.Price,
-		product.Image,
-		&availableColorsDB,
-		product.Description,
-		product.IsCatalogReady,
-		catalogVariantsJSON,
-		product.ID,
-	)
+		if err := setProductPriceTx(tx, product.ID, PriceFieldPrice, &product.Price, changedBy, nil); err != nil {
+			return err
+		}
+		return setProductPriceTx(tx, product.ID, PriceFieldUnitCost, product.UnitCost, changedBy, nil)
+	})
 	if err != nil {
 		return err
 	}
@@ -288,12 +452,13 @@ func (s *PostgresStore) UpdateProduct(product *Product) error {
 	return nil
 }
 
-func (s *PostgresStore) GetCatalogProducts() ([]*Product, error) {
+func (s *PostgresStore) GetCatalogProducts(filter ProductFilter) ([]*Product, error) {
+	clause, args := productFilterClause(filter, nil)
+	pagination, args := paginationClause(filter, args)
 	rows, err := s.db.Query(`
-		SELECT id, name, price, image, available_colors,
-		       description, is_catalog_ready, catalog_variants,
-		       created_at, updated_at
-		FROM products WHERE is_catalog_ready = true ORDER BY created_at DESC`)
+		SELECT `+productColumns+`
+		FROM products WHERE is_catalog_ready = true `+clause+`
+		ORDER BY created_at DESC, id`+pagination, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -311,15 +476,151 @@ func (s *PostgresStore) GetCatalogProducts() ([]*Product, error) {
 		if err != nil {
 			return nil, err
 		}
-		sanitizeProduct(product)
 		products = append(products, product)
 	}
 
+	if err := s.attachCatalogVariants(products...); err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		sanitizeProduct(product)
+	}
+
 	return products, nil
 }
 
+// CountCatalogProducts counts the catalog products matching the filter,
+// ignoring its pagination.
+func (s *PostgresStore) CountCatalogProducts(filter ProductFilter) (int, error) {
+	clause, args := productFilterClause(filter, nil)
+
+	var count int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM products WHERE is_catalog_ready = true `+clause, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetCatalogProductByID returns a catalog ready product that is not archived,
+// sanitized for the public catalog.
+func (s *PostgresStore) GetCatalogProductByID(id string) (*Product, error) {
+	rows, err := s.db.Query(`
+		SELECT `+productColumns+`
+		FROM products
+		WHERE id = $1 AND is_catalog_ready = true AND archived_at IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		product, err := scanIntoProducts(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.attachCatalogVariants(product); err != nil {
+			return nil, err
+		}
+
+		sanitizeProduct(product)
+		return product, nil
+	}
+
+	return nil, fmt.Errorf("product [%s] not found", id)
+}
+
+// GetProductByCode finds the product or catalog variant that owns the given
+// SKU or barcode.
+func (s *PostgresStore) GetProductByCode(code string) (*ProductCodeLookup, error) {
+	var productID string
+	var variantID sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, NULL FROM products WHERE sku = $1 OR barcode = $1
+		UNION ALL
+		SELECT product_id, id FROM catalog_variants WHERE sku = $1 OR barcode = $1
+		LIMIT 1`, code).Scan(&productID, &variantID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product with code [%s] not found", code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := &ProductCodeLookup{Product: product}
+	if variantID.Valid {
+		for i := range product.CatalogVariants {
+			if product.CatalogVariants[i].ID == variantID.String {
+				lookup.Variant = &product.CatalogVariants[i]
+			}
+		}
+	}
+
+	return lookup, nil
+}
+
+// IsProductCodeTaken reports whether the SKU or barcode is already used by any
+// product or variant other than productID and variantID, either of which can
+// be empty.
+func (s *PostgresStore) IsProductCodeTaken(code, productID, variantID string) (bool, error) {
+	var taken bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM products
+			WHERE (sku = $1 OR barcode = $1) AND id::TEXT != $2
+			UNION ALL
+			SELECT 1 FROM catalog_variants
+			WHERE (sku = $1 OR barcode = $1) AND id::TEXT != $3)`, code, productID, variantID).Scan(&taken)
+	if err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+// sanitizeProduct hides what the public catalog must not show, unit costs
+// included.
 func sanitizeProduct(p *Product) {
 	p.AvailableColors = nil
+	p.UnitCost = nil
+	for i := range p.CatalogVariants {
+		p.CatalogVariants[i].UnitCost = nil
+	}
+}
+
+// SetProductArchived archives or restores a product.
+func (s *PostgresStore) SetProductArchived(id string, archived bool) error {
+	_, err := s.db.Exec(`
+		UPDATE products
+		SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, CURRENT_TIMESTAMP) END
+		WHERE id = $2`, archived, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ProductHasSales reports whether the product was ever sold.
+func (s *PostgresStore) ProductHasSales(id string) (bool, error) {
+	var hasSales bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM product_variations WHERE product_id = $1)`, id).Scan(&hasSales)
+	if err != nil {
+		return false, err
+	}
+	return hasSales, nil
 }
 
 func (s *PostgresStore) DeleteProduct(id string) error {
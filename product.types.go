@@ -1,45 +1,151 @@
 package main
 
-import "time"
-
-type CatalogVariant struct {
-	ID        string    `json:"id"`
-	ColorHex  string    `json:"color_hex"`
-	ColorName string    `json:"color_name"`
-	Image     string    `json:"image"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Product struct {
 	ID              string           `json:"id"`
 	Name            string           `json:"name"`
-	Price           int              `json:"price"`
+	Price           Money            `json:"price"`
+	SalePrice       *Money           `json:"sale_price,omitempty"`
+	UnitCost        *Money           `json:"unit_cost,omitempty"`
 	Image           string           `json:"image"`
 	AvailableColors []string         `json:"available_colors"`
+	SKU             *string          `json:"sku,omitempty"`
+	Barcode         *string          `json:"barcode,omitempty"`
+	CategoryID      *string          `json:"category_id,omitempty"`
+	Tags            []string         `json:"tags"`
 	Description     *string          `json:"description,omitempty"`
 	IsCatalogReady  bool             `json:"is_catalog_ready"`
+	ArchivedAt      *time.Time       `json:"archived_at,omitempty"`
+	InStock         bool             `json:"in_stock"`
 	CatalogVariants []CatalogVariant `json:"catalog_variants,omitempty"`
 	CreatedAt       time.Time        `json:"created_at"`
 	UpdatedAt       time.Time        `json:"updated_at"`
 }
 
 type CreateProductRequest struct {
-	Name            string           `json:"name"`
-	Price           int              `json:"price"`
-	Image           string           `json:"image"`
-	AvailableColors []string         `json:"available_colors"`
-	Description     *string          `json:"description,omitempty"`
-	IsCatalogReady  *bool            `json:"is_catalog_ready,omitempty"`
-	CatalogVariants []CatalogVariant `json:"catalog_variants,omitempty"`
+	Name            string                        `json:"name"`
+	Price           Money                         `json:"price"`
+	UnitCost        *Money                        `json:"unit_cost,omitempty"`
+	Image           string                        `json:"image"`
+	AvailableColors []string                      `json:"available_colors"`
+	SKU             *string                       `json:"sku,omitempty"`
+	Barcode         *string                       `json:"barcode,omitempty"`
+	CategoryID      *string                       `json:"category_id,omitempty"`
+	Tags            []string                      `json:"tags"`
+	Description     *string                       `json:"description,omitempty"`
+	IsCatalogReady  *bool                         `json:"is_catalog_ready,omitempty"`
+	CatalogVariants []CreateCatalogVariantRequest `json:"catalog_variants,omitempty"`
+}
+
+// IsArchived reports whether the product was archived, archived products are
+// kept for the sales history but can't be sold anymore.
+func (p *Product) IsArchived() bool {
+	return p.ArchivedAt != nil
+}
+
+// CurrentPrice is the price a product sells for right now, its sale price
+// while a sale is running and its list price otherwise.
+func (p *Product) CurrentPrice() Money {
+	if p.SalePrice != nil {
+		return *p.SalePrice
+	}
+	return p.Price
+}
+
+// PriceForColor is the price the product sells for right now in the given
+// color. A running sale applies to every color, variants with their own price
+// included, otherwise the price of the variant applies when it has one.
+func (p *Product) PriceForColor(color string) Money {
+	if variant := p.FindVariant(color); p.SalePrice == nil && variant != nil && variant.Price != nil {
+		return *variant.Price
+	}
+	return p.CurrentPrice()
+}
+
+// checkSalePrice makes sure a sale lowers the price of the product in every
+// color, since the sale price also replaces the prices of its variants.
+func (p *Product) checkSalePrice(price Money) error {
+	if price.Cmp(p.Price) >= 0 {
+		return fmt.Errorf("sale price must be lower than the product price %s", formatCurrency(p.Price))
+	}
+	for _, variant := range p.CatalogVariants {
+		if variant.Price != nil && price.Cmp(*variant.Price) >= 0 {
+			return fmt.Errorf("sale price must be lower than the price %s of color [%s]", formatCurrency(*variant.Price), variant.ColorName)
+		}
+	}
+	return nil
+}
+
+// UnitCostForColor is what a unit of the product costs in the given color,
+// the cost of its variant when the variant has one. It is nil when no cost
+// was set.
+func (p *Product) UnitCostForColor(color string) *Money {
+	if variant := p.FindVariant(color); variant != nil && variant.UnitCost != nil {
+		return variant.UnitCost
+	}
+	return p.UnitCost
+}
+
+// FindVariant returns the catalog variant with the given color name or hex,
+// ignoring case, or nil when there is none.
+func (p *Product) FindVariant(color string) *CatalogVariant {
+	for i, variant := range p.CatalogVariants {
+		if strings.EqualFold(variant.ColorName, color) || strings.EqualFold(variant.ColorHex, color) {
+			return &p.CatalogVariants[i]
+		}
+	}
+	return nil
+}
+
+// HasColor reports whether the product is offered in the given color, either
+// as one of its available colors or as a catalog variant.
+func (p *Product) HasColor(color string) bool {
+	for _, available := range p.AvailableColors {
+		if strings.EqualFold(available, color) {
+			return true
+		}
+	}
+	return p.FindVariant(color) != nil
+}
+
+// ProductFilter narrows product listings. Category and Collection accept
+// either an ID or a slug, a category also matches its subcategories.
+// Archived products are left out unless IncludeArchived is set.
+// Limit and Offset paginate the results when Limit is positive.
+type ProductFilter struct {
+	Search          string
+	Category        string
+	Tag             string
+	Collection      string
+	Color           string
+	InStockOnly     bool
+	IncludeArchived bool
+	Limit           int
+	Offset          int
+}
+
+// ProductCodeLookup is the result of looking up a SKU or barcode, Variant is
+// set when the code belongs to a catalog variant instead of the product itself.
+type ProductCodeLookup struct {
+	Product *Product        `json:"product"`
+	Variant *CatalogVariant `json:"variant,omitempty"`
 }
 
 func NewProduct(
 	name string,
-	price int,
+	price Money,
 	image string,
 	availableColors []string,
 ) (*Product, error) {
+	if err := validatePrice(price); err != nil {
+		return nil, err
+	}
+
 	return &Product{
 		Name:            name,
 		Price:           price,
@@ -47,3 +153,30 @@ func NewProduct(
 		AvailableColors: availableColors,
 	}, nil
 }
+
+// validatePrice checks a product or sale line price. They are stored in whole
+// pesos.
+func validatePrice(price Money) error {
+	if price.Currency != baseCurrency {
+		return fmt.Errorf("price must be in %s, got %s", baseCurrency, price.Currency)
+	}
+	if !price.IsWholeUnits() {
+		return fmt.Errorf("price [%s] must be a whole amount of pesos", price)
+	}
+	return nil
+}
+
+// validateUnitCost checks the optional unit cost of a product or variant, it
+// is stored in whole pesos like prices.
+func validateUnitCost(cost *Money) error {
+	if cost == nil {
+		return nil
+	}
+	if err := validatePrice(*cost); err != nil {
+		return fmt.Errorf("unit cost: %w", err)
+	}
+	if cost.IsNegative() {
+		return fmt.Errorf("unit cost can't be negative")
+	}
+	return nil
+}
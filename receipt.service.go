@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	receiptDark  = [3]float64{0.067, 0.094, 0.153}
+	receiptMuted = [3]float64{0.42, 0.45, 0.5}
+	receiptWhite = [3]float64{1, 1, 1}
+	receiptBlack = [3]float64{0.1, 0.1, 0.1}
+)
+
+func (server *APIServer) handleIssueSaleReceipt(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	sale, err := server.store.GetSaleByID(id)
+	if err != nil {
+		return err
+	}
+	if sale.Status == SaleStatusCancelled {
+		return fmt.Errorf("sale [%s] is cancelled", id)
+	}
+
+	receipt, err := server.store.IssueReceipt(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, receipt)
+}
+
+// handleGetSaleReceipt renders the receipt of a sale, it has to be issued
+// first so reading it never takes a receipt number.
+func (server *APIServer) handleGetSaleReceipt(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	sale, err := server.store.GetSaleByID(id)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := server.store.GetReceiptBySaleID(id)
+	if err != nil {
+		return err
+	}
+
+	pdf := renderReceiptPDF(sale, receipt)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="recibo-%s.pdf"`, receipt.Code()))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(pdf)
+	return err
+}
+
+// renderReceiptPDF draws the receipt of a sale. The business name and the
+// footer come from RECEIPT_BUSINESS_NAME and RECEIPT_FOOTER.
+func renderReceiptPDF(sale *SaleResponse, receipt *Receipt) []byte {
+	businessName := os.Getenv("RECEIPT_BUSINESS_NAME")
+	if businessName == "" {
+		businessName = "Dashboard"
+	}
+
+	const left, right = 50.0, pdfPageWidth - 50.0
+	doc := newPDFDocument()
+
+	// Header
+	doc.FillRect(0, pdfPageHeight-100, pdfPageWidth, 100, receiptDark)
+	doc.Text(left, pdfPageHeight-55, 22, true, receiptWhite, businessName)
+	doc.TextRight(right, pdfPageHeight-50, 12, true, receiptWhite, "Recibo "+receipt.Code())
+	doc.TextRight(right, pdfPageHeight-68, 9, false, receiptWhite, "Emitido el "+receipt.IssuedAt.In(businessLocation()).Format("2006-01-02"))
+
+	// Customer snapshot
+	y := float64(pdfPageHeight - 135)
+	doc.Text(left, y, 9, true, receiptMuted, "CLIENTE")
+	doc.TextRight(right, y, 9, true, receiptMuted, "VENTA")
+	y -= 16
+	doc.Text(left, y, 11, true, receiptBlack, sale.CustomerName)
+	doc.TextRight(right, y, 9, false, receiptBlack, "Pedido "+sale.OrderNumber)
+	y -= 14
+	doc.Text(left, y, 9, false, receiptBlack, "cc "+sale.CustomerCc)
+	doc.TextRight(right, y, 9, false, receiptBlack, "Fecha "+formatReceiptDate(sale.CreatedAt))
+	y -= 14
+	doc.Text(left, y, 9, false, receiptBlack, sale.CustomerAddress)
+	doc.TextRight(right, y, 9, false, receiptBlack, "Estado "+sale.Status)
+	y -= 14
+	doc.Text(left, y, 9, false, receiptBlack, sale.CustomerCity+" / "+sale.CustomerDepartment)
+	y -= 14
+	doc.Text(left, y, 9, false, receiptBlack, strconv.Itoa(sale.CustomerPhone))
+	if sale.CustomerInstagramAccount != "" {
+		y -= 14
+		doc.Text(left, y, 9, false, receiptBlack, "@"+sale.CustomerInstagramAccount)
+	}
+
+	// Line items
+	const colColor, colQuantity, colPrice = 290.0, 400.0, 480.0
+	tableHeader := func() {
+		doc.FillRect(left, y-6, right-left, 20, receiptDark)
+		doc.Text(left+6, y, 9, true, receiptWhite, "Producto")
+		doc.Text(colColor, y, 9, true, receiptWhite, "Color")
+		doc.TextRight(colQuantity, y, 9, true, receiptWhite, "Cant.")
+		doc.TextRight(colPrice, y, 9, true, receiptWhite, "Precio")
+		doc.TextRight(right-6, y, 9, true, receiptWhite, "Total")
+		y -= 24
+	}
+
+	y -= 36
+	tableHeader()
+	subtotal := NewMoney(0, baseCurrency)
+	for _, line := range sale.ProductVariations {
+		if y < 170 {
+			doc.AddPage()
+			y = pdfPageHeight - 60
+			tableHeader()
+		}
+
+		subtotal = subtotal.Add(line.LineTotal)
+		doc.Text(left+6, y, 9, false, receiptBlack, line.Name)
+		doc.Text(colColor, y, 9, false, receiptBlack, line.Color)
+		doc.TextRight(colQuantity, y, 9, false, receiptBlack, strconv.Itoa(line.Quantity))
+		doc.TextRight(colPrice, y, 9, false, receiptBlack, formatCurrency(line.Price))
+		doc.TextRight(right-6, y, 9, false, receiptBlack, formatCurrency(line.LineTotal))
+		if !line.DiscountAmount.IsZero() {
+			y -= 12
+			doc.Text(left+6, y, 8, false, receiptMuted, "Descuento "+formatCurrency(line.DiscountAmount))
+		}
+		y -= 8
+		doc.Line(left, y, right, y, receiptMuted)
+		y -= 14
+	}
+
+	// Totals
+	shipping := NewMoney(0, baseCurrency)
+	for _, shipment := range sale.Shipments {
+		if shipment.ChargeCustomer {
+			shipping = shipping.Add(shipment.Cost)
+		}
+	}
+
+	type receiptTotal struct {
+		label string
+		value Money
+	}
+	totals := []receiptTotal{{"Subtotal", subtotal}}
+	if !sale.DiscountAmount.IsZero() {
+		label := "Descuento"
+		if sale.CouponCode != nil {
+			label += " (" + *sale.CouponCode + ")"
+		}
+		totals = append(totals, receiptTotal{label, NewMoney(-sale.DiscountAmount.Amount, sale.DiscountAmount.Currency)})
+	}
+	if !shipping.IsZero() {
+		totals = append(totals, receiptTotal{"Envío", shipping})
+	}
+
+	y -= 6
+	for _, total := range totals {
+		doc.Text(colQuantity-40, y, 9, false, receiptBlack, total.label)
+		doc.TextRight(right-6, y, 9, false, receiptBlack, formatReceiptAmount(total.value))
+		y -= 16
+	}
+	doc.FillRect(colQuantity-46, y-6, right-colQuantity+46, 20, receiptDark)
+	doc.Text(colQuantity-40, y, 10, true, receiptWhite, "Total")
+	doc.TextRight(right-6, y, 10, true, receiptWhite, formatCurrency(sale.Total))
+	y -= 24
+	doc.Text(colQuantity-40, y, 9, false, receiptBlack, "Pagado")
+	doc.TextRight(right-6, y, 9, false, receiptBlack, formatCurrency(sale.AmountPaid))
+	y -= 16
+	doc.Text(colQuantity-40, y, 9, true, receiptBlack, "Saldo")
+	doc.TextRight(right-6, y, 9, true, receiptBlack, formatCurrency(sale.Balance))
+
+	if footer := os.Getenv("RECEIPT_FOOTER"); footer != "" {
+		doc.Text(left, 40, 8, false, receiptMuted, footer)
+	}
+
+	return doc.Bytes()
+}
+
+func formatReceiptAmount(value Money) string {
+	if value.IsNegative() {
+		return "- " + formatCurrency(NewMoney(-value.Amount, value.Currency))
+	}
+	return formatCurrency(value)
+}
+
+// formatReceiptDate keeps the date of a timestamp returned by the database, as
+// the day it was in the business time zone.
+func formatReceiptDate(value string) string {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.In(businessLocation()).Format("2006-01-02")
+		}
+	}
+	return value
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const receiptCounter = "receipts"
+
+func (s *PostgresStore) CreateReceiptsTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS receipts (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            sale_id UUID NOT NULL UNIQUE REFERENCES sales(id) ON DELETE RESTRICT,
+            number BIGINT NOT NULL UNIQUE,
+            issued_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+    `)
+	return err
+}
+
+// IssueReceipt issues the receipt of the sale with the next receipt number.
+// A sale that already has a receipt keeps it and it is returned instead.
+func (s *PostgresStore) IssueReceipt(saleID string) (*Receipt, error) {
+	receipt := new(Receipt)
+	err := s.withTx(func(tx *sql.Tx) error {
+		// Lock the sale so concurrent requests don't issue two receipts
+		_, err := tx.Exec(`SELECT id FROM sales WHERE id = $1 FOR UPDATE`, saleID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(`
+			SELECT id, sale_id, number, issued_at
+			FROM receipts WHERE sale_id = $1`, saleID,
+		).Scan(&receipt.ID, &receipt.SaleID, &receipt.Number, &receipt.IssuedAt)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		number, err := nextCounterValue(tx, receiptCounter)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRow(`
+			INSERT INTO receipts (sale_id, number)
+			VALUES ($1, $2)
+			RETURNING id, sale_id, number, issued_at`, saleID, number,
+		).Scan(&receipt.ID, &receipt.SaleID, &receipt.Number, &receipt.IssuedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// GetReceiptBySaleID returns the receipt of the sale without issuing it.
+func (s *PostgresStore) GetReceiptBySaleID(saleID string) (*Receipt, error) {
+	receipt := new(Receipt)
+	err := s.db.QueryRow(`
+		SELECT id, sale_id, number, issued_at
+		FROM receipts WHERE sale_id = $1`, saleID,
+	).Scan(&receipt.ID, &receipt.SaleID, &receipt.Number, &receipt.IssuedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sale [%s] has no receipt yet", saleID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
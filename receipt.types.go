@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Receipt is the receipt issued for a sale. Numbers are sequential and have no
+// gaps, a sale keeps its receipt number once it is issued.
+type Receipt struct {
+	ID       string    `json:"id"`
+	SaleID   string    `json:"sale_id"`
+	Number   int       `json:"number"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// Code is the receipt number as printed, e.g. R-000042.
+func (r *Receipt) Code() string {
+	return fmt.Sprintf("R-%06d", r.Number)
+}
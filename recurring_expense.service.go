@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func (server *APIServer) handleCreateRecurringExpense(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateRecurringExpenseRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	recurring, err := NewRecurringExpense(req)
+	if err != nil {
+		return err
+	}
+
+	if err := server.validateExpenseRelations(recurring.CategoryID, recurring.VendorID, &recurring.Type); err != nil {
+		return err
+	}
+
+	if err := server.store.CreateRecurringExpense(recurring); err != nil {
+		return err
+	}
+
+	// Recovering recurring expense from DB
+	createdRecurring, err := server.store.GetRecurringExpenseByID(recurring.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdRecurring)
+}
+
+func (server *APIServer) handleGetRecurringExpenses(w http.ResponseWriter, _ *http.Request) error {
+	recurringExpenses, err := server.store.GetRecurringExpenses(false)
+	if err != nil {
+		return err
+	}
+	if recurringExpenses == nil {
+		recurringExpenses = []*RecurringExpense{}
+	}
+	return WriteJSON(w, http.StatusOK, recurringExpenses)
+}
+
+func (server *APIServer) handleGetRecurringExpenseByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	recurring, err := server.store.GetRecurringExpenseByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, recurring)
+}
+
+func (server *APIServer) handleUpdateRecurringExpense(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetRecurringExpenseByID(id)
+	if err != nil {
+		return err
+	}
+
+	req := new(CreateRecurringExpenseRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	recurring, err := NewRecurringExpense(req)
+	if err != nil {
+		return err
+	}
+
+	recurring.ID = id
+	if err := server.validateExpenseRelations(recurring.CategoryID, recurring.VendorID, &recurring.Type); err != nil {
+		return err
+	}
+
+	if err := server.store.UpdateRecurringExpense(recurring); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedRecurring, err := server.store.GetRecurringExpenseByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedRecurring)
+}
+
+func (server *APIServer) handleDeleteRecurringExpense(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetRecurringExpenseByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteRecurringExpense(id); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
+
+// handleGetUpcomingExpenses previews the occurrences of the active recurring
+// expenses in the next ?days= days, 30 by default.
+func (server *APIServer) handleGetUpcomingExpenses(w http.ResponseWriter, r *http.Request) error {
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxUpcomingDays {
+			return fmt.Errorf("days must be a number between 1 and %d", maxUpcomingDays)
+		}
+	}
+	until := localDate(time.Now(), businessLocation()).AddDate(0, 0, days)
+
+	recurringExpenses, err := server.store.GetRecurringExpenses(true)
+	if err != nil {
+		return err
+	}
+
+	upcoming := []UpcomingExpense{}
+	for _, recurring := range recurringExpenses {
+		exceptions, err := server.store.GetRecurringExpenseExceptions(recurring)
+		if err != nil {
+			return err
+		}
+
+		for _, date := range recurring.OccurrencesBetween(recurring.GeneratedUntil, until) {
+			occurrence := UpcomingExpense{
+				RecurringExpenseID: recurring.ID,
+				Name:               recurring.Name,
+				Date:               date,
+				Price:              recurring.Price,
+				Currency:           recurring.Currency,
+				Description:        recurring.Description,
+				Status:             OccurrenceStatusScheduled,
+			}
+
+			if exception, ok := exceptions[date.Format(time.DateOnly)]; ok {
+				if exception.Action == OccurrenceActionSkip {
+					occurrence.Status = OccurrenceStatusSkipped
+				} else {
+					occurrence.Status = OccurrenceStatusOverridden
+					if exception.Price != nil {
+						occurrence.Price = *exception.Price
+					}
+					if exception.Description != nil {
+						occurrence.Description = *exception.Description
+					}
+				}
+			}
+
+			upcoming = append(upcoming, occurrence)
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Date.Before(upcoming[j].Date)
+	})
+
+	return WriteJSON(w, http.StatusOK, upcoming)
+}
+
+// handleUpdateOccurrence skips or overrides the occurrence of a recurring
+// expense on the date of the URL. Only occurrences that were not created yet
+// can be changed, created ones are edited as expenses.
+func (server *APIServer) handleUpdateOccurrence(w http.ResponseWriter, r *http.Request) error {
+	recurring, date, err := server.getOccurrence(r)
+	if err != nil {
+		return err
+	}
+
+	req := new(UpdateOccurrenceRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	exception := &RecurringExpenseException{
+		RecurringExpenseID: recurring.ID,
+		Date:               date,
+		Action:             req.Action,
+	}
+	switch req.Action {
+	case OccurrenceActionSkip:
+	case OccurrenceActionOverride:
+		if req.Price == nil && req.Description == nil {
+			return fmt.Errorf("an override needs a price or a description")
+		}
+		if req.Price != nil {
+			price, err := ParseMoney(req.Price.String(), recurring.Currency)
+			if err != nil {
+				return err
+			}
+			if price.IsNegative() {
+				return fmt.Errorf("price can't be negative")
+			}
+			exception.Price = &price
+		}
+		exception.Description = req.Description
+	default:
+		return fmt.Errorf("invalid action [%s], use skip or override", req.Action)
+	}
+
+	if err := server.store.SaveRecurringExpenseException(exception); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, exception)
+}
+
+// handleDeleteOccurrence undoes the skip or override of an occurrence.
+func (server *APIServer) handleDeleteOccurrence(w http.ResponseWriter, r *http.Request) error {
+	recurring, date, err := server.getOccurrence(r)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteRecurringExpenseException(recurring.ID, date); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": date.Format(time.DateOnly)})
+}
+
+// getOccurrence reads the recurring expense and the occurrence date of the
+// URL, checking the expense is due on that date and wasn't created yet.
+func (server *APIServer) getOccurrence(r *http.Request) (*RecurringExpense, time.Time, error) {
+	id, err := getID(r)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	recurring, err := server.store.GetRecurringExpenseByID(id)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	value := mux.Vars(r)["date"]
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid date [%s], use YYYY-MM-DD", value)
+	}
+
+	if !recurring.IsOccurrence(date) {
+		return nil, time.Time{}, fmt.Errorf("recurring expense [%s] is not due on %s", id, value)
+	}
+	if recurring.GeneratedUntil != nil && !date.After(*recurring.GeneratedUntil) {
+		return nil, time.Time{}, fmt.Errorf("the expense of %s was already created, edit it instead", value)
+	}
+
+	return recurring, date, nil
+}
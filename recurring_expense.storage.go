@@ -0,0 +1,451 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (s *PostgresStore) CreateRecurringExpensesTables() error {
+	// Create the tables if they don't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS recurring_expenses (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            price NUMERIC(18, 3) NOT NULL,
+            currency VARCHAR(3) NOT NULL,
+            type VARCHAR(255) NOT NULL,
+            description VARCHAR(255) NOT NULL,
+            category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL,
+            vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL,
+            cadence VARCHAR(20) NOT NULL,
+            start_date DATE NOT NULL,
+            end_date DATE,
+            generated_until DATE,
+            is_active BOOLEAN NOT NULL DEFAULT TRUE,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE TABLE IF NOT EXISTS recurring_expense_exceptions (
+            recurring_expense_id UUID NOT NULL REFERENCES recurring_expenses(id) ON DELETE CASCADE,
+            occurrence_date DATE NOT NULL,
+            action VARCHAR(20) NOT NULL,
+            price NUMERIC(18, 3),
+            description VARCHAR(255),
+            PRIMARY KEY (recurring_expense_id, occurrence_date)
+        );
+
+        -- Every occurrence is created once
+        ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_expense_id UUID REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+        ALTER TABLE expenses ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+        CREATE UNIQUE INDEX IF NOT EXISTS expenses_recurring_occurrence_idx ON expenses (recurring_expense_id, occurrence_date);
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("recurring_expenses")
+}
+
+const recurringExpenseColumns = `
+	id, name, price, currency, type, description, category_id, vendor_id, cadence,
+	start_date, end_date, generated_until, is_active, created_at, updated_at`
+
+func (s *PostgresStore) CreateRecurringExpense(recurring *RecurringExpense) error {
+	query := `
+        INSERT INTO recurring_expenses (
+            name,
+            price,
+            currency,
+            type,
+            description,
+            category_id,
+            vendor_id,
+            cadence,
+            start_date,
+            end_date,
+            is_active
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		recurring.Name,
+		recurring.Price,
+		recurring.Currency,
+		recurring.Type,
+		recurring.Description,
+		recurring.CategoryID,
+		recurring.VendorID,
+		recurring.Cadence,
+		recurring.StartDate,
+		recurring.EndDate,
+		recurring.IsActive,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted recurring expense
+	recurring.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetRecurringExpenseByID(id string) (*RecurringExpense, error) {
+	rows, err := s.db.Query(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoRecurringExpenses(rows)
+	}
+
+	return nil, fmt.Errorf("recurring expense [%s] not found", id)
+}
+
+// GetRecurringExpenses lists every recurring expense, or only the active ones
+// with activeOnly.
+func (s *PostgresStore) GetRecurringExpenses(activeOnly bool) ([]*RecurringExpense, error) {
+	rows, err := s.db.Query(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE NOT $1 OR is_active
+		ORDER BY name`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var recurringExpenses []*RecurringExpense
+	for rows.Next() {
+		recurring, err := scanIntoRecurringExpenses(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		recurringExpenses = append(recurringExpenses, recurring)
+	}
+
+	return recurringExpenses, nil
+}
+
+func scanIntoRecurringExpenses(rows *sql.Rows) (*RecurringExpense, error) {
+	recurring := new(RecurringExpense)
+	var price string
+	var categoryID, vendorID sql.NullString
+	var endDate, generatedUntil sql.NullTime
+
+	err := rows.Scan(
+		&recurring.ID,
+		&recurring.Name,
+		&price,
+		&recurring.Currency,
+		&recurring.Type,
+		&recurring.Description,
+		&categoryID,
+		&vendorID,
+		&recurring.Cadence,
+		&recurring.StartDate,
+		&endDate,
+		&generatedUntil,
+		&recurring.IsActive,
+		&recurring.CreatedAt,
+		&recurring.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring.CategoryID = nullStringToPtr(categoryID)
+	recurring.VendorID = nullStringToPtr(vendorID)
+	if endDate.Valid {
+		recurring.EndDate = &endDate.Time
+	}
+	if generatedUntil.Valid {
+		recurring.GeneratedUntil = &generatedUntil.Time
+	}
+
+	recurring.Price, err = ParseMoney(price, recurring.Currency)
+
+	return recurring, err
+}
+
+// UpdateRecurringExpense updates a recurring expense. The occurrences that
+// were already created are kept.
+func (s *PostgresStore) UpdateRecurringExpense(recurring *RecurringExpense) error {
+	query := `
+		UPDATE recurring_expenses
+		SET
+		    name = $1,
+		    price = $2,
+		    currency = $3,
+		    type = $4,
+		    description = $5,
+		    category_id = $6,
+		    vendor_id = $7,
+		    cadence = $8,
+		    start_date = $9,
+		    end_date = $10,
+		    is_active = $11
+		WHERE id = $12
+	`
+
+	_, err := s.db.Exec(
+		query,
+		recurring.Name,
+		recurring.Price,
+		recurring.Currency,
+		recurring.Type,
+		recurring.Description,
+		recurring.CategoryID,
+		recurring.VendorID,
+		recurring.Cadence,
+		recurring.StartDate,
+		recurring.EndDate,
+		recurring.IsActive,
+		recurring.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRecurringExpense deletes a recurring expense, the expenses it created
+// are kept.
+func (s *PostgresStore) DeleteRecurringExpense(id string) error {
+	_, err := s.db.Exec("DELETE FROM recurring_expenses WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveRecurringExpenseException skips or overrides an occurrence, replacing
+// the previous change to it.
+func (s *PostgresStore) SaveRecurringExpenseException(exception *RecurringExpenseException) error {
+	_, err := s.db.Exec(`
+		INSERT INTO recurring_expense_exceptions (
+			recurring_expense_id,
+			occurrence_date,
+			action,
+			price,
+			description
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (recurring_expense_id, occurrence_date) DO UPDATE SET
+			action = EXCLUDED.action,
+			price = EXCLUDED.price,
+			description = EXCLUDED.description`,
+		exception.RecurringExpenseID,
+		exception.Date,
+		exception.Action,
+		exception.Price,
+		exception.Description,
+	)
+	return err
+}
+
+func (s *PostgresStore) DeleteRecurringExpenseException(recurringExpenseID string, date time.Time) error {
+	result, err := s.db.Exec(`
+		DELETE FROM recurring_expense_exceptions
+		WHERE recurring_expense_id = $1 AND occurrence_date = $2`, recurringExpenseID, date)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("occurrence [%s] of recurring expense [%s] has no changes", date.Format(time.DateOnly), recurringExpenseID)
+	}
+	return nil
+}
+
+// GetRecurringExpenseExceptions returns the changed occurrences of a
+// recurring expense by their YYYY-MM-DD date.
+func (s *PostgresStore) GetRecurringExpenseExceptions(recurring *RecurringExpense) (map[string]*RecurringExpenseException, error) {
+	return queryRecurringExpenseExceptions(s.db, recurring)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryRecurringExpenseExceptions(db queryer, recurring *RecurringExpense) (map[string]*RecurringExpenseException, error) {
+	rows, err := db.Query(`
+		SELECT occurrence_date, action, price, description
+		FROM recurring_expense_exceptions
+		WHERE recurring_expense_id = $1`, recurring.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	exceptions := make(map[string]*RecurringExpenseException)
+	for rows.Next() {
+		exception := &RecurringExpenseException{RecurringExpenseID: recurring.ID}
+		var price, description sql.NullString
+		if err := rows.Scan(&exception.Date, &exception.Action, &price, &description); err != nil {
+			return nil, err
+		}
+
+		if price.Valid {
+			amount, err := ParseMoney(price.String, recurring.Currency)
+			if err != nil {
+				return nil, err
+			}
+			exception.Price = &amount
+		}
+		exception.Description = nullStringToPtr(description)
+
+		exceptions[exception.Date.Format(time.DateOnly)] = exception
+	}
+
+	return exceptions, nil
+}
+
+// MaterializeRecurringExpenses creates the expenses of every occurrence due
+// by the date of now. Expenses are dated on their occurrence, so occurrences
+// missed while the scheduler was not running land in the right month.
+func (s *PostgresStore) MaterializeRecurringExpenses(now time.Time) error {
+	today := localDate(now, businessLocation())
+
+	rows, err := s.db.Query(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE is_active AND start_date <= $1 AND (generated_until IS NULL OR generated_until < $1)`,
+		today)
+	if err != nil {
+		return err
+	}
+
+	var due []*RecurringExpense
+	for rows.Next() {
+		recurring, err := scanIntoRecurringExpenses(rows)
+		if err != nil {
+			_ = rows.Close()
+			return err
+		}
+		due = append(due, recurring)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, recurring := range due {
+		err := s.withTx(func(tx *sql.Tx) error {
+			return materializeRecurringExpenseTx(tx, recurring, today)
+		})
+		if err != nil {
+			return fmt.Errorf("error creating expenses of recurring expense [%s]: %v", recurring.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func materializeRecurringExpenseTx(tx *sql.Tx, recurring *RecurringExpense, today time.Time) error {
+	// Another instance may have created the occurrences in the meantime
+	var generatedUntil sql.NullTime
+	err := tx.QueryRow(`
+		SELECT generated_until FROM recurring_expenses WHERE id = $1 FOR UPDATE`, recurring.ID).Scan(&generatedUntil)
+	if err != nil {
+		return err
+	}
+	var from *time.Time
+	if generatedUntil.Valid {
+		if !generatedUntil.Time.Before(today) {
+			return nil
+		}
+		from = &generatedUntil.Time
+	}
+
+	exceptions, err := queryRecurringExpenseExceptions(tx, recurring)
+	if err != nil {
+		return err
+	}
+
+	for _, date := range recurring.OccurrencesBetween(from, today) {
+		price, description := recurring.Price, recurring.Description
+		if exception, ok := exceptions[date.Format(time.DateOnly)]; ok {
+			if exception.Action == OccurrenceActionSkip {
+				continue
+			}
+			if exception.Price != nil {
+				price = *exception.Price
+			}
+			if exception.Description != nil {
+				description = *exception.Description
+			}
+		}
+
+		var id string
+		err := tx.QueryRow(`
+			INSERT INTO expenses (
+				name, price, type, description, currency, category_id, vendor_id,
+				recurring_expense_id, occurrence_date
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (recurring_expense_id, occurrence_date) DO NOTHING
+			RETURNING id`,
+			recurring.Name,
+			price,
+			recurring.Type,
+			description,
+			recurring.Currency,
+			recurring.CategoryID,
+			recurring.VendorID,
+			recurring.ID,
+			date,
+		).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// created_at is set to the insert time by a trigger
+		_, err = tx.Exec(`UPDATE expenses SET created_at = $1 WHERE id = $2`, startOfDay(date, businessLocation()), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE recurring_expenses SET generated_until = $1 WHERE id = $2`, today, recurring.ID)
+	return err
+}
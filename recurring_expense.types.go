@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cadences of recurring expenses.
+const (
+	RecurringCadenceWeekly    = "weekly"
+	RecurringCadenceBiweekly  = "biweekly"
+	RecurringCadenceMonthly   = "monthly"
+	RecurringCadenceQuarterly = "quarterly"
+	RecurringCadenceYearly    = "yearly"
+)
+
+// Changes to a single occurrence of a recurring expense. A skipped occurrence
+// creates no expense, an overridden one is created with its own price.
+const (
+	OccurrenceActionSkip     = "skip"
+	OccurrenceActionOverride = "override"
+)
+
+// Statuses of upcoming occurrences.
+const (
+	OccurrenceStatusScheduled  = "scheduled"
+	OccurrenceStatusSkipped    = "skipped"
+	OccurrenceStatusOverridden = "overridden"
+)
+
+// maxUpcomingDays limits how far ahead upcoming occurrences can be previewed.
+const maxUpcomingDays = 366
+
+// RecurringExpense is a template the scheduler turns into an expense on every
+// occurrence between StartDate and EndDate. GeneratedUntil is the last day
+// whose occurrences were already created.
+type RecurringExpense struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Price          Money      `json:"price"`
+	Currency       string     `json:"currency"`
+	Type           string     `json:"type"`
+	Description    string     `json:"description"`
+	CategoryID     *string    `json:"category_id"`
+	VendorID       *string    `json:"vendor_id"`
+	Cadence        string     `json:"cadence"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        *time.Time `json:"end_date"`
+	GeneratedUntil *time.Time `json:"generated_until"`
+	IsActive       bool       `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// CreateRecurringExpenseRequest takes the dates as YYYY-MM-DD and the price in
+// the currency of the expense, like CreateExpenseRequest.
+type CreateRecurringExpenseRequest struct {
+	Name        string      `json:"name"`
+	Price       json.Number `json:"price"`
+	Currency    string      `json:"currency"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	CategoryID  *string     `json:"category_id"`
+	VendorID    *string     `json:"vendor_id"`
+	Cadence     string      `json:"cadence"`
+	StartDate   string      `json:"start_date"`
+	EndDate     *string     `json:"end_date"`
+	IsActive    *bool       `json:"is_active"`
+}
+
+// RecurringExpenseException skips or overrides the occurrence of a recurring
+// expense on Date.
+type RecurringExpenseException struct {
+	RecurringExpenseID string    `json:"recurring_expense_id"`
+	Date               time.Time `json:"date"`
+	Action             string    `json:"action"`
+	Price              *Money    `json:"price"`
+	Description        *string   `json:"description"`
+}
+
+type UpdateOccurrenceRequest struct {
+	Action      string       `json:"action"`
+	Price       *json.Number `json:"price"`
+	Description *string      `json:"description"`
+}
+
+// UpcomingExpense is an occurrence of a recurring expense that wasn't created
+// yet, with its exception applied.
+type UpcomingExpense struct {
+	RecurringExpenseID string    `json:"recurring_expense_id"`
+	Name               string    `json:"name"`
+	Date               time.Time `json:"date"`
+	Price              Money     `json:"price"`
+	Currency           string    `json:"currency"`
+	Description        string    `json:"description"`
+	Status             string    `json:"status"`
+}
+
+func NewRecurringExpense(req *CreateRecurringExpenseRequest) (*RecurringExpense, error) {
+	// The price and the currency are checked like those of an expense
+	expense, err := NewExpense(req.Name, req.Price.String(), req.Type, req.Description, req.Currency, req.CategoryID, req.VendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValidRecurringCadence(req.Cadence) {
+		return nil, fmt.Errorf("invalid cadence [%s], use weekly, biweekly, monthly, quarterly or yearly", req.Cadence)
+	}
+
+	startDate, err := time.Parse(time.DateOnly, req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date [%s], use YYYY-MM-DD", req.StartDate)
+	}
+
+	var endDate *time.Time
+	if req.EndDate != nil && *req.EndDate != "" {
+		date, err := time.Parse(time.DateOnly, *req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date [%s], use YYYY-MM-DD", *req.EndDate)
+		}
+		if date.Before(startDate) {
+			return nil, fmt.Errorf("end_date can't be before start_date")
+		}
+		endDate = &date
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	return &RecurringExpense{
+		Name:        strings.TrimSpace(expense.Name),
+		Price:       expense.Price,
+		Currency:    expense.Currency,
+		Type:        expense.Type,
+		Description: expense.Description,
+		CategoryID:  expense.CategoryID,
+		VendorID:    expense.VendorID,
+		Cadence:     req.Cadence,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		IsActive:    isActive,
+	}, nil
+}
+
+func isValidRecurringCadence(cadence string) bool {
+	switch cadence {
+	case RecurringCadenceWeekly, RecurringCadenceBiweekly, RecurringCadenceMonthly,
+		RecurringCadenceQuarterly, RecurringCadenceYearly:
+		return true
+	}
+	return false
+}
+
+// Occurrence returns the date of the n-th occurrence, the first one being the
+// start date. Monthly cadences keep the day of the start date and fall back
+// to the last day of shorter months, so the 31st becomes the 30th in April.
+func (re *RecurringExpense) Occurrence(n int) time.Time {
+	start := re.StartDate
+	months := 0
+	switch re.Cadence {
+	case RecurringCadenceWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case RecurringCadenceBiweekly:
+		return start.AddDate(0, 0, 14*n)
+	case RecurringCadenceMonthly:
+		months = n
+	case RecurringCadenceQuarterly:
+		months = 3 * n
+	case RecurringCadenceYearly:
+		months = 12 * n
+	}
+
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, start.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
+
+// OccurrencesBetween lists the occurrences after from, or since the start
+// date when from is nil, up to and including to. The end date is never
+// passed.
+func (re *RecurringExpense) OccurrencesBetween(from *time.Time, to time.Time) []time.Time {
+	if re.EndDate != nil && re.EndDate.Before(to) {
+		to = *re.EndDate
+	}
+
+	var dates []time.Time
+	for n := 0; ; n++ {
+		date := re.Occurrence(n)
+		if date.After(to) {
+			break
+		}
+		if from == nil || date.After(*from) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// IsOccurrence reports whether the expense is due on date.
+func (re *RecurringExpense) IsOccurrence(date time.Time) bool {
+	for _, occurrence := range re.OccurrencesBetween(nil, date) {
+		if occurrence.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
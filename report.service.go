@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (server *APIServer) handleGetReceivables(w http.ResponseWriter, _ *http.Request) error {
+	receivables, err := server.store.GetReceivables()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, NewReceivablesReport(receivables))
+}
+
+// handleGetProductProfitability reports the margin of the products sold,
+// grouped by ?group_by= (product by default) between the optional ?from= and
+// ?to= days.
+func (server *APIServer) handleGetProductProfitability(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	filter := ProfitabilityFilter{GroupBy: query.Get("group_by")}
+	if filter.GroupBy == "" {
+		filter.GroupBy = ProfitabilityByProduct
+	}
+	if !isValidProfitabilityGroup(filter.GroupBy) {
+		return fmt.Errorf("invalid group_by [%s], use product, color, month or sale", filter.GroupBy)
+	}
+
+	var err error
+	if filter.From, err = parseReportDate(query.Get("from"), "from"); err != nil {
+		return err
+	}
+	if filter.To, err = parseReportDate(query.Get("to"), "to"); err != nil {
+		return err
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return fmt.Errorf("to can't be before from")
+	}
+
+	rows, err := server.store.GetProductProfitability(filter)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, NewProfitabilityReport(filter.GroupBy, rows))
+}
+
+// parseReportDate reads an optional YYYY-MM-DD query param, it returns nil
+// when the param is empty.
+func parseReportDate(value, name string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s [%s], use YYYY-MM-DD", name, value)
+	}
+	return &date, nil
+}
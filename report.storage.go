@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// GetReceivables returns the sales that are not cancelled and still have an
+// outstanding balance, oldest first.
+func (s *PostgresStore) GetReceivables() ([]Receivable, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			s.id,
+			s.customer_id,
+			s.customer_name,
+			COALESCE(s.customer_phone, 0),
+			s.status,
+			t.total,
+			t.amount_paid,
+			s.created_at
+		FROM
+			sales s
+		CROSS JOIN LATERAL (
+			SELECT ` + saleTotalSQL + ` AS total, ` + saleAmountPaidSQL + ` AS amount_paid
+		) t
+		WHERE
+			s.status != 'cancelled' AND t.total > t.amount_paid
+		ORDER BY
+			s.created_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	now := time.Now().UTC()
+	var receivables []Receivable
+	for rows.Next() {
+		var receivable Receivable
+		err := rows.Scan(
+			&receivable.SaleID,
+			&receivable.CustomerID,
+			&receivable.CustomerName,
+			&receivable.CustomerPhone,
+			&receivable.Status,
+			&receivable.Total,
+			&receivable.AmountPaid,
+			&receivable.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		receivable.Balance = receivable.Total.Sub(receivable.AmountPaid)
+		receivable.DaysOutstanding = int(now.Sub(receivable.CreatedAt).Hours() / 24)
+
+		receivables = append(receivables, receivable)
+	}
+
+	return receivables, nil
+}
+
+// profitabilityGroup is how the lines of the profitability report are
+// grouped. columns fills the row fields of the grouping, in the order of
+// ProfitabilityRow.
+type profitabilityGroup struct {
+	columns string
+	groupBy string
+	orderBy string
+}
+
+var profitabilityGroups = map[string]profitabilityGroup{
+	ProfitabilityByProduct: {
+		columns: "product_id::TEXT, product_name, NULL, NULL::TIMESTAMPTZ, NULL, NULL",
+		groupBy: "product_id, product_name",
+		orderBy: "SUM(revenue) - COALESCE(SUM(cogs), 0) DESC, product_name",
+	},
+	ProfitabilityByColor: {
+		columns: "product_id::TEXT, product_name, color, NULL::TIMESTAMPTZ, NULL, NULL",
+		groupBy: "product_id, product_name, color",
+		orderBy: "SUM(revenue) - COALESCE(SUM(cogs), 0) DESC, product_name, color",
+	},
+	ProfitabilityByMonth: {
+		columns: "NULL, NULL, NULL, DATE_TRUNC('month', sold_at AT TIME ZONE $3) AT TIME ZONE $3, NULL, NULL",
+		groupBy: "DATE_TRUNC('month', sold_at AT TIME ZONE $3)",
+		orderBy: "DATE_TRUNC('month', sold_at AT TIME ZONE $3)",
+	},
+	ProfitabilityBySale: {
+		columns: "NULL, NULL, NULL, NULL::TIMESTAMPTZ, sale_id::TEXT, order_number",
+		groupBy: "sale_id, order_number, sold_at",
+		orderBy: "sold_at DESC",
+	},
+}
+
+// GetProductProfitability totals the units, revenue and COGS of the lines of
+// the sales that are not cancelled, grouped as the filter says. Days and
+// months are those of the business time zone.
+func (s *PostgresStore) GetProductProfitability(filter ProfitabilityFilter) ([]ProfitabilityRow, error) {
+	group, ok := profitabilityGroups[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group_by [%s]", filter.GroupBy)
+	}
+
+	rows, err := s.db.Query(`
+		WITH sold AS (
+			SELECT
+				pv.id AS line_id,
+				pv.product_id,
+				p.name AS product_name,
+				pv.color,
+				s.id AS sale_id,
+				`+orderNumberSQL("s")+` AS order_number,
+				s.discount_amount AS sale_discount,
+				s.created_at AS sold_at,
+				pv.quantity,
+				pv.price * pv.quantity - pv.discount_amount AS line_total,
+				pv.unit_cost * pv.quantity AS cogs
+			FROM
+				product_variations pv
+			JOIN
+				sale_products sp ON sp.product_variation_id = pv.id
+			JOIN
+				sales s ON s.id = sp.sale_id
+			JOIN
+				products p ON p.id = pv.product_id
+			WHERE
+				s.status != 'cancelled'
+				AND `+dateRangeSQL("s.created_at", 1, 2, 3)+`
+		),
+		-- The discount of a sale, coupons included, is shared among its lines
+		-- in proportion to their totals. Rounding the running share makes the
+		-- shares of a sale add up to its discount exactly.
+		lines AS (
+			SELECT
+				*,
+				line_total - COALESCE(
+					ROUND(sale_discount::NUMERIC * SUM(line_total) OVER running / NULLIF(SUM(line_total) OVER sale, 0))
+					- ROUND(sale_discount::NUMERIC * (SUM(line_total) OVER running - line_total) / NULLIF(SUM(line_total) OVER sale, 0)),
+					0) AS revenue
+			FROM
+				sold
+			WINDOW
+				sale AS (PARTITION BY sale_id),
+				running AS (PARTITION BY sale_id ORDER BY line_id ROWS UNBOUNDED PRECEDING)
+		)
+		SELECT
+			`+group.columns+`,
+			SUM(quantity),
+			SUM(revenue),
+			COALESCE(SUM(cogs), 0),
+			COALESCE(SUM(quantity) FILTER (WHERE cogs IS NULL), 0)
+		FROM
+			lines
+		GROUP BY
+			`+group.groupBy+`
+		ORDER BY
+			`+group.orderBy, filter.From, filter.To, businessLocation().String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var profitability []ProfitabilityRow
+	for rows.Next() {
+		var row ProfitabilityRow
+		err := rows.Scan(
+			&row.ProductID,
+			&row.ProductName,
+			&row.Color,
+			&row.Month,
+			&row.SaleID,
+			&row.OrderNumber,
+			&row.Units,
+			&row.Revenue,
+			&row.COGS,
+			&row.UnitsWithoutCost,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		profitability = append(profitability, row)
+	}
+
+	return profitability, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Receivable is a sale that was not paid in full.
+type Receivable struct {
+	SaleID          string    `json:"sale_id"`
+	CustomerID      string    `json:"customer_id"`
+	CustomerName    string    `json:"customer_name"`
+	CustomerPhone   int       `json:"customer_phone"`
+	Status          string    `json:"status"`
+	Total           Money     `json:"total"`
+	AmountPaid      Money     `json:"amount_paid"`
+	Balance         Money     `json:"balance"`
+	DaysOutstanding int       `json:"days_outstanding"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ReceivablesAgingBucket groups the outstanding balance by the age of the
+// sales, MaxDays is nil for the last bucket.
+type ReceivablesAgingBucket struct {
+	MinDays int   `json:"min_days"`
+	MaxDays *int  `json:"max_days"`
+	Sales   int   `json:"sales"`
+	Balance Money `json:"balance"`
+}
+
+type ReceivablesReport struct {
+	TotalOutstanding Money                    `json:"total_outstanding"`
+	PartiallyPaid    int                      `json:"partially_paid"`
+	Unpaid           int                      `json:"unpaid"`
+	Aging            []ReceivablesAgingBucket `json:"aging"`
+	Sales            []Receivable             `json:"sales"`
+}
+
+// receivablesAgingLimits are the upper bounds in days of the aging buckets.
+var receivablesAgingLimits = []int{30, 60, 90}
+
+// NewReceivablesReport summarizes the given receivables.
+func NewReceivablesReport(receivables []Receivable) *ReceivablesReport {
+	report := &ReceivablesReport{TotalOutstanding: NewMoney(0, baseCurrency), Sales: receivables}
+	if report.Sales == nil {
+		report.Sales = []Receivable{}
+	}
+
+	minDays := 0
+	for i := range receivablesAgingLimits {
+		report.Aging = append(report.Aging, ReceivablesAgingBucket{
+			MinDays: minDays,
+			MaxDays: &receivablesAgingLimits[i],
+			Balance: NewMoney(0, baseCurrency),
+		})
+		minDays = receivablesAgingLimits[i] + 1
+	}
+	report.Aging = append(report.Aging, ReceivablesAgingBucket{MinDays: minDays, Balance: NewMoney(0, baseCurrency)})
+
+	for _, receivable := range receivables {
+		report.TotalOutstanding = report.TotalOutstanding.Add(receivable.Balance)
+		if !receivable.AmountPaid.IsZero() {
+			report.PartiallyPaid++
+		} else {
+			report.Unpaid++
+		}
+
+		for i := range report.Aging {
+			bucket := &report.Aging[i]
+			if bucket.MaxDays == nil || receivable.DaysOutstanding <= *bucket.MaxDays {
+				bucket.Sales++
+				bucket.Balance = bucket.Balance.Add(receivable.Balance)
+				break
+			}
+		}
+	}
+
+	return report
+}
+
+// Groupings of the product profitability report.
+const (
+	ProfitabilityByProduct = "product"
+	ProfitabilityByColor   = "color"
+	ProfitabilityByMonth   = "month"
+	ProfitabilityBySale    = "sale"
+)
+
+// ProfitabilityFilter selects the sales of the product profitability report,
+// From and To are inclusive days and are ignored when nil.
+type ProfitabilityFilter struct {
+	GroupBy string
+	From    *time.Time
+	To      *time.Time
+}
+
+// ProfitabilityRow totals the units sold in a group of the report, only the
+// fields of the grouping are set. Revenue is what the units were charged after
+// line discounts and their share of the discount or coupon of their sale.
+// Units sold without a unit cost are left out of COGS and counted in
+// UnitsWithoutCost, so the margin of their group is overstated.
+type ProfitabilityRow struct {
+	ProductID        *string    `json:"product_id,omitempty"`
+	ProductName      *string    `json:"product_name,omitempty"`
+	Color            *string    `json:"color,omitempty"`
+	Month            *time.Time `json:"month,omitempty"`
+	SaleID           *string    `json:"sale_id,omitempty"`
+	OrderNumber      *string    `json:"order_number,omitempty"`
+	Units            int        `json:"units"`
+	Revenue          Money      `json:"revenue"`
+	COGS             Money      `json:"cogs"`
+	GrossMargin      Money      `json:"gross_margin"`
+	MarginPercentage *float64   `json:"margin_percentage"`
+	UnitsWithoutCost int        `json:"units_without_cost"`
+}
+
+type ProfitabilityReport struct {
+	GroupBy          string             `json:"group_by"`
+	Units            int                `json:"units"`
+	Revenue          Money              `json:"revenue"`
+	COGS             Money              `json:"cogs"`
+	GrossMargin      Money              `json:"gross_margin"`
+	MarginPercentage *float64           `json:"margin_percentage"`
+	UnitsWithoutCost int                `json:"units_without_cost"`
+	Rows             []ProfitabilityRow `json:"rows"`
+}
+
+// NewProfitabilityReport computes the margins of the given rows and their
+// totals.
+func NewProfitabilityReport(groupBy string, rows []ProfitabilityRow) *ProfitabilityReport {
+	report := &ProfitabilityReport{
+		GroupBy: groupBy,
+		Revenue: NewMoney(0, baseCurrency),
+		COGS:    NewMoney(0, baseCurrency),
+		Rows:    rows,
+	}
+	if report.Rows == nil {
+		report.Rows = []ProfitabilityRow{}
+	}
+
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		row.GrossMargin = row.Revenue.Sub(row.COGS)
+		row.MarginPercentage = marginPercentage(row.GrossMargin, row.Revenue)
+
+		report.Units += row.Units
+		report.Revenue = report.Revenue.Add(row.Revenue)
+		report.COGS = report.COGS.Add(row.COGS)
+		report.UnitsWithoutCost += row.UnitsWithoutCost
+	}
+
+	report.GrossMargin = report.Revenue.Sub(report.COGS)
+	report.MarginPercentage = marginPercentage(report.GrossMargin, report.Revenue)
+
+	return report
+}
+
+// marginPercentage is the share of revenue kept as margin, rounded to two
+// decimals. It is nil when there is no revenue.
+func marginPercentage(margin, revenue Money) *float64 {
+	if revenue.IsZero() {
+		return nil
+	}
+	percentage := math.Round(float64(margin.Amount)*10000/float64(revenue.Amount)) / 100
+	return &percentage
+}
+
+// isValidProfitabilityGroup reports whether groupBy is a grouping of the
+// product profitability report.
+func isValidProfitabilityGroup(groupBy string) bool {
+	switch groupBy {
+	case ProfitabilityByProduct, ProfitabilityByColor, ProfitabilityByMonth, ProfitabilityBySale:
+		return true
+	}
+	return false
+}
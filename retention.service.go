@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleGetCohorts reports the retention of the customers grouped by the
+// month of their first purchase, for the last ?months= months, 12 by default.
+func (server *APIServer) handleGetCohorts(w http.ResponseWriter, r *http.Request) error {
+	opts, err := parseRetentionOptions(r)
+	if err != nil {
+		return err
+	}
+
+	months := defaultCohortMonths
+	if value := r.URL.Query().Get("months"); value != "" {
+		months, err = strconv.Atoi(value)
+		if err != nil || months < 1 || months > maxCohortMonths {
+			return fmt.Errorf("months must be a number between 1 and %d", maxCohortMonths)
+		}
+	}
+
+	purchases, err := server.store.GetCustomerPurchases(opts)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, NewCohortReport(purchases, time.Now(), months, opts))
+}
+
+// handleGetRFM segments the customers by the recency, frequency and monetary
+// value of their purchases, listing those of ?segment= when given.
+func (server *APIServer) handleGetRFM(w http.ResponseWriter, r *http.Request) error {
+	opts, err := parseRetentionOptions(r)
+	if err != nil {
+		return err
+	}
+
+	segment := r.URL.Query().Get("segment")
+	if segment != "" && !isValidRFMSegment(segment) {
+		return fmt.Errorf("invalid segment [%s]", segment)
+	}
+
+	purchases, err := server.store.GetCustomerPurchases(opts)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, NewRFMReport(purchases, time.Now(), segment, opts))
+}
+
+// parseRetentionOptions reads the ?paid_only= and ?tz= params shared by the
+// retention reports.
+func parseRetentionOptions(r *http.Request) (RetentionOptions, error) {
+	query := r.URL.Query()
+	opts := RetentionOptions{PaidOnly: query.Get("paid_only") == "true"}
+
+	var err error
+	if opts.Location, err = loadTimeZone(query.Get("tz")); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// GetCustomerPurchases returns the counted sales of every customer, oldest
+// first, valued after discounts. Cancelled sales are never counted, and with
+// opts.PaidOnly neither are the sales waiting for payment.
+func (s *PostgresStore) GetCustomerPurchases(opts RetentionOptions) ([]CustomerPurchase, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			s.id,
+			s.customer_id,
+			COALESCE(c.name, s.customer_name, ''),
+			s.created_at,
+			COALESCE(SUM(pv.price * pv.quantity - pv.discount_amount), 0) - s.discount_amount
+		FROM
+			sales s
+		LEFT JOIN
+			customers c ON c.id = s.customer_id
+		LEFT JOIN
+			sale_products sp ON sp.sale_id = s.id
+		LEFT JOIN
+			product_variations pv ON pv.id = sp.product_variation_id
+		WHERE
+			s.customer_id IS NOT NULL
+			AND s.status != 'cancelled' AND (NOT $1 OR s.status = ANY($2))
+		GROUP BY
+			s.id, c.name
+		ORDER BY
+			s.created_at`,
+		opts.PaidOnly, pq.Array(paidSaleStatuses))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var purchases []CustomerPurchase
+	for rows.Next() {
+		var purchase CustomerPurchase
+		err := rows.Scan(
+			&purchase.SaleID,
+			&purchase.CustomerID,
+			&purchase.CustomerName,
+			&purchase.CreatedAt,
+			&purchase.Value,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		purchases = append(purchases, purchase)
+	}
+
+	return purchases, rows.Err()
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// CustomerPurchase is a counted sale of a customer. Value is what the
+// customer paid for its products after the line and sale discounts.
+type CustomerPurchase struct {
+	SaleID       string
+	CustomerID   string
+	CustomerName string
+	CreatedAt    time.Time
+	Value        Money
+}
+
+// RetentionOptions changes which sales are counted in the cohorts and the
+// RFM segments, and the time zone their days and months are taken in.
+// PaidOnly leaves out the sales that were not paid yet.
+type RetentionOptions struct {
+	PaidOnly bool
+	Location *time.Location
+}
+
+// Cohort limits of the ?months= param of the cohort report.
+const (
+	defaultCohortMonths = 12
+	maxCohortMonths     = 60
+)
+
+// CohortPeriod is a month after the first purchase of a cohort. Customers
+// counts those who bought again in that month, and Rate is their percentage
+// of the cohort.
+type CohortPeriod struct {
+	Offset    int       `json:"offset"`
+	Month     time.Time `json:"month"`
+	Customers int       `json:"customers"`
+	Rate      float64   `json:"rate"`
+}
+
+// Cohort groups the customers whose first purchase was in Month. Revenue is
+// what they have paid since, and RepeatCustomers counts those with more than
+// one purchase.
+type Cohort struct {
+	Month           time.Time      `json:"month"`
+	Customers       int            `json:"customers"`
+	RepeatCustomers int            `json:"repeat_customers"`
+	RepeatRate      float64        `json:"repeat_rate"`
+	Revenue         Money          `json:"revenue"`
+	Periods         []CohortPeriod `json:"periods"`
+}
+
+// CohortRetention is the retention of all the cohorts Offset months after
+// their first purchase, over the customers of the cohorts old enough to have
+// reached it.
+type CohortRetention struct {
+	Offset    int     `json:"offset"`
+	Cohorts   int     `json:"cohorts"`
+	Customers int     `json:"customers"`
+	Returning int     `json:"returning"`
+	Rate      float64 `json:"rate"`
+}
+
+type CohortReport struct {
+	Months    int               `json:"months"`
+	Cohorts   []Cohort          `json:"cohorts"`
+	Retention []CohortRetention `json:"retention"`
+}
+
+// NewCohortReport groups the customers of the given purchases by the month of
+// their first purchase, keeping the cohorts of the last months months up to
+// now. Each cohort has a period for every month since, up to the current one.
+func NewCohortReport(purchases []CustomerPurchase, now time.Time, months int, opts RetentionOptions) *CohortReport {
+	report := &CohortReport{Months: months, Cohorts: []Cohort{}, Retention: []CohortRetention{}}
+
+	currentMonth := periodStart(now, GranularityMonth, opts.Location)
+	firstMonth := currentMonth.AddDate(0, 1-months, 0)
+
+	// Purchases are sorted by date, so the first one of a customer sets their cohort
+	cohortOf := make(map[string]*Cohort)
+	purchasesOf := make(map[string]int)
+	returnedIn := make(map[string]map[int]bool)
+	cohorts := make(map[int64]*Cohort)
+	for _, purchase := range purchases {
+		month := periodStart(purchase.CreatedAt, GranularityMonth, opts.Location)
+
+		cohort, ok := cohortOf[purchase.CustomerID]
+		if !ok {
+			if month.Before(firstMonth) {
+				cohortOf[purchase.CustomerID] = nil
+				continue
+			}
+			cohort, ok = cohorts[month.Unix()]
+			if !ok {
+				cohort = newCohort(month, currentMonth)
+				cohorts[month.Unix()] = cohort
+			}
+			cohortOf[purchase.CustomerID] = cohort
+			returnedIn[purchase.CustomerID] = make(map[int]bool)
+			cohort.Customers++
+		}
+		if cohort == nil {
+			continue
+		}
+
+		cohort.Revenue = cohort.Revenue.Add(purchase.Value)
+		purchasesOf[purchase.CustomerID]++
+		if purchasesOf[purchase.CustomerID] == 2 {
+			cohort.RepeatCustomers++
+		}
+
+		offset := monthsBetween(cohort.Month, month, opts.Location)
+		if offset > 0 && !returnedIn[purchase.CustomerID][offset] {
+			returnedIn[purchase.CustomerID][offset] = true
+			cohort.Periods[offset-1].Customers++
+		}
+	}
+
+	for _, cohort := range cohorts {
+		cohort.RepeatRate = ratePercentage(cohort.RepeatCustomers, cohort.Customers)
+		for i := range cohort.Periods {
+			period := &cohort.Periods[i]
+			period.Rate = ratePercentage(period.Customers, cohort.Customers)
+
+			for len(report.Retention) < period.Offset {
+				report.Retention = append(report.Retention, CohortRetention{Offset: len(report.Retention) + 1})
+			}
+			retention := &report.Retention[period.Offset-1]
+			retention.Cohorts++
+			retention.Customers += cohort.Customers
+			retention.Returning += period.Customers
+		}
+		report.Cohorts = append(report.Cohorts, *cohort)
+	}
+	for i := range report.Retention {
+		retention := &report.Retention[i]
+		retention.Rate = ratePercentage(retention.Returning, retention.Customers)
+	}
+
+	sort.Slice(report.Cohorts, func(i, j int) bool {
+		return report.Cohorts[i].Month.Before(report.Cohorts[j].Month)
+	})
+
+	return report
+}
+
+func newCohort(month, currentMonth time.Time) *Cohort {
+	cohort := &Cohort{Month: month, Revenue: NewMoney(0, baseCurrency), Periods: []CohortPeriod{}}
+	for offset := 1; offset <= monthsBetween(month, currentMonth, month.Location()); offset++ {
+		cohort.Periods = append(cohort.Periods, CohortPeriod{Offset: offset, Month: month.AddDate(0, offset, 0)})
+	}
+	return cohort
+}
+
+// monthsBetween is the number of calendar months from the month of from to
+// the month of to, in loc.
+func monthsBetween(from, to time.Time, loc *time.Location) int {
+	fromYear, fromMonth, _ := from.In(loc).Date()
+	toYear, toMonth, _ := to.In(loc).Date()
+	return (toYear-fromYear)*12 + int(toMonth-fromMonth)
+}
+
+// ratePercentage is part as a percentage of total, rounded to two decimals,
+// or zero when total is zero.
+func ratePercentage(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(total)) / 100
+}
+
+// RFM segments, from the recency and frequency scores of the customers.
+const (
+	RFMChampions          = "champions"
+	RFMLoyal              = "loyal"
+	RFMPotentialLoyalists = "potential_loyalists"
+	RFMNew                = "new"
+	RFMNeedAttention      = "need_attention"
+	RFMAtRisk             = "at_risk"
+	RFMHibernating        = "hibernating"
+	RFMLost               = "lost"
+)
+
+// rfmSegments are the segments in the order they are reported.
+var rfmSegments = []string{
+	RFMChampions,
+	RFMLoyal,
+	RFMPotentialLoyalists,
+	RFMNew,
+	RFMNeedAttention,
+	RFMAtRisk,
+	RFMHibernating,
+	RFMLost,
+}
+
+func isValidRFMSegment(segment string) bool {
+	for _, valid := range rfmSegments {
+		if segment == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerRFM scores the recency, frequency and monetary value of a customer
+// from 1 to 5 by their quintile among all the customers, 5 being the most
+// recent, frequent or valuable. Equal values get the same score, and Score
+// joins the three, e.g. 545.
+type CustomerRFM struct {
+	CustomerID     string    `json:"customer_id"`
+	CustomerName   string    `json:"customer_name"`
+	FirstPurchase  time.Time `json:"first_purchase"`
+	LastPurchase   time.Time `json:"last_purchase"`
+	RecencyDays    int       `json:"recency_days"`
+	Frequency      int       `json:"frequency"`
+	Monetary       Money     `json:"monetary"`
+	RecencyScore   int       `json:"recency_score"`
+	FrequencyScore int       `json:"frequency_score"`
+	MonetaryScore  int       `json:"monetary_score"`
+	Score          string    `json:"score"`
+	Segment        string    `json:"segment"`
+}
+
+type RFMSegmentSummary struct {
+	Segment   string `json:"segment"`
+	Customers int    `json:"customers"`
+	Monetary  Money  `json:"monetary"`
+}
+
+type RFMReport struct {
+	AsOf      time.Time           `json:"as_of"`
+	Segments  []RFMSegmentSummary `json:"segments"`
+	Customers []CustomerRFM       `json:"customers"`
+}
+
+// NewRFMReport scores and segments the customers of the given purchases,
+// their recency being the days from their last purchase to now. The customers
+// are sorted by monetary value, and only those of segment are listed when it
+// is not empty. Segments always cover every customer.
+func NewRFMReport(purchases []CustomerPurchase, now time.Time, segment string, opts RetentionOptions) *RFMReport {
+	today := localDate(now, opts.Location)
+	report := &RFMReport{AsOf: today, Segments: []RFMSegmentSummary{}, Customers: []CustomerRFM{}}
+
+	var customers []*CustomerRFM
+	byID := make(map[string]*CustomerRFM)
+	for _, purchase := range purchases {
+		customer, ok := byID[purchase.CustomerID]
+		if !ok {
+			customer = &CustomerRFM{
+				CustomerID:    purchase.CustomerID,
+				CustomerName:  purchase.CustomerName,
+				FirstPurchase: purchase.CreatedAt,
+				Monetary:      NewMoney(0, baseCurrency),
+			}
+			byID[purchase.CustomerID] = customer
+			customers = append(customers, customer)
+		}
+		if purchase.CreatedAt.After(customer.LastPurchase) {
+			customer.LastPurchase = purchase.CreatedAt
+		}
+		customer.Frequency++
+		customer.Monetary = customer.Monetary.Add(purchase.Value)
+	}
+
+	recency := make([]float64, len(customers))
+	frequency := make([]float64, len(customers))
+	monetary := make([]float64, len(customers))
+	for i, customer := range customers {
+		customer.RecencyDays = int(today.Sub(localDate(customer.LastPurchase, opts.Location)).Hours() / 24)
+		recency[i] = -float64(customer.RecencyDays)
+		frequency[i] = float64(customer.Frequency)
+		monetary[i] = float64(customer.Monetary.Amount)
+	}
+	recencyScores, frequencyScores, monetaryScores := rfmScores(recency), rfmScores(frequency), rfmScores(monetary)
+
+	summaries := make(map[string]*RFMSegmentSummary)
+	for _, name := range rfmSegments {
+		summaries[name] = &RFMSegmentSummary{Segment: name, Monetary: NewMoney(0, baseCurrency)}
+	}
+	for i, customer := range customers {
+		customer.RecencyScore = recencyScores[i]
+		customer.FrequencyScore = frequencyScores[i]
+		customer.MonetaryScore = monetaryScores[i]
+		customer.Score = fmt.Sprintf("%d%d%d", customer.RecencyScore, customer.FrequencyScore, customer.MonetaryScore)
+		customer.Segment = rfmSegment(customer.RecencyScore, customer.FrequencyScore)
+
+		summary := summaries[customer.Segment]
+		summary.Customers++
+		summary.Monetary = summary.Monetary.Add(customer.Monetary)
+
+		if segment == "" || customer.Segment == segment {
+			report.Customers = append(report.Customers, *customer)
+		}
+	}
+	for _, name := range rfmSegments {
+		report.Segments = append(report.Segments, *summaries[name])
+	}
+
+	sort.SliceStable(report.Customers, func(i, j int) bool {
+		if cmp := report.Customers[i].Monetary.Cmp(report.Customers[j].Monetary); cmp != 0 {
+			return cmp > 0
+		}
+		return report.Customers[i].CustomerName < report.Customers[j].CustomerName
+	})
+
+	return report
+}
+
+// rfmScores gives each value a score from 1 to 5 by its quintile, higher
+// values getting higher scores and equal values the same one.
+func rfmScores(values []float64) []int {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+
+	scores := make([]int, len(values))
+	for rank, i := range order {
+		if rank > 0 && values[i] == values[order[rank-1]] {
+			scores[i] = scores[order[rank-1]]
+			continue
+		}
+		scores[i] = rank*5/len(values) + 1
+	}
+	return scores
+}
+
+// rfmSegment names the segment of the recency and frequency scores.
+func rfmSegment(recency, frequency int) string {
+	switch {
+	case recency >= 4 && frequency >= 4:
+		return RFMChampions
+	case recency >= 3 && frequency >= 4:
+		return RFMLoyal
+	case recency >= 4 && frequency >= 2:
+		return RFMPotentialLoyalists
+	case recency >= 4:
+		return RFMNew
+	case recency <= 2 && frequency >= 3:
+		return RFMAtRisk
+	case recency == 1:
+		return RFMLost
+	case recency == 2:
+		return RFMHibernating
+	}
+	return RFMNeedAttention
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"os"
 	"strings"
+	"time"
 )
 
 // BucketBasics encapsulates the Amazon Simple storage Service (Amazon S3) actions
@@ -19,8 +20,9 @@ type BucketBasics struct {
 }
 
 type ImageDataS3 struct {
-	Base64Data []byte
-	Type       string
+	Base64Data  []byte
+	Type        string
+	ContentType string
 }
 
 // S3ParamsFromBase64 converts a base64 string with MIME type to ImageDataS3
@@ -42,7 +44,14 @@ func S3ParamsFromBase64(base64Str string) (ImageDataS3, error) {
 			Type:       "",
 		}, errors.New("invalid base64 string")
 	}
-	fileType := strings.Split(fileTypeParts[0], "/")[1]
+	mediaType := strings.Split(fileTypeParts[0], "/")
+	if len(mediaType) != 2 {
+		return ImageDataS3{
+			Base64Data: []byte(""),
+			Type:       "",
+		}, errors.New("invalid base64 string")
+	}
+	fileType := mediaType[1]
 
 	// Decode the base64 string into bytes
 	decodedData, err := base64.StdEncoding.DecodeString(parts[1])
@@ -54,13 +63,47 @@ func S3ParamsFromBase64(base64Str string) (ImageDataS3, error) {
 	}
 
 	return ImageDataS3{
-		Base64Data: decodedData,
-		Type:       fileType,
+		Base64Data:  decodedData,
+		Type:        fileType,
+		ContentType: strings.TrimPrefix(fileTypeParts[0], "data:"),
 	}, nil
 }
 
-// UploadFile reads from a file and puts the data into an object in a bucket.
+// publicContentTypes are the images that can be uploaded for everyone to see.
+// SVG is left out, it can carry scripts.
+var publicContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// privateContentTypes are the files that can be uploaded as documents only
+// staff can see, such as supplier invoices.
+var privateContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// presignedURLExpiry is how long a link to a private file works.
+const presignedURLExpiry = 15 * time.Minute
+
+// UploadFile reads from a file and puts the data into an object in a bucket
+// that everyone can read, such as the images of the catalog.
 func (basics BucketBasics) UploadFile(base64 string) (string, error) {
+	return basics.putObject(base64, publicContentTypes, true)
+}
+
+// UploadPrivateFile puts a document into the bucket without the public read
+// grant, it can only be read through a presigned URL, see PresignURL.
+func (basics BucketBasics) UploadPrivateFile(base64 string) (string, error) {
+	return basics.putObject(base64, privateContentTypes, false)
+}
+
+func (basics BucketBasics) putObject(base64 string, allowed map[string]bool, public bool) (string, error) {
 	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
 	bucketUrl := os.Getenv("AWS_S3_BUCKET_URL")
 	objectKey := uuid.NewString()
@@ -69,20 +112,27 @@ func (basics BucketBasics) UploadFile(base64 string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	contentType := strings.ToLower(params.ContentType)
+	if !allowed[contentType] {
+		return "", fmt.Errorf("files of type [%s] can't be uploaded", params.ContentType)
+	}
 	reader := bytes.NewReader(params.Base64Data)
-	contentType := fmt.Sprintf("image/%s", params.Type)
 
-	_, err = basics.S3Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		GrantRead:       aws.String("uri=http://acs.amazonaws.com/groups/global/AllUsers"),
+	input := &s3.PutObjectInput{
 		Bucket:          aws.String(bucketName),
 		Key:             aws.String(objectKey),
 		Body:            reader,
 		ContentEncoding: aws.String("base64"),
 		ContentType:     aws.String(contentType),
-	})
+	}
+	if public {
+		input.GrantRead = aws.String("uri=http://acs.amazonaws.com/groups/global/AllUsers")
+	}
+
+	_, err = basics.S3Client.PutObject(context.TODO(), input)
 	if err != nil {
-		return "", fmt.Errorf("Couldn't upload file %v to %v:%v. Here's why: %v\n",
-			base64, bucketName, objectKey, err)
+		return "", fmt.Errorf("Couldn't upload file to %v:%v. Here's why: %v\n",
+			bucketName, objectKey, err)
 	}
 
 	imageUrl := fmt.Sprintf("%s/%s", bucketUrl, objectKey)
@@ -90,6 +140,25 @@ func (basics BucketBasics) UploadFile(base64 string) (string, error) {
 	return imageUrl, err
 }
 
+// PresignURL returns a temporary link to a file uploaded with
+// UploadPrivateFile, fileUrl being the URL returned when it was uploaded.
+func (basics BucketBasics) PresignURL(fileUrl string) (string, error) {
+	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
+
+	parts := strings.Split(fileUrl, "/")
+	objectKey := parts[len(parts)-1]
+
+	request, err := s3.NewPresignClient(basics.S3Client).PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	}, s3.WithPresignExpires(presignedURLExpiry))
+	if err != nil {
+		return "", fmt.Errorf("Couldn't presign s3 file %v. Here's why: %v\n", objectKey, err)
+	}
+
+	return request.URL, nil
+}
+
 // DeleteFile searches a file by id and then deletes it from the bucket
 func (basics BucketBasics) DeleteFile(imageUrl string) error {
 	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
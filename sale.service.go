@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func (server *APIServer) handleCreateSale(w http.ResponseWriter, r *http.Request) error {
@@ -11,30 +14,204 @@ func (server *APIServer) handleCreateSale(w http.ResponseWriter, r *http.Request
 		return err
 	}
 
+	sale, err := server.createSale(req, getAuthUserID(r))
+	if err != nil {
+		return err
+	}
+
+	// Recovering product from DB
+	createdSale, err := server.store.GetSaleByID(sale.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdSale)
+}
+
+// createSale validates the requested products and stores the sale. It is
+// shared by the dashboard and the conversion of catalog order requests.
+func (server *APIServer) createSale(req *CreateSaleRequest, createdBy *string) (*SaleWithProducts, error) {
+	if err := server.resolveSaleSKUs(req.Products); err != nil {
+		return nil, err
+	}
+
+	if err := server.validateSaleProducts(req.Products); err != nil {
+		return nil, err
+	}
+
 	sale, err := NewSale(
 		req.CustomerID,
+		req.Status,
 		req.Products,
+		createdBy,
 	)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	sale.OrderRequestID = req.OrderRequestID
+	sale.DiscountType = req.DiscountType
+	sale.DiscountValue = req.DiscountValue
+	if code := strings.TrimSpace(req.CouponCode); code != "" {
+		if sale.DiscountType != "" {
+			return nil, fmt.Errorf("a sale discount can't be combined with a coupon")
+		}
+		if err := server.applyCoupon(sale, code); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sale.ApplyDiscounts(); err != nil {
+		return nil, err
+	}
+
+	if err := server.store.CreateSale(sale); err != nil {
+		return nil, err
 	}
 
-	err = server.store.CreateSale(sale)
+	return sale, nil
+}
+
+// applyCoupon sets the discount of the sale from the coupon with the given
+// code, after checking the customer can still redeem it. The check is done
+// again when the coupon is redeemed with the sale, see redeemCoupon.
+func (server *APIServer) applyCoupon(sale *SaleWithProducts, code string) error {
+	coupon, err := server.store.GetCouponByCode(code)
 	if err != nil {
 		return err
 	}
 
-	// Recovering product from DB
-	createdSale, err := server.store.GetSaleByID(sale.ID)
+	customerUses, err := server.store.CountCouponUsesByCustomer(coupon.ID, sale.CustomerID)
 	if err != nil {
 		return err
 	}
 
-	return WriteJSON(w, http.StatusOK, createdSale)
+	if err := coupon.CheckRedeemable(time.Now().UTC(), customerUses); err != nil {
+		return err
+	}
+
+	sale.DiscountType = coupon.DiscountType
+	sale.DiscountValue = coupon.DiscountValue
+	sale.CouponID = &coupon.ID
+	sale.CouponCode = &coupon.Code
+
+	return nil
+}
+
+// resolveSaleSKUs fills the product and color of every sale line that was
+// sent with a SKU.
+func (server *APIServer) resolveSaleSKUs(products []ProductVariations) error {
+	for i := range products {
+		line := &products[i]
+		sku := normalizeSKU(&line.SKU)
+		if sku == nil {
+			continue
+		}
+
+		lookup, err := server.store.GetProductByCode(*sku)
+		if err != nil {
+			return err
+		}
+
+		if line.ProductID != "" && line.ProductID != lookup.Product.ID {
+			return fmt.Errorf("sku [%s] does not belong to product [%s]", *sku, line.ProductID)
+		}
+		line.ProductID = lookup.Product.ID
+
+		switch {
+		case lookup.Variant != nil:
+			line.Color = lookup.Variant.ColorName
+		case line.Color == "" && len(lookup.Product.AvailableColors) == 1:
+			line.Color = lookup.Product.AvailableColors[0]
+		case line.Color == "":
+			return fmt.Errorf("color is required for sku [%s]", *sku)
+		}
+	}
+
+	return nil
 }
 
-func (server *APIServer) handleGetSales(w http.ResponseWriter, _ *http.Request) error {
-	sales, err := server.store.GetSales()
+// validateSaleProducts checks every sale line against its product and sets
+// the list price and the unit cost of the line. A line without a price is charged the list
+// price, any other price needs PriceOverride and a reason.
+func (server *APIServer) validateSaleProducts(products []ProductVariations) error {
+	if len(products) == 0 {
+		return fmt.Errorf("a sale needs at least one product")
+	}
+
+	for i := range products {
+		line := &products[i]
+		product, err := server.store.GetProductByID(line.ProductID)
+		if err != nil {
+			return err
+		}
+		if product.IsArchived() {
+			return fmt.Errorf("product [%s] is archived and can't be sold", product.Name)
+		}
+
+		if line.Quantity == 0 {
+			line.Quantity = 1
+		}
+		if line.Quantity < 0 {
+			return fmt.Errorf("quantity of product [%s] must be greater than zero", product.Name)
+		}
+
+		line.Color = strings.TrimSpace(line.Color)
+		if !product.HasColor(line.Color) {
+			return fmt.Errorf("product [%s] is not available in color [%s]", product.Name, line.Color)
+		}
+		if variant := product.FindVariant(line.Color); variant != nil {
+			// Lines sent with the color hex are stored with the color name,
+			// which is what the stock is taken and restored by
+			line.Color = variant.ColorName
+			if !variant.InStock {
+				return fmt.Errorf("product [%s] is out of stock in color [%s]", product.Name, line.Color)
+			}
+			if variant.Stock != nil && *variant.Stock < line.Quantity {
+				return fmt.Errorf("only %d units of product [%s] left in color [%s]", *variant.Stock, product.Name, line.Color)
+			}
+		}
+
+		line.ListPrice = product.PriceForColor(line.Color)
+		line.UnitCost = product.UnitCostForColor(line.Color)
+		if line.Price == (Money{}) {
+			line.Price = line.ListPrice
+		}
+		if err := validatePrice(line.Price); err != nil {
+			return fmt.Errorf("product [%s]: %w", product.Name, err)
+		}
+
+		if line.Price == line.ListPrice {
+			line.PriceOverride = false
+			line.PriceOverrideReason = nil
+			continue
+		}
+		if !line.PriceOverride {
+			return fmt.Errorf("price [%s] of product [%s] differs from its list price [%s], set price_override with a reason to charge it", formatCurrency(line.Price), product.Name, formatCurrency(line.ListPrice))
+		}
+		if line.PriceOverrideReason == nil || strings.TrimSpace(*line.PriceOverrideReason) == "" {
+			return fmt.Errorf("a price override of product [%s] needs a reason", product.Name)
+		}
+		if line.Price.IsNegative() {
+			return fmt.Errorf("price of product [%s] can't be negative", product.Name)
+		}
+		reason := strings.TrimSpace(*line.PriceOverrideReason)
+		line.PriceOverrideReason = &reason
+	}
+
+	return nil
+}
+
+func (server *APIServer) handleGetSales(w http.ResponseWriter, r *http.Request) error {
+	filter := SaleFilter{
+		Status: r.URL.Query().Get("status"),
+		Search: strings.TrimSpace(r.URL.Query().Get("q")),
+	}
+	if filter.Status != "" && !isValidSaleStatus(filter.Status) {
+		return fmt.Errorf("invalid sale status [%s]", filter.Status)
+	}
+
+	sales, err := server.store.GetSales(filter)
 	if err != nil {
 		return err
 	}
@@ -62,3 +239,56 @@ func (server *APIServer) handleGetSaleByID(w http.ResponseWriter, r *http.Reques
 
 	return WriteJSON(w, http.StatusOK, sale)
 }
+
+func (server *APIServer) handleUpdateSaleStatus(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	sale, err := server.store.GetSaleByID(id)
+	if err != nil {
+		return err
+	}
+
+	req := new(UpdateSaleStatusRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if err := validateSaleStatusTransition(sale.Status, req.Status); err != nil {
+		return err
+	}
+
+	err = server.store.UpdateSaleStatus(id, sale.Status, req.Status, strings.TrimSpace(req.Note), getAuthUserID(r))
+	if err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedSale, err := server.store.GetSaleByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedSale)
+}
+
+func (server *APIServer) handleGetSaleStatusHistory(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetSaleByID(id)
+	if err != nil {
+		return err
+	}
+
+	changes, err := server.store.GetSaleStatusChanges(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, changes)
+}
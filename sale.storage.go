@@ -15,18 +15,18 @@ func (s *PostgresStore) CreateSalesTablesWithRelations() error {
 		-- Create a table to store product variations
 		CREATE TABLE IF NOT EXISTS product_variations (
 			id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-			product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+			product_id UUID REFERENCES products(id) ON DELETE RESTRICT,
 			color VARCHAR(20) NOT NULL,
 			price BIGINT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
 		
 		-- Create a table to store sales information
 		CREATE TABLE IF NOT EXISTS sales (
 			id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
 			customer_id UUID REFERENCES customers(id) ON DELETE CASCADE,
--- 			sale_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
+-- 			sale_date TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP, 
 			 -- Snapshot of customer
 			customer_name VARCHAR(255),
 			customer_instagram_account VARCHAR(255),
@@ -37,8 +37,8 @@ func (s *PostgresStore) CreateSalesTablesWithRelations() error {
 			customer_comments VARCHAR(255),
 			customer_cc VARCHAR(255),
 			-- End Snapshot of customer
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
 		
 		-- Create a mapping table to associate products with sales
@@ -52,6 +52,30 @@ func (s *PostgresStore) CreateSalesTablesWithRelations() error {
 		return err
 	}
 
+	// Sold variations must outlive their product, products with sales are archived instead
+	var restrictExists bool
+	err = s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM pg_constraint
+			WHERE conname = 'fk_product_variation_product_id'
+			AND confdeltype = 'r')
+	`).Scan(&restrictExists)
+	if err != nil {
+		return err
+	}
+
+	if !restrictExists {
+		_, err = s.db.Exec(`
+			ALTER TABLE product_variations DROP CONSTRAINT IF EXISTS product_variations_product_id_fkey;
+			ALTER TABLE product_variations DROP CONSTRAINT IF EXISTS fk_product_variation_product_id;
+			ALTER TABLE product_variations ADD CONSTRAINT fk_product_variation_product_id
+				FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
 	var triggerExists bool
 	// START product_variations trigger -----------------------------
 	// Check if the updatedAt trigger already exists
@@ -189,26 +213,128 @@ func (s *PostgresStore) CreateSalesTablesWithRelations() error {
 	}
 	// END sales trigger -----------------------------
 
-	return nil
-}
+	// Sales recorded before statuses existed were already delivered
+	_, err = s.db.Exec(`
+		ALTER TABLE sales ADD COLUMN IF NOT EXISTS status VARCHAR(20);
+		UPDATE sales SET status = 'delivered' WHERE status IS NULL;
+		ALTER TABLE sales ALTER COLUMN status SET DEFAULT 'pending';
+		ALTER TABLE sales ALTER COLUMN status SET NOT NULL;
 
-func (s *PostgresStore) CreateSale(sale *SaleWithProducts) error {
-	pvIDs, err := createProductVariations(sale, s)
+		CREATE INDEX IF NOT EXISTS sales_status_idx ON sales (status);
+
+		-- Discounts of each line and of the whole sale
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS discount_type VARCHAR(20);
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS discount_value BIGINT;
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_type VARCHAR(20);
+		ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_value BIGINT;
+		ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+
+		-- Human-readable number of each sale, existing sales are numbered by date
+		ALTER TABLE sales ADD COLUMN IF NOT EXISTS order_number BIGINT;
+		UPDATE sales s SET order_number = numbered.order_number
+		FROM (
+			SELECT
+				id,
+				(SELECT COALESCE(MAX(order_number), 0) FROM sales)
+					+ ROW_NUMBER() OVER (ORDER BY created_at, id) AS order_number
+			FROM sales
+			WHERE order_number IS NULL
+		) AS numbered
+		WHERE s.id = numbered.id;
+		INSERT INTO counters (name, value)
+		SELECT 'sales', COALESCE(MAX(order_number), 0) FROM sales
+		ON CONFLICT (name) DO UPDATE SET value = GREATEST(counters.value, EXCLUDED.value);
+		ALTER TABLE sales ALTER COLUMN order_number SET NOT NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS sales_order_number_idx ON sales (order_number);
+
+		-- Units sold in each line, older sales have a line per unit
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0);
+
+		-- Price of the product when sold, next to the price charged
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS list_price BIGINT;
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS price_override_reason TEXT;
+		UPDATE product_variations SET list_price = price WHERE list_price IS NULL;
+		ALTER TABLE product_variations ALTER COLUMN list_price SET NOT NULL;
+
+		-- Unit cost of the product when sold, NULL when it had none
+		ALTER TABLE product_variations ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
+
+		-- Lines keep the color name of the variant sold, as long as catalog_variants.color_name
+		ALTER TABLE product_variations ALTER COLUMN color TYPE VARCHAR(255);
+
+		CREATE TABLE IF NOT EXISTS sale_status_changes (
+			id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+			sale_id UUID NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+			from_status VARCHAR(20),
+			to_status VARCHAR(20) NOT NULL,
+			note VARCHAR(255),
+			changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS sale_status_changes_sale_id_idx ON sale_status_changes (sale_id, created_at);
+	`)
 	if err != nil {
 		return err
 	}
 
+	return nil
+}
+
+func (s *PostgresStore) CreateSale(sale *SaleWithProducts) error {
 	customer, err := s.GetCustomerByID(sale.CustomerID)
 	if err != nil {
 		return err
 	}
 
-	saleID, err := createSale(customer, s)
-	if err != nil {
-		return err
-	}
+	// The coupon, the stock and the order request are taken in the same
+	// transaction as the sale is stored, so a failed sale never keeps them and
+	// they are never used twice
+	err = s.withTx(func(tx *sql.Tx) error {
+		if sale.OrderRequestID != nil {
+			if err := claimOrderRequest(tx, *sale.OrderRequestID); err != nil {
+				return err
+			}
+		}
+
+		if sale.CouponID != nil {
+			if err := redeemCoupon(tx, sale); err != nil {
+				return err
+			}
+		}
+
+		if err := decrementVariantStock(tx, sale.Products); err != nil {
+			return err
+		}
+
+		pvIDs, err := createProductVariations(tx, sale)
+		if err != nil {
+			return err
+		}
+
+		saleID, err := createSale(tx, customer, sale)
+		if err != nil {
+			return err
+		}
+		sale.ID = saleID
+
+		if err := createSaleProducts(tx, saleID, pvIDs); err != nil {
+			return err
+		}
 
-	err = createSaleProducts(saleID, pvIDs, s)
+		if sale.OrderRequestID != nil {
+			if err := markOrderRequestConverted(tx, sale); err != nil {
+				return err
+			}
+		}
+
+		// The status history starts with the status the sale was created in
+		_, err = tx.Exec(`
+			INSERT INTO sale_status_changes (sale_id, to_status, changed_by)
+			VALUES ($1, $2, $3)`, saleID, sale.Status, sale.CreatedBy)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -227,35 +353,171 @@ func (s *PostgresStore) CreateSale(sale *SaleWithProducts) error {
 		}
 	}()
 
-	// Set the ID of the inserted sale (sales table)
-	sale.ID = saleID
-
 	return nil
 }
 
-// createProductVariations inserts product variations
-func createProductVariations(sale *SaleWithProducts, s *PostgresStore) ([]string, error) {
-	tx, err := s.db.Begin()
+// UpdateSaleStatus moves a sale from one status to another and records the
+// change in its status history. It fails if the status changed in between.
+// Cancelling a sale returns its products to the stock of their variants.
+func (s *PostgresStore) UpdateSaleStatus(saleID, from, to, note string, changedBy *string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE sales SET status = $1 WHERE id = $2 AND status = $3`, to, saleID, from)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("sale [%s] is no longer %s", saleID, from)
+		}
+
+		if to == SaleStatusCancelled {
+			_, err = tx.Exec(`
+				UPDATE catalog_variants cv
+				SET stock = cv.stock + sold.quantity
+				FROM (
+					SELECT variant_id, SUM(lines.quantity) AS quantity
+					FROM (
+						SELECT DISTINCT ON (pv.id) cv.id AS variant_id, pv.quantity
+						FROM sale_products sp
+						JOIN product_variations pv ON sp.product_variation_id = pv.id
+						JOIN catalog_variants cv
+							ON cv.product_id = pv.product_id AND LOWER(cv.color_name) = LOWER(pv.color)
+						WHERE sp.sale_id = $1
+						ORDER BY pv.id, cv.position
+					) AS lines
+					GROUP BY variant_id
+				) AS sold
+				WHERE cv.id = sold.variant_id AND cv.stock IS NOT NULL`, saleID)
+			if err != nil {
+				return err
+			}
+
+			// The coupon use is given back
+			_, err = tx.Exec(`
+				UPDATE coupons SET times_used = GREATEST(times_used - 1, 0)
+				WHERE id = (SELECT coupon_id FROM sales WHERE id = $1)`, saleID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO sale_status_changes (sale_id, from_status, to_status, note, changed_by)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5)`, saleID, from, to, note, changedBy)
+		return err
+	})
+}
+
+func (s *PostgresStore) GetSaleStatusChanges(saleID string) ([]*SaleStatusChange, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			sc.id,
+			sc.sale_id,
+			sc.from_status,
+			sc.to_status,
+			sc.note,
+			sc.changed_by,
+			NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''),
+			sc.created_at
+		FROM
+			sale_status_changes sc
+		LEFT JOIN
+			users u ON sc.changed_by = u.id
+		WHERE
+			sc.sale_id = $1
+		ORDER BY
+			sc.created_at`, saleID)
 	if err != nil {
-		return nil, fmt.Errorf("error starting transaction: %v", err)
+		return nil, err
 	}
-	defer func() {
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
 		if err != nil {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("error rolling back transaction: %v", rollbackErr)
-			}
-			return
+			log.Fatal(err)
 		}
-		if err := tx.Commit(); err != nil {
-			log.Printf("error committing transaction: %v", err)
+	}(rows)
+
+	var changes []*SaleStatusChange
+	for rows.Next() {
+		change := new(SaleStatusChange)
+		var fromStatus, note, changedBy, changedByName sql.NullString
+		err := rows.Scan(
+			&change.ID,
+			&change.SaleID,
+			&fromStatus,
+			&change.ToStatus,
+			&note,
+			&changedBy,
+			&changedByName,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
 		}
-	}()
 
+		change.FromStatus = nullStringToPtr(fromStatus)
+		change.Note = nullStringToPtr(note)
+		change.ChangedBy = nullStringToPtr(changedBy)
+		change.ChangedByName = nullStringToPtr(changedByName)
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// decrementVariantStock takes the quantity of each sold line from the stock of
+// the matching catalog variant. Variants without tracked stock are left alone,
+// and the whole sale is rejected when a tracked variant runs out.
+func decrementVariantStock(tx *sql.Tx, products []ProductVariations) error {
+	for _, line := range products {
+		var outOfStock bool
+		err := tx.QueryRow(`
+			WITH variant AS (
+				SELECT id, stock FROM catalog_variants
+				WHERE product_id = $1 AND LOWER(color_name) = LOWER($2)
+				ORDER BY position
+				LIMIT 1
+				FOR UPDATE
+			), updated AS (
+				UPDATE catalog_variants cv SET stock = cv.stock - $3
+				FROM variant v
+				WHERE cv.id = v.id AND v.stock >= $3
+				RETURNING cv.id
+			)
+			SELECT EXISTS(SELECT 1 FROM variant WHERE stock IS NOT NULL)
+				AND NOT EXISTS(SELECT 1 FROM updated)`,
+			line.ProductID, line.Color, line.Quantity).Scan(&outOfStock)
+		if err != nil {
+			return err
+		}
+		if outOfStock {
+			return fmt.Errorf("product [%s] is out of stock in color [%s]", line.ProductID, line.Color)
+		}
+	}
+	return nil
+}
+
+// createProductVariations inserts product variations
+func createProductVariations(tx *sql.Tx, sale *SaleWithProducts) ([]string, error) {
 	copyIn, err := tx.Prepare(pq.CopyIn(
 		"product_variations",
 		"product_id",
 		"color",
 		"price",
+		"quantity",
+		"list_price",
+		"unit_cost",
+		"price_override_reason",
+		"discount_type",
+		"discount_value",
+		"discount_amount",
 		"created_at",
 		"updated_at",
 	))
@@ -273,6 +535,13 @@ func createProductVariations(sale *SaleWithProducts, s *PostgresStore) ([]string
 			product.ProductID,
 			product.Color,
 			product.Price,
+			product.Quantity,
+			product.ListPrice,
+			product.UnitCost,
+			product.PriceOverrideReason,
+			sql.NullString{String: product.DiscountType, Valid: product.DiscountType != ""},
+			sql.NullInt64{Int64: int64(product.DiscountValue), Valid: product.DiscountType != ""},
+			product.DiscountAmount,
 			product.CreatedAt,
 			product.UpdatedAt,
 		)
@@ -312,7 +581,7 @@ func createProductVariations(sale *SaleWithProducts, s *PostgresStore) ([]string
 	return insertedIDs, nil
 }
 
-func createSale(customerInSale *Customer, s *PostgresStore) (string, error) {
+func createSale(tx *sql.Tx, customerInSale *Customer, sale *SaleWithProducts) (string, error) {
 	query := `
         INSERT INTO sales (
 			customer_id,
@@ -324,10 +593,17 @@ func createSale(customerInSale *Customer, s *PostgresStore) (string, error) {
 			customer_department,
 			customer_comments,
 			customer_cc,
+			status,
+			discount_type,
+			discount_value,
+			discount_amount,
+			coupon_id,
+			coupon_code,
+			order_number,
 		    created_at,
 		    updated_at
         )
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
         RETURNING id
     `
 
@@ -335,8 +611,16 @@ func createSale(customerInSale *Customer, s *PostgresStore) (string, error) {
 	customerInSale.CreatedAt = time.Time{}
 	customerInSale.UpdatedAt = time.Time{}
 
+	// The order number is taken in the same transaction as the insert so
+	// numbers are never skipped
+	orderNumber, err := nextCounterValue(tx, saleOrderNumberCounter)
+	if err != nil {
+		return "", err
+	}
+	sale.OrderNumber = formatOrderNumber(orderNumber)
+
 	var id string
-	err := s.db.QueryRow(
+	err = tx.QueryRow(
 		query,
 		customerInSale.ID,
 		customerInSale.Name,
@@ -347,6 +631,13 @@ func createSale(customerInSale *Customer, s *PostgresStore) (string, error) {
 		customerInSale.Department,
 		customerInSale.Comments,
 		customerInSale.Cc,
+		sale.Status,
+		sql.NullString{String: sale.DiscountType, Valid: sale.DiscountType != ""},
+		sql.NullInt64{Int64: int64(sale.DiscountValue), Valid: sale.DiscountType != ""},
+		sale.DiscountAmount,
+		sale.CouponID,
+		sale.CouponCode,
+		orderNumber,
 		customerInSale.CreatedAt,
 		customerInSale.UpdatedAt,
 	).Scan(&id)
@@ -360,13 +651,7 @@ func createSale(customerInSale *Customer, s *PostgresStore) (string, error) {
 	return id, nil
 }
 
-func createSaleProducts(saleID string, pvIDs []string, s *PostgresStore) error {
-	// Begin a transaction
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("error starting transaction: %v", err)
-	}
-
+func createSaleProducts(tx *sql.Tx, saleID string, pvIDs []string) error {
 	// Prepare the COPY command
 	copyIn, err := tx.Prepare(pq.CopyIn(
 		"sale_products",
@@ -399,19 +684,64 @@ func createSaleProducts(saleID string, pvIDs []string, s *PostgresStore) error {
 	if err != nil {
 		return fmt.Errorf("error executing COPY statement: %v", err)
 	}
-
-	// Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("error committing transaction: %v", err)
-	}
 	return nil
 }
 
-func (s *PostgresStore) GetSales() ([]*SaleResponse, error) {
-	rows, err := s.db.Query(`
+// saleTotalSQL is what the customer owes for the sale s, its discounted
+// products plus the shipping charged to them.
+const saleTotalSQL = `(
+	(
+		SELECT COALESCE(SUM(pv_total.price * pv_total.quantity - pv_total.discount_amount), 0)
+		FROM sale_products sp_total
+		JOIN product_variations pv_total ON sp_total.product_variation_id = pv_total.id
+		WHERE sp_total.sale_id = s.id
+	) + (
+		SELECT COALESCE(SUM(sh_total.cost), 0)
+		FROM shipments sh_total
+		WHERE sh_total.sale_id = s.id AND sh_total.charge_customer
+	) - s.discount_amount
+)`
+
+const saleOrderNumberCounter = "sales"
+
+// orderNumberSQL formats the order number of the sale with the given alias
+// like formatOrderNumber does.
+func orderNumberSQL(alias string) string {
+	number := alias + ".order_number::text"
+	return pq.QuoteLiteral(orderNumberPrefix()) + " || LPAD(" + number + ", GREATEST(LENGTH(" + number + "), 6), '0')"
+}
+
+// saleAmountPaidSQL is the sum of the payments of the sale s.
+const saleAmountPaidSQL = `(
+	SELECT COALESCE(SUM(pay.amount), 0) FROM payments pay WHERE pay.sale_id = s.id
+)`
+
+// saleProductVariationJSON is the JSON of a sold product variation, pv being
+// the variation and p its product.
+const saleProductVariationJSON = `JSON_BUILD_OBJECT(
+				'id', pv.id,
+				'color', pv.color,
+				'price', pv.price,
+				'quantity', pv.quantity,
+				'list_price', pv.list_price,
+				'unit_cost', pv.unit_cost,
+				'price_override_reason', pv.price_override_reason,
+				'discount_type', pv.discount_type,
+				'discount_value', pv.discount_value,
+				'discount_amount', pv.discount_amount,
+				'line_total', pv.price * pv.quantity - pv.discount_amount,
+				'image', p.image,
+				'name', p.name
+			)`
+
+// salesQuery builds the query listing sales with their product variations and
+// the other sales of the same customer. where filters the sales (s) and
+// orderBy sorts them.
+func salesQuery(where, orderBy string) string {
+	return `
 		SELECT
 			s.id,
+			` + orderNumberSQL("s") + ` AS order_number,
 			s.customer_id,
 			s.customer_name,
 			s.customer_instagram_account,
@@ -422,48 +752,48 @@ func (s *PostgresStore) GetSales() ([]*SaleResponse, error) {
 			s.customer_comments,
 			s.customer_cc,
 			COUNT(*) OVER (PARTITION BY s.customer_id) AS customer_total_purchases,
+			s.status,
+			s.discount_type,
+			s.discount_value,
+			s.discount_amount,
+			s.coupon_id,
+			s.coupon_code,
+			` + saleTotalSQL + ` AS total,
+			` + saleAmountPaidSQL + ` AS amount_paid,
 			s.created_at,
 			s.updated_at,
-			JSON_AGG(JSON_BUILD_OBJECT(
-				'id', pv.id,
-				'color', pv.color,
-				'price', pv.price,
-				'image', p.image,
-				'name', p.name
-				 )) AS product_variations,
+			JSON_AGG(` + saleProductVariationJSON + `) AS product_variations,
 			COALESCE((
-						 SELECT JSON_AGG(JSON_BUILD_OBJECT(
-								 'id', so.id,
-								 'customer_id', so.customer_id,
-								 'customer_name', so.customer_name,
-								 'customer_instagram_account', so.customer_instagram_account,
-								 'customer_phone', so.customer_phone,
-								 'customer_address', so.customer_address,
-								 'customer_city', so.customer_city,
-								 'customer_department', so.customer_department,
-								 'customer_comments', so.customer_comments,
-								 'customer_cc', so.customer_cc,
-								 'customer_total_purchases', 0,
-								 'created_at', so.created_at,
-								 'updated_at', so.updated_at,
-								 'product_variations', (
-									 SELECT JSON_AGG(JSON_BUILD_OBJECT(
-											 'id', pv.id,
-											 'color', pv.color,
-											 'price', pv.price,
-											 'image', p.image,
-											 'name', p.name
-													 ))
-									 FROM sales s_inner
-											  JOIN sale_products sp_inner ON s_inner.id = sp_inner.sale_id
-											  JOIN product_variations pv ON sp_inner.product_variation_id = pv.id
-											  JOIN products p ON pv.product_id = p.id
-									 WHERE s_inner.id = so.id
-								 )
-										 ))
-						 FROM sales so
-						 WHERE so.customer_id = s.customer_id AND so.id != s.id
-					 ), '[]'::json) AS other_sales
+				SELECT JSON_AGG(JSON_BUILD_OBJECT(
+					'id', so.id,
+					'order_number', ` + orderNumberSQL("so") + `,
+					'customer_id', so.customer_id,
+					'customer_name', so.customer_name,
+					'customer_instagram_account', so.customer_instagram_account,
+					'customer_phone', so.customer_phone,
+					'customer_address', so.customer_address,
+					'customer_city', so.customer_city,
+					'customer_department', so.customer_department,
+					'customer_comments', so.customer_comments,
+					'customer_cc', so.customer_cc,
+					'customer_total_purchases', 0,
+					'status', so.status,
+					'discount_amount', so.discount_amount,
+					'total', (SELECT ` + saleTotalSQL + ` FROM sales s WHERE s.id = so.id),
+					'amount_paid', (SELECT ` + saleAmountPaidSQL + ` FROM sales s WHERE s.id = so.id),
+					'created_at', so.created_at,
+					'updated_at', so.updated_at,
+					'product_variations', (
+						SELECT JSON_AGG(` + saleProductVariationJSON + `)
+						FROM sale_products sp_inner
+						JOIN product_variations pv ON sp_inner.product_variation_id = pv.id
+						JOIN products p ON pv.product_id = p.id
+						WHERE sp_inner.sale_id = so.id
+					)
+				))
+				FROM sales so
+				WHERE so.customer_id = s.customer_id AND so.id != s.id
+			), '[]'::json) AS other_sales
 		FROM
 			sales s
 		JOIN
@@ -472,20 +802,35 @@ func (s *PostgresStore) GetSales() ([]*SaleResponse, error) {
 			product_variations pv ON sp.product_variation_id = pv.id
 		JOIN
 			products p ON pv.product_id = p.id
+		WHERE (` + where + `)
 		GROUP BY
-			s.id,
-			s.customer_id,
-			s.customer_name,
-			s.customer_instagram_account,
-			s.customer_phone,
-			s.customer_address,
-			s.customer_city,
-			s.customer_department,
-			s.customer_comments,
-			s.customer_cc,
-			s.created_at,
-			s.updated_at;
-`)
+			s.id
+		` + orderBy
+}
+
+func (s *PostgresStore) GetSales(filter SaleFilter) ([]*SaleResponse, error) {
+	orderNumber, isOrderNumber := parseOrderNumber(filter.Search)
+	return s.querySales(salesQuery(
+		`($1 = '' OR s.status = $1) AND (
+			$2 = ''
+			OR s.order_number = $3
+			OR s.customer_name ILIKE '%' || $2 || '%'
+			OR s.customer_instagram_account ILIKE '%' || $2 || '%'
+			OR s.customer_phone::text LIKE '%' || $2 || '%'
+		)`,
+		"",
+	), filter.Status, filter.Search, sql.NullInt64{Int64: int64(orderNumber), Valid: isOrderNumber})
+}
+
+func (s *PostgresStore) GetSalesLast3Months() ([]*SaleResponse, error) {
+	return s.querySales(salesQuery(
+		"s.created_at >= (NOW() - INTERVAL '3 months')",
+		"ORDER BY s.created_at DESC",
+	))
+}
+
+func (s *PostgresStore) querySales(query string, args ...any) ([]*SaleResponse, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -507,113 +852,50 @@ func (s *PostgresStore) GetSales() ([]*SaleResponse, error) {
 		sales = append(sales, sale)
 	}
 
+	if err := s.attachSaleDetails(sales...); err != nil {
+		return nil, err
+	}
+
 	return sales, nil
 }
 
-func (s *PostgresStore) GetSalesLast3Months() ([]*SaleResponse, error) {
-	rows, err := s.db.Query(`
-		SELECT
-			 s.id,
-			 s.customer_id,
-			 s.customer_name,
-			 s.customer_instagram_account,
-			 s.customer_phone,
-			 s.customer_address,
-			 s.customer_city,
-			 s.customer_department,
-			 s.customer_comments,
-			 s.customer_cc,
-			 COUNT(*) OVER (PARTITION BY s.customer_id) AS customer_total_purchases,
-			 s.created_at,
-			 s.updated_at,
-			 JSON_AGG(JSON_BUILD_OBJECT(
-				 'id', pv.id,
-				 'color', pv.color,
-				 'price', pv.price,
-				 'image', p.image,
-				 'name', p.name
-			 )) AS product_variations,
-			 COALESCE((
-					 SELECT JSON_AGG(JSON_BUILD_OBJECT(
-						 'id', so.id,
-						 'customer_id', so.customer_id,
-						 'customer_name', so.customer_name,
-						 'customer_instagram_account', so.customer_instagram_account,
-						 'customer_phone', so.customer_phone,
-						 'customer_address', so.customer_address,
-						 'customer_city', so.customer_city,
-						 'customer_department', so.customer_department,
-						 'customer_comments', so.customer_comments,
-						 'customer_cc', so.customer_cc,
-						 'customer_total_purchases', 0,
-						 'created_at', so.created_at,
-						 'updated_at', so.updated_at,
-						 'product_variations', (
-							 SELECT JSON_AGG(JSON_BUILD_OBJECT(
-								 'id', pv.id,
-								 'color', pv.color,
-								 'price', pv.price,
-								 'image', p.image,
-								 'name', p.name
-							 ))
-							 FROM sales s_inner
-								  JOIN sale_products sp_inner ON s_inner.id = sp_inner.sale_id
-								  JOIN product_variations pv ON sp_inner.product_variation_id = pv.id
-								  JOIN products p ON pv.product_id = p.id
-							 WHERE s_inner.id = so.id
-						 )
-						 ))
-					 FROM sales so
-					 WHERE so.customer_id = s.customer_id AND so.id != s.id
-				 ), '[]'::json) AS other_sales
-		FROM
-			 sales s
-		JOIN
-			 sale_products sp ON s.id = sp.sale_id
-		JOIN
-			 product_variations pv ON sp.product_variation_id = pv.id
-		JOIN
-			 products p ON pv.product_id = p.id
-		WHERE
-			 s.created_at >= (NOW() - INTERVAL '3 months')
-		GROUP BY
-			 s.id,
-			 s.customer_id,
-			 s.customer_name,
-			 s.customer_instagram_account,
-			 s.customer_phone,
-			 s.customer_address,
-			 s.customer_city,
-			 s.customer_department,
-			 s.customer_comments,
-			 s.customer_cc,
-			 s.created_at,
-			 s.updated_at
-		ORDER BY s.created_at DESC;
-	`)
+// attachSaleDetails loads the shipments and payments of the given sales.
+func (s *PostgresStore) attachSaleDetails(sales ...*SaleResponse) error {
+	var saleIDs []string
+	for _, sale := range sales {
+		saleIDs = append(saleIDs, sale.ID)
+	}
+
+	shipmentsBySale, err := s.getShipmentsBySaleIDs(saleIDs)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer func(rows *sql.Rows) {
-		if err := rows.Close(); err != nil {
-			log.Fatal(err)
+
+	paymentsBySale, err := s.getPaymentsBySaleIDs(saleIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, sale := range sales {
+		sale.Shipments = shipmentsBySale[sale.ID]
+		if sale.Shipments == nil {
+			sale.Shipments = []Shipment{}
 		}
-	}(rows)
-	var sales []*SaleResponse
-	for rows.Next() {
-		sale, err := scanIntoSales(rows)
-		if err != nil {
-			return nil, err
+		sale.Payments = paymentsBySale[sale.ID]
+		if sale.Payments == nil {
+			sale.Payments = []Payment{}
 		}
-		sales = append(sales, sale)
 	}
-	return sales, nil
+
+	return nil
 }
 
+// GetSalesByMonth lists the sales grouped by the month of the business time
+// zone they were made in.
 func (s *PostgresStore) GetSalesByMonth() ([]*SaleResponseSortedByMonth, error) {
 	rows, err := s.db.Query(`
 		SELECT
-			DATE_TRUNC('month', s.created_at) AS sort_by_month,
+			DATE_TRUNC('month', s.created_at AT TIME ZONE $1) AT TIME ZONE $1 AS sort_by_month,
 			s.id,
 			s.customer_id,
 			s.customer_name,
@@ -625,12 +907,15 @@ func (s *PostgresStore) GetSalesByMonth() ([]*SaleResponseSortedByMonth, error)
 			s.customer_comments,
 			s.customer_cc,
 			COUNT(*) OVER (PARTITION BY s.customer_id) AS customer_total_purchases,
+			`+saleTotalSQL+` AS total,
+			`+saleAmountPaidSQL+` AS amount_paid,
 			s.created_at,
 			s.updated_at,
 			JSON_AGG(JSON_BUILD_OBJECT(
 				'id', pv.id,
 				'color', pv.color,
 				'price', pv.price,
+				'quantity', pv.quantity,
 				'image', p.image,
 				'name', p.name
 			)) AS product_variations
@@ -659,7 +944,7 @@ func (s *PostgresStore) GetSalesByMonth() ([]*SaleResponseSortedByMonth, error)
 		ORDER BY
 			sort_by_month DESC,
 			s.created_at DESC;
-	`)
+	`, businessLocation().String())
 	if err != nil {
 		return nil, err
 	}
@@ -688,8 +973,11 @@ func scanIntoSales(rows *sql.Rows) (*SaleResponse, error) {
 	sale := new(SaleResponse)
 	var productVariationsJSON []byte
 	var otherSalesJSON []byte
+	var discountType, couponID, couponCode sql.NullString
+	var discountValue sql.NullInt64
 	err := rows.Scan(
 		&sale.ID,
+		&sale.OrderNumber,
 		&sale.CustomerID,
 		&sale.CustomerName,
 		&sale.CustomerInstagramAccount,
@@ -700,6 +988,14 @@ func scanIntoSales(rows *sql.Rows) (*SaleResponse, error) {
 		&sale.CustomerComments,
 		&sale.CustomerCc,
 		&sale.CustomerTotalPurchases,
+		&sale.Status,
+		&discountType,
+		&discountValue,
+		&sale.DiscountAmount,
+		&couponID,
+		&couponCode,
+		&sale.Total,
+		&sale.AmountPaid,
 		&sale.CreatedAt,
 		&sale.UpdatedAt,
 		&productVariationsJSON, // Scan JSON data into a []byte
@@ -720,89 +1016,21 @@ func scanIntoSales(rows *sql.Rows) (*SaleResponse, error) {
 		return nil, fmt.Errorf("error unmarshaling otherSalesJSON JSON: %v", err)
 	}
 
+	sale.DiscountType = nullStringToPtr(discountType)
+	sale.DiscountValue = nullIntToPtr(discountValue)
+	sale.CouponID = nullStringToPtr(couponID)
+	sale.CouponCode = nullStringToPtr(couponCode)
+	sale.Balance = sale.Total.Sub(sale.AmountPaid)
+	for i := range sale.OtherSales {
+		other := &sale.OtherSales[i]
+		other.Balance = other.Total.Sub(other.AmountPaid)
+	}
+
 	return sale, nil
 }
 
 func (s *PostgresStore) GetSaleByID(id string) (*SaleResponse, error) {
-	rows, err := s.db.Query(`
-		SELECT
-			s.id,
-			s.customer_id,
-			s.customer_name,
-			s.customer_instagram_account,
-			s.customer_phone,
-			s.customer_address,
-			s.customer_city,
-			s.customer_department,
-			s.customer_comments,
-			s.customer_cc,
-		    COUNT(*) OVER (PARTITION BY s.customer_id) AS customer_total_purchases,
-			s.created_at,
-			s.updated_at,
-			JSON_AGG(JSON_BUILD_OBJECT(
-				'id', pv.id,
-				'color', pv.color,
-				'price', pv.price,
-				'image', p.image,
-				'name', p.name
-			)) AS product_variations,
-			COALESCE((
-			 SELECT JSON_AGG(JSON_BUILD_OBJECT(
-					 'id', so.id,
-					 'customer_id', so.customer_id,
-					 'customer_name', so.customer_name,
-					 'customer_instagram_account', so.customer_instagram_account,
-					 'customer_phone', so.customer_phone,
-					 'customer_address', so.customer_address,
-					 'customer_city', so.customer_city,
-					 'customer_department', so.customer_department,
-					 'customer_comments', so.customer_comments,
-					 'customer_cc', so.customer_cc,
-					 'customer_total_purchases', 0,
-					 'created_at', so.created_at,
-					 'updated_at', so.updated_at,
-					 'product_variations', (
-						 SELECT JSON_AGG(JSON_BUILD_OBJECT(
-								 'id', pv.id,
-								 'color', pv.color,
-								 'price', pv.price,
-								 'image', p.image,
-								 'name', p.name
-										 ))
-						 FROM sales s_inner
-								  JOIN sale_products sp_inner ON s_inner.id = sp_inner.sale_id
-								  JOIN product_variations pv ON sp_inner.product_variation_id = pv.id
-								  JOIN products p ON pv.product_id = p.id
-						 WHERE s_inner.id = so.id
-					 )
-							 ))
-			 FROM sales so
-			 WHERE so.customer_id = s.customer_id AND so.id != s.id
-		 ), '[]'::json) AS other_sales
-		FROM
-			sales s
-		JOIN
-			sale_products sp ON s.id = sp.sale_id
-		JOIN
-			product_variations pv ON sp.product_variation_id = pv.id
-		JOIN
-			products p ON pv.product_id = p.id
-		WHERE
-			s.id = $1
-		GROUP BY
-			s.id,
-			s.customer_id,
-			s.customer_name,
-			s.customer_instagram_account,
-			s.customer_phone,
-			s.customer_address,
-			s.customer_city,
-			s.customer_department,
-			s.customer_comments,
-			s.customer_cc,
-			s.created_at,
-			s.updated_at;
-	`, id)
+	rows, err := s.db.Query(salesQuery("s.id = $1", ""), id)
 	if err != nil {
 		return nil, err
 	}
@@ -815,7 +1043,14 @@ func (s *PostgresStore) GetSaleByID(id string) (*SaleResponse, error) {
 	}(rows)
 
 	for rows.Next() {
-		return scanIntoSales(rows)
+		sale, err := scanIntoSales(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.attachSaleDetails(sale); err != nil {
+			return nil, err
+		}
+		return sale, nil
 	}
 
 	return nil, fmt.Errorf("sale [%s] not found", id)
@@ -825,6 +1060,7 @@ func scanIntoSalesSortedByMoth(rows *sql.Rows) (*SaleResponseSortedByMonth, erro
 	sale := new(SaleResponseSortedByMonth)
 	var productVariationsJSON []byte
 	err := rows.Scan(
+		&sale.SortByMonth,
 		&sale.ID,
 		&sale.CustomerID,
 		&sale.CustomerName,
@@ -836,10 +1072,11 @@ func scanIntoSalesSortedByMoth(rows *sql.Rows) (*SaleResponseSortedByMonth, erro
 		&sale.CustomerComments,
 		&sale.CustomerCc,
 		&sale.CustomerTotalPurchases,
+		&sale.Total,
+		&sale.AmountPaid,
 		&sale.CreatedAt,
 		&sale.UpdatedAt,
 		&productVariationsJSON, // Scan JSON data into a []byte
-		&sale.SortByMonth,
 	)
 	if err != nil {
 		return nil, err
@@ -850,6 +1087,7 @@ func scanIntoSalesSortedByMoth(rows *sql.Rows) (*SaleResponseSortedByMonth, erro
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling product_variations JSON: %v", err)
 	}
+	sale.Balance = sale.Total.Sub(sale.AmountPaid)
 
 	return sale, nil
 }
@@ -1,37 +1,248 @@
 package main
 
-import "time"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
+// Statuses of a sale. A sale starts pending, and moves through the statuses
+// allowed by saleStatusTransitions.
+const (
+	SaleStatusPending   = "pending"
+	SaleStatusPaid      = "paid"
+	SaleStatusShipped   = "shipped"
+	SaleStatusDelivered = "delivered"
+	SaleStatusCancelled = "cancelled"
+)
+
+// saleStatusTransitions lists the statuses each status can move to.
+var saleStatusTransitions = map[string][]string{
+	SaleStatusPending: {SaleStatusPaid, SaleStatusCancelled},
+	SaleStatusPaid:    {SaleStatusShipped, SaleStatusDelivered, SaleStatusCancelled},
+	SaleStatusShipped: {SaleStatusDelivered},
+}
+
+// paidSaleStatuses are the statuses of sales that were paid for.
+var paidSaleStatuses = []string{SaleStatusPaid, SaleStatusShipped, SaleStatusDelivered}
+
+// isCountedSaleStatus reports whether a sale in status counts in the reports.
+// Cancelled sales never do, and with paidOnly neither do the unpaid ones.
+func isCountedSaleStatus(status string, paidOnly bool) bool {
+	if status == SaleStatusCancelled {
+		return false
+	}
+	if !paidOnly {
+		return true
+	}
+	for _, paid := range paidSaleStatuses {
+		if status == paid {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidSaleStatus(status string) bool {
+	switch status {
+	case SaleStatusPending, SaleStatusPaid, SaleStatusShipped, SaleStatusDelivered, SaleStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// validateSaleStatusTransition checks that a sale can move from one status to
+// the other.
+func validateSaleStatusTransition(from, to string) error {
+	if !isValidSaleStatus(to) {
+		return fmt.Errorf("invalid sale status [%s]", to)
+	}
+	for _, allowed := range saleStatusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("a %s sale can't be marked as %s", from, to)
+}
+
+// SaleWithProducts is a sale being created. The sale discount applies to the
+// subtotal left after the discounts of each line.
 type SaleWithProducts struct {
-	ID         string              `json:"id"`
-	CustomerID string              `json:"customer_id"`
-	Products   []ProductVariations `json:"products"`
+	ID             string              `json:"id"`
+	OrderNumber    string              `json:"order_number"`
+	CustomerID     string              `json:"customer_id"`
+	Status         string              `json:"status"`
+	CreatedBy      *string             `json:"created_by"`
+	DiscountType   string              `json:"discount_type,omitempty"`
+	DiscountValue  int                 `json:"discount_value,omitempty"`
+	DiscountAmount Money               `json:"discount_amount"`
+	CouponID       *string             `json:"coupon_id,omitempty"`
+	CouponCode     *string             `json:"coupon_code,omitempty"`
+	OrderRequestID *string             `json:"order_request_id,omitempty"`
+	Products       []ProductVariations `json:"products"`
+}
+
+// Subtotal is the sum of the lines of the sale after their discounts.
+func (sale *SaleWithProducts) Subtotal() Money {
+	subtotal := NewMoney(0, baseCurrency)
+	for _, line := range sale.Products {
+		subtotal = subtotal.Add(line.LineTotal())
+	}
+	return subtotal
+}
+
+// Total is what the customer pays for the products of the sale.
+func (sale *SaleWithProducts) Total() Money {
+	return sale.Subtotal().Sub(sale.DiscountAmount)
+}
+
+// ApplyDiscounts computes the discount amount of every line and of the sale.
+func (sale *SaleWithProducts) ApplyDiscounts() error {
+	for i := range sale.Products {
+		line := &sale.Products[i]
+		amount, err := computeDiscount(line.DiscountType, line.DiscountValue, line.GrossTotal())
+		if err != nil {
+			return err
+		}
+		line.DiscountAmount = amount
+	}
+
+	amount, err := computeDiscount(sale.DiscountType, sale.DiscountValue, sale.Subtotal())
+	if err != nil {
+		return err
+	}
+	sale.DiscountAmount = amount
+
+	return nil
 }
 
+// CreateSaleRequest creates a pending sale unless another Status is given,
+// e.g. for sales that were already paid.
+// A sale discount can be given either with DiscountType and DiscountValue or
+// with a CouponCode.
 type CreateSaleRequest struct {
-	CustomerID string              `json:"customer_id"`
-	Products   []ProductVariations `json:"products"`
+	CustomerID    string              `json:"customer_id"`
+	Status        string              `json:"status"`
+	DiscountType  string              `json:"discount_type"`
+	DiscountValue int                 `json:"discount_value"`
+	CouponCode    string              `json:"coupon_code"`
+	Products      []ProductVariations `json:"products"`
+	// OrderRequestID is set when converting an order request, never by clients
+	OrderRequestID *string `json:"-"`
 }
 
+// SaleFilter narrows sale listings, an empty Status lists every sale.
+// Search matches the order number or the name, Instagram account or phone of
+// the customer.
+type SaleFilter struct {
+	Status string
+	Search string
+}
+
+// orderNumberPrefix is printed before order numbers, V- unless the
+// ORDER_NUMBER_PREFIX env var sets another one.
+func orderNumberPrefix() string {
+	if prefix, ok := os.LookupEnv("ORDER_NUMBER_PREFIX"); ok {
+		return prefix
+	}
+	return "V-"
+}
+
+// formatOrderNumber formats the sequential number of a sale, e.g. V-000123.
+func formatOrderNumber(number int) string {
+	return fmt.Sprintf("%s%06d", orderNumberPrefix(), number)
+}
+
+// parseOrderNumber reads an order number with or without its prefix and
+// leading zeros, e.g. V-000123, v-123 or 123.
+func parseOrderNumber(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	prefix := orderNumberPrefix()
+	if len(value) >= len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		value = value[len(prefix):]
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, false
+	}
+	return number, true
+}
+
+// SaleStatusChange is an entry of the status history of a sale. FromStatus
+// is nil for the status the sale was created with.
+type SaleStatusChange struct {
+	ID            string    `json:"id"`
+	SaleID        string    `json:"sale_id"`
+	FromStatus    *string   `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	Note          *string   `json:"note,omitempty"`
+	ChangedBy     *string   `json:"changed_by"`
+	ChangedByName *string   `json:"changed_by_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type UpdateSaleStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// ProductVariations is a sold item. When creating a sale, SKU can be sent in
+// place of ProductID and Color. Price is the unit price before the discount
+// of the line, it defaults to ListPrice, the price of the product when sold,
+// and can only differ from it when PriceOverride is set with a reason.
+// UnitCost is what a unit of the product cost when sold, it is taken from the
+// product and is nil when the product had no cost. Quantity defaults to one,
+// sales made before it existed have a line per unit.
 type ProductVariations struct {
-	ID        string    `json:"id"`
-	ProductID string    `json:"product_id"`
-	Color     string    `json:"color"`
-	Price     int       `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  string    `json:"id"`
+	ProductID           string    `json:"product_id"`
+	SKU                 string    `json:"sku,omitempty"`
+	Color               string    `json:"color"`
+	Price               Money     `json:"price"`
+	Quantity            int       `json:"quantity"`
+	ListPrice           Money     `json:"list_price"`
+	UnitCost            *Money    `json:"unit_cost,omitempty"`
+	PriceOverride       bool      `json:"price_override,omitempty"`
+	PriceOverrideReason *string   `json:"price_override_reason,omitempty"`
+	DiscountType        string    `json:"discount_type,omitempty"`
+	DiscountValue       int       `json:"discount_value,omitempty"`
+	DiscountAmount      Money     `json:"discount_amount"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// GrossTotal is the price of every unit of the line before its discount.
+func (pv ProductVariations) GrossTotal() Money {
+	return pv.Price.Mul(pv.Quantity)
+}
+
+// LineTotal is what is charged for the line after its discount.
+func (pv ProductVariations) LineTotal() Money {
+	return pv.GrossTotal().Sub(pv.DiscountAmount)
 }
 
 type ProductVariationsResponse struct {
-	ID    string `json:"id"`
-	Color string `json:"color"`
-	Price int    `json:"price"`
-	Image string `json:"image"`
-	Name  string `json:"name"`
+	ID                  string  `json:"id"`
+	Color               string  `json:"color"`
+	Price               Money   `json:"price"`
+	Quantity            int     `json:"quantity"`
+	ListPrice           Money   `json:"list_price"`
+	UnitCost            *Money  `json:"unit_cost"`
+	PriceOverrideReason *string `json:"price_override_reason"`
+	DiscountType        *string `json:"discount_type"`
+	DiscountValue       *int    `json:"discount_value"`
+	DiscountAmount      Money   `json:"discount_amount"`
+	LineTotal           Money   `json:"line_total"`
+	Image               string  `json:"image"`
+	Name                string  `json:"name"`
 }
 
 type SaleResponse struct {
 	ID                       string                      `json:"id"`
+	OrderNumber              string                      `json:"order_number"`
 	CustomerID               string                      `json:"customer_id"`
 	CustomerName             string                      `json:"customer_name"`
 	CustomerInstagramAccount string                      `json:"customer_instagram_account"`
@@ -42,9 +253,20 @@ type SaleResponse struct {
 	CustomerComments         string                      `json:"customer_comments"`
 	CustomerCc               string                      `json:"customer_cc"`
 	CustomerTotalPurchases   int                         `json:"customer_total_purchases"`
+	Status                   string                      `json:"status"`
+	DiscountType             *string                     `json:"discount_type"`
+	DiscountValue            *int                        `json:"discount_value"`
+	DiscountAmount           Money                       `json:"discount_amount"`
+	CouponID                 *string                     `json:"coupon_id"`
+	CouponCode               *string                     `json:"coupon_code"`
+	Total                    Money                       `json:"total"`
+	AmountPaid               Money                       `json:"amount_paid"`
+	Balance                  Money                       `json:"balance"`
 	CreatedAt                string                      `json:"created_at"`
 	UpdatedAt                string                      `json:"updated_at"`
 	ProductVariations        []ProductVariationsResponse `json:"product_variations"`
+	Shipments                []Shipment                  `json:"shipments"`
+	Payments                 []Payment                   `json:"payments"`
 	OtherSales               []SaleResponse              `json:"other_sales"`
 	// OtherSales: it returns null inside nested data,
 	// so it could be an empty slice, with data or 'null' after parsing it
@@ -57,10 +279,21 @@ type SaleResponseSortedByMonth struct {
 
 func NewSale(
 	customerID string,
+	status string,
 	products []ProductVariations,
+	createdBy *string,
 ) (*SaleWithProducts, error) {
+	if status == "" {
+		status = SaleStatusPending
+	}
+	if !isValidSaleStatus(status) || status == SaleStatusCancelled {
+		return nil, fmt.Errorf("invalid sale status [%s]", status)
+	}
+
 	return &SaleWithProducts{
 		CustomerID: customerID,
+		Status:     status,
+		CreatedBy:  createdBy,
 		Products:   products,
 	}, nil
 }
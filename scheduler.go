@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// StartScheduler runs the periodic background jobs every interval until the
+// process exits. Errors are logged and retried on the next tick.
+func StartScheduler(store Storage, interval time.Duration) {
+	runScheduledJobs(store)
+
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		runScheduledJobs(store)
+	}
+}
+
+func runScheduledJobs(store Storage) {
+	if err := store.ApplyDuePriceSchedules(time.Now()); err != nil {
+		log.Printf("error applying price schedules: %v", err)
+	}
+
+	if err := store.MaterializeRecurringExpenses(time.Now()); err != nil {
+		log.Printf("error creating recurring expenses: %v", err)
+	}
+}
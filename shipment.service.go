@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func getShipmentID(r *http.Request) (string, error) {
+	return getRouteUUID(r, "shipmentID")
+}
+
+func (server *APIServer) handleCreateShipment(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	sale, err := server.store.GetSaleByID(saleID)
+	if err != nil {
+		return err
+	}
+
+	req := new(CreateShipmentRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	shipment, err := NewShipment(
+		saleID,
+		req.Carrier,
+		req.TrackingNumber,
+		req.Cost,
+		req.ChargeCustomer,
+		req.ShippedAt,
+		req.DeliveredAt,
+		req.Notes,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateShipment(shipment); err != nil {
+		return err
+	}
+
+	if err := server.syncSaleStatusWithShipment(r, sale, shipment); err != nil {
+		return err
+	}
+
+	// Recovering shipment from DB
+	createdShipment, err := server.store.GetShipmentByID(saleID, shipment.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdShipment)
+}
+
+func (server *APIServer) handleGetShipments(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := server.store.GetSaleByID(saleID); err != nil {
+		return err
+	}
+
+	shipments, err := server.store.GetShipments(saleID)
+	if err != nil {
+		return err
+	}
+	if shipments == nil {
+		shipments = []Shipment{}
+	}
+
+	return WriteJSON(w, http.StatusOK, shipments)
+}
+
+func (server *APIServer) handleGetShipmentByID(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	shipmentID, err := getShipmentID(r)
+	if err != nil {
+		return err
+	}
+
+	shipment, err := server.store.GetShipmentByID(saleID, shipmentID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, shipment)
+}
+
+func (server *APIServer) handleUpdateShipment(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	shipmentID, err := getShipmentID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetShipmentByID(saleID, shipmentID)
+	if err != nil {
+		return err
+	}
+
+	var shipment Shipment
+	if err := json.NewDecoder(r.Body).Decode(&shipment); err != nil {
+		return err
+	}
+
+	shipment.ID = shipmentID
+	shipment.SaleID = saleID
+	if err := shipment.Validate(); err != nil {
+		return err
+	}
+
+	if err := server.store.UpdateShipment(&shipment); err != nil {
+		return err
+	}
+
+	sale, err := server.store.GetSaleByID(saleID)
+	if err != nil {
+		return err
+	}
+
+	if err := server.syncSaleStatusWithShipment(r, sale, &shipment); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedShipment, err := server.store.GetShipmentByID(saleID, shipmentID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedShipment)
+}
+
+func (server *APIServer) handleDeleteShipment(w http.ResponseWriter, r *http.Request) error {
+	saleID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	shipmentID, err := getShipmentID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetShipmentByID(saleID, shipmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteShipment(saleID, shipmentID); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": shipmentID})
+}
+
+// syncSaleStatusWithShipment moves a paid sale to shipped or delivered when
+// its shipment is. Sales that can't make that move are left as they are.
+func (server *APIServer) syncSaleStatusWithShipment(r *http.Request, sale *SaleResponse, shipment *Shipment) error {
+	status := ""
+	switch {
+	case shipment.DeliveredAt != nil:
+		status = SaleStatusDelivered
+	case shipment.ShippedAt != nil:
+		status = SaleStatusShipped
+	default:
+		return nil
+	}
+
+	if validateSaleStatusTransition(sale.Status, status) != nil {
+		return nil
+	}
+
+	return server.store.UpdateSaleStatus(sale.ID, sale.Status, status, "Shipment "+shipment.Carrier, getAuthUserID(r))
+}
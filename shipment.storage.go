@@ -0,0 +1,215 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+func (s *PostgresStore) CreateShipmentsTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS shipments (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            sale_id UUID NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+            carrier VARCHAR(100) NOT NULL,
+            tracking_number VARCHAR(100),
+            cost BIGINT NOT NULL DEFAULT 0,
+            charge_customer BOOLEAN NOT NULL DEFAULT FALSE,
+            shipped_at TIMESTAMPTZ,
+            delivered_at TIMESTAMPTZ,
+            notes TEXT,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        CREATE INDEX IF NOT EXISTS shipments_sale_id_idx ON shipments (sale_id);
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("shipments")
+}
+
+const shipmentColumns = `
+	id, sale_id, carrier, tracking_number, cost, charge_customer,
+	shipped_at, delivered_at, notes, created_at, updated_at`
+
+func (s *PostgresStore) CreateShipment(shipment *Shipment) error {
+	query := `
+        INSERT INTO shipments (
+            sale_id,
+            carrier,
+            tracking_number,
+            cost,
+            charge_customer,
+            shipped_at,
+            delivered_at,
+            notes
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		shipment.SaleID,
+		shipment.Carrier,
+		shipment.TrackingNumber,
+		shipment.Cost,
+		shipment.ChargeCustomer,
+		shipment.ShippedAt,
+		shipment.DeliveredAt,
+		shipment.Notes,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted shipment
+	shipment.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetShipmentByID(saleID, id string) (*Shipment, error) {
+	rows, err := s.db.Query(`
+		SELECT `+shipmentColumns+`
+		FROM shipments WHERE sale_id = $1 AND id = $2`, saleID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoShipments(rows)
+	}
+
+	return nil, fmt.Errorf("shipment [%s] not found", id)
+}
+
+func (s *PostgresStore) GetShipments(saleID string) ([]Shipment, error) {
+	shipmentsBySale, err := s.getShipmentsBySaleIDs([]string{saleID})
+	if err != nil {
+		return nil, err
+	}
+	return shipmentsBySale[saleID], nil
+}
+
+func (s *PostgresStore) getShipmentsBySaleIDs(saleIDs []string) (map[string][]Shipment, error) {
+	shipmentsBySale := make(map[string][]Shipment)
+	if len(saleIDs) == 0 {
+		return shipmentsBySale, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+shipmentColumns+`
+		FROM shipments
+		WHERE sale_id = ANY($1)
+		ORDER BY created_at`, pq.Array(saleIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		shipment, err := scanIntoShipments(rows)
+		if err != nil {
+			return nil, err
+		}
+		shipmentsBySale[shipment.SaleID] = append(shipmentsBySale[shipment.SaleID], *shipment)
+	}
+
+	return shipmentsBySale, rows.Err()
+}
+
+func scanIntoShipments(rows *sql.Rows) (*Shipment, error) {
+	shipment := new(Shipment)
+	var trackingNumber, notes sql.NullString
+	var shippedAt, deliveredAt sql.NullTime
+
+	err := rows.Scan(
+		&shipment.ID,
+		&shipment.SaleID,
+		&shipment.Carrier,
+		&trackingNumber,
+		&shipment.Cost,
+		&shipment.ChargeCustomer,
+		&shippedAt,
+		&deliveredAt,
+		&notes,
+		&shipment.CreatedAt,
+		&shipment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	shipment.TrackingNumber = nullStringToPtr(trackingNumber)
+	shipment.Notes = nullStringToPtr(notes)
+	if shippedAt.Valid {
+		shipment.ShippedAt = &shippedAt.Time
+	}
+	if deliveredAt.Valid {
+		shipment.DeliveredAt = &deliveredAt.Time
+	}
+
+	return shipment, nil
+}
+
+func (s *PostgresStore) UpdateShipment(shipment *Shipment) error {
+	query := `
+		UPDATE shipments
+		SET
+		    carrier = $1,
+		    tracking_number = $2,
+		    cost = $3,
+		    charge_customer = $4,
+		    shipped_at = $5,
+		    delivered_at = $6,
+		    notes = $7
+		WHERE id = $8 AND sale_id = $9
+	`
+
+	_, err := s.db.Exec(
+		query,
+		shipment.Carrier,
+		shipment.TrackingNumber,
+		shipment.Cost,
+		shipment.ChargeCustomer,
+		shipment.ShippedAt,
+		shipment.DeliveredAt,
+		shipment.Notes,
+		shipment.ID,
+		shipment.SaleID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) DeleteShipment(saleID, id string) error {
+	_, err := s.db.Exec("DELETE FROM shipments WHERE sale_id = $1 AND id = $2", saleID, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Shipment is a package sent for a sale. Cost is what the carrier charges us,
+// when ChargeCustomer is set the customer pays it on top of the products,
+// otherwise the store absorbs it.
+type Shipment struct {
+	ID             string     `json:"id"`
+	SaleID         string     `json:"sale_id"`
+	Carrier        string     `json:"carrier"`
+	TrackingNumber *string    `json:"tracking_number"`
+	Cost           Money      `json:"cost"`
+	ChargeCustomer bool       `json:"charge_customer"`
+	ShippedAt      *time.Time `json:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	Notes          *string    `json:"notes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CreateShipmentRequest struct {
+	Carrier        string     `json:"carrier"`
+	TrackingNumber *string    `json:"tracking_number"`
+	Cost           Money      `json:"cost"`
+	ChargeCustomer bool       `json:"charge_customer"`
+	ShippedAt      *time.Time `json:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	Notes          *string    `json:"notes"`
+}
+
+func NewShipment(
+	saleID string,
+	carrier string,
+	trackingNumber *string,
+	cost Money,
+	chargeCustomer bool,
+	shippedAt *time.Time,
+	deliveredAt *time.Time,
+	notes *string,
+) (*Shipment, error) {
+	shipment := &Shipment{
+		SaleID:         saleID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		Cost:           cost,
+		ChargeCustomer: chargeCustomer,
+		ShippedAt:      shippedAt,
+		DeliveredAt:    deliveredAt,
+		Notes:          notes,
+	}
+
+	if err := shipment.Validate(); err != nil {
+		return nil, err
+	}
+
+	return shipment, nil
+}
+
+// Validate normalizes the shipment and checks its carrier, cost and dates.
+func (sh *Shipment) Validate() error {
+	sh.Carrier = strings.TrimSpace(sh.Carrier)
+	if sh.Carrier == "" {
+		return fmt.Errorf("carrier is required")
+	}
+	// A shipment sent without a cost is free
+	if sh.Cost == (Money{}) {
+		sh.Cost = NewMoney(0, baseCurrency)
+	}
+	if err := validatePrice(sh.Cost); err != nil {
+		return fmt.Errorf("shipping cost: %w", err)
+	}
+	if sh.Cost.IsNegative() {
+		return fmt.Errorf("shipping cost can't be negative")
+	}
+	if sh.DeliveredAt != nil && sh.ShippedAt == nil {
+		return fmt.Errorf("a shipment can't be delivered before being shipped")
+	}
+	if sh.DeliveredAt != nil && sh.DeliveredAt.Before(*sh.ShippedAt) {
+		return fmt.Errorf("delivered_at must be after shipped_at")
+	}
+
+	if sh.ShippedAt != nil {
+		shippedAt := sh.ShippedAt.UTC()
+		sh.ShippedAt = &shippedAt
+	}
+	if sh.DeliveredAt != nil {
+		deliveredAt := sh.DeliveredAt.UTC()
+		sh.DeliveredAt = &deliveredAt
+	}
+
+	return nil
+}
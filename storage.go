@@ -2,6 +2,9 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -19,27 +22,127 @@ type Storage interface {
 	GetCustomersLast3Months() ([]*Customer, error) // added
 	UpdateCustomer(customer *Customer) error
 	DeleteCustomer(id string) error
+	GetCustomerByPhone(phone int) (*Customer, error)
 	// Products
-	CreateProduct(product *Product) error
+	CreateProduct(product *Product, variants []*CatalogVariant) error
 	GetProductByID(id string) (*Product, error)
-	GetProducts() ([]*Product, error)
-	GetCatalogProducts() ([]*Product, error)
-	UpdateProduct(product *Product) error
+	GetProducts(filter ProductFilter) ([]*Product, error)
+	GetCatalogProducts(filter ProductFilter) ([]*Product, error)
+	CountCatalogProducts(filter ProductFilter) (int, error)
+	GetCatalogProductByID(id string) (*Product, error)
+	UpdateProduct(product *Product, changedBy *string) error
 	DeleteProduct(id string) error
+	SetProductArchived(id string, archived bool) error
+	ProductHasSales(id string) (bool, error)
+	GetProductByCode(code string) (*ProductCodeLookup, error)
+	IsProductCodeTaken(code, productID, variantID string) (bool, error)
+	// Catalog variants
+	CreateCatalogVariant(variant *CatalogVariant) error
+	GetCatalogVariantByID(productID, id string) (*CatalogVariant, error)
+	GetCatalogVariants(productID string) ([]CatalogVariant, error)
+	UpdateCatalogVariant(variant *CatalogVariant, setStock bool, changedBy *string) error
+	DeleteCatalogVariant(productID, id string) error
+	GetCatalogVariantByColor(productID, color string) (*CatalogVariant, error)
+	// Categories
+	CreateCategory(category *Category) error
+	GetCategoryByID(id string) (*Category, error)
+	GetCategories() ([]*Category, error)
+	UpdateCategory(category *Category) error
+	IsCategoryDescendant(categoryID, ancestorID string) (bool, error)
+	DeleteCategory(id string) error
+	GetTags() ([]*TagSummary, error)
+	// Collections
+	CreateCollection(collection *Collection) error
+	GetCollectionByID(id string) (*Collection, error)
+	GetCollections() ([]*Collection, error)
+	UpdateCollection(collection *Collection) error
+	SetCollectionProducts(collectionID string, productIDs []string) error
+	DeleteCollection(id string) error
+	// Prices
+	GetPriceChanges(productID string) ([]*PriceChange, error)
+	CreatePriceSchedule(schedule *PriceSchedule) error
+	GetPriceScheduleByID(productID, id string) (*PriceSchedule, error)
+	GetPriceSchedules(productID string) ([]*PriceSchedule, error)
+	CancelPriceSchedule(schedule *PriceSchedule, userID *string) error
+	ApplyDuePriceSchedules(now time.Time) error
 	// Sales
 	CreateSale(sale *SaleWithProducts) error
 	GetSaleByID(id string) (*SaleResponse, error)
-	GetSales() ([]*SaleResponse, error)
+	GetSales(filter SaleFilter) ([]*SaleResponse, error)
 	GetSalesLast3Months() ([]*SaleResponse, error)          // added
 	GetSalesByMonth() ([]*SaleResponseSortedByMonth, error) // Not in use yet
+	UpdateSaleStatus(saleID, from, to, note string, changedBy *string) error
+	GetSaleStatusChanges(saleID string) ([]*SaleStatusChange, error)
+	// Coupons
+	CreateCoupon(coupon *Coupon) error
+	GetCouponByID(id string) (*Coupon, error)
+	GetCouponByCode(code string) (*Coupon, error)
+	GetCoupons() ([]*Coupon, error)
+	UpdateCoupon(coupon *Coupon) error
+	DeleteCoupon(id string) error
+	CountCouponUsesByCustomer(couponID, customerID string) (int, error)
+	// Shipments
+	CreateShipment(shipment *Shipment) error
+	GetShipmentByID(saleID, id string) (*Shipment, error)
+	GetShipments(saleID string) ([]Shipment, error)
+	UpdateShipment(shipment *Shipment) error
+	DeleteShipment(saleID, id string) error
+	// Payments
+	CreatePayment(payment *Payment) error
+	GetPaymentByID(saleID, id string) (*Payment, error)
+	GetPayments(saleID string) ([]Payment, error)
+	DeletePayment(saleID, id string) error
+	// Order requests
+	CreateOrderRequest(order *OrderRequest) error
+	GetOrderRequestByID(id string) (*OrderRequest, error)
+	GetOrderRequests(status string) ([]*OrderRequest, error)
+	RecordOrderRequestAttempt(ipAddress string) error
+	CountRecentOrderRequestAttempts(ipAddress string, since time.Time) (int, error)
+	RejectOrderRequest(id, reason string, reviewedBy *string) error
+	// Receipts
+	IssueReceipt(saleID string) (*Receipt, error)
+	GetReceiptBySaleID(saleID string) (*Receipt, error)
+	// Expense categories
+	CreateExpenseCategory(category *ExpenseCategory) error
+	GetExpenseCategoryByID(id string) (*ExpenseCategory, error)
+	GetExpenseCategories() ([]*ExpenseCategory, error)
+	UpdateExpenseCategory(category *ExpenseCategory) error
+	DeleteExpenseCategory(id string) error
+	// Vendors
+	CreateVendor(vendor *Vendor) error
+	GetVendorByID(id string) (*Vendor, error)
+	GetVendors() ([]*Vendor, error)
+	UpdateVendor(vendor *Vendor) error
+	DeleteVendor(id string) error
 	// Expenses
 	CreateExpense(expense *Expense) error
 	GetExpenseByID(id string) (*Expense, error)
-	GetExpenses() ([]*Expense, error)
+	GetExpenses(filter ExpenseFilter) ([]*Expense, error)
 	UpdateExpense(expense *Expense) error
 	DeleteExpense(id string) error
+	// Recurring expenses
+	CreateRecurringExpense(recurring *RecurringExpense) error
+	GetRecurringExpenseByID(id string) (*RecurringExpense, error)
+	GetRecurringExpenses(activeOnly bool) ([]*RecurringExpense, error)
+	UpdateRecurringExpense(recurring *RecurringExpense) error
+	DeleteRecurringExpense(id string) error
+	SaveRecurringExpenseException(exception *RecurringExpenseException) error
+	DeleteRecurringExpenseException(recurringExpenseID string, date time.Time) error
+	GetRecurringExpenseExceptions(recurring *RecurringExpense) (map[string]*RecurringExpenseException, error)
+	MaterializeRecurringExpenses(now time.Time) error
+	// Exchange rates
+	SaveExchangeRates(rates []*ExchangeRate) error
+	GetExchangeRates(currency string) ([]*ExchangeRate, error)
+	DeleteExchangeRate(currency string, date time.Time) error
 	// EarningsSummary
-	GetEarnings() ([]*Earnings, error)
+	GetEarningsFacts(opts EarningsOptions) (*EarningsFacts, error)
+	// KPIs
+	GetFirstPurchases(paidOnly bool, customerIDs []string) (map[string]time.Time, error)
+	// Retention
+	GetCustomerPurchases(opts RetentionOptions) ([]CustomerPurchase, error)
+	// Reports
+	GetReceivables() ([]Receivable, error)
+	GetProductProfitability(filter ProfitabilityFilter) ([]ProfitabilityRow, error)
 }
 
 type PostgresStore struct {
@@ -47,7 +150,12 @@ type PostgresStore struct {
 }
 
 func (s *PostgresStore) Init() error {
-	err := s.CreateUsersTable()
+	err := s.CreateCountersTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateUsersTable()
 	if err != nil {
 		return err
 	}
@@ -57,20 +165,186 @@ func (s *PostgresStore) Init() error {
 		return err
 	}
 
+	err = s.CreateCategoriesTable()
+	if err != nil {
+		return err
+	}
+
 	err = s.CreateProductsTable()
 	if err != nil {
 		return err
 	}
 
+	err = s.CreateCatalogVariantsTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateCollectionsTables()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreatePriceTables()
+	if err != nil {
+		return err
+	}
+
 	err = s.CreateSalesTablesWithRelations()
 	if err != nil {
 		return err
 	}
 
+	err = s.CreateCouponsTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateShipmentsTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreatePaymentsTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateReceiptsTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateOrderRequestsTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateExpenseCategoriesTable()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateVendorsTable()
+	if err != nil {
+		return err
+	}
+
 	err = s.CreateExpensesTable()
 	if err != nil {
 		return err
 	}
 
+	err = s.CreateRecurringExpensesTables()
+	if err != nil {
+		return err
+	}
+
+	err = s.CreateExchangeRatesTable()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateCountersTable creates the table of named sequences used for numbers
+// that must not have gaps, which Postgres sequences don't guarantee.
+func (s *PostgresStore) CreateCountersTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS counters (
+			name VARCHAR(50) PRIMARY KEY,
+			value BIGINT NOT NULL DEFAULT 0
+		);
+	`)
+	return err
+}
+
+// nextCounterValue increments the named counter and returns its new value.
+// The counter row stays locked until tx ends, so numbers are only used when
+// tx commits and concurrent callers wait for each other.
+func nextCounterValue(tx *sql.Tx, name string) (int, error) {
+	var value int
+	err := tx.QueryRow(`
+		INSERT INTO counters (name, value) VALUES ($1, 1)
+		ON CONFLICT (name) DO UPDATE SET value = counters.value + 1
+		RETURNING value`, name).Scan(&value)
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// ensureUpdatedAtTrigger creates the <table>_updated_at_trigger that keeps the
+// updated_at column of the given table current, if it doesn't exist yet.
+func (s *PostgresStore) ensureUpdatedAtTrigger(table string) error {
+	var triggerExists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM pg_trigger
+			WHERE tgname = $1
+			AND tgrelid = $2::regclass)
+	`, table+"_updated_at_trigger", table).Scan(&triggerExists)
+	if err != nil {
+		return err
+	}
+
+	if triggerExists {
+		return nil
+	}
+
+	_, err = s.db.Exec(`
+		CREATE OR REPLACE FUNCTION update_timestamp()
+		RETURNS TRIGGER AS $$
+		BEGIN
+			NEW.updated_at = NOW();
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE TRIGGER ` + table + `_updated_at_trigger
+		BEFORE UPDATE ON ` + table + `
+		FOR EACH ROW
+		EXECUTE FUNCTION update_timestamp();
+	`)
+
+	return err
+}
+
+// withTx runs fn inside a transaction, committing it when fn succeeds and
+// rolling it back otherwise.
+func (s *PostgresStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Printf("error rolling back transaction: %v", rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
 	return nil
 }
+
+// nullStringToPtr converts a nullable column to a pointer, nil when NULL.
+func nullStringToPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+// nullIntToPtr converts a nullable column to a pointer, nil when NULL.
+func nullIntToPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	// Time zones are looked up by name, so the binary ships their database
+	_ "time/tzdata"
+)
+
+// defaultTimeZone is the time zone of the business. Days, weeks and months
+// of the reports start at midnight in it, and recurring expenses are dated on
+// it.
+const defaultTimeZone = "America/Bogota"
+
+// businessLocation is the time zone of the business, set with the TIME_ZONE
+// env var.
+func businessLocation() *time.Location {
+	name := defaultTimeZone
+	if value := os.Getenv("TIME_ZONE"); value != "" {
+		name = value
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("invalid TIME_ZONE [%s], using %s", name, defaultTimeZone)
+		loc, _ = time.LoadLocation(defaultTimeZone)
+	}
+	return loc
+}
+
+// loadTimeZone reads an IANA time zone name such as America/Bogota, the
+// business time zone is used when name is empty.
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return businessLocation(), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("invalid time zone [%s], use a name such as %s", name, defaultTimeZone)
+	}
+	return loc, nil
+}
+
+// localDate is the day of t in loc, at midnight UTC like the values of DATE
+// columns.
+func localDate(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// startOfDay is the instant the given day starts in loc.
+func startOfDay(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"log"
+	"net"
 	"net/http"
-	"strconv"
+	"os"
 	"strings"
+	"unicode/utf8"
 )
 
 // WriteJSON writes the given data as JSON to the HTTP response with the provided status code.
@@ -20,6 +24,33 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
+// WriteCachedJSON writes the given data as JSON with an ETag and a
+// Cache-Control header so it can be cached by browsers and CDNs. When the
+// request already has the current version it answers 304 Not Modified.
+func WriteCachedJSON(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=60, stale-while-revalidate=300")
+
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
 // apiFunc is a type representing a function that handles HTTP requests and returns an error.
 type apiFunc func(http.ResponseWriter, *http.Request) error
 
@@ -55,6 +86,88 @@ func getID(r *http.Request) (string, error) {
 	return id, nil
 }
 
+// getVariantID extracts the variantID parameter from the URL path of the HTTP request r.
+func getVariantID(r *http.Request) (string, error) {
+	return getRouteUUID(r, "variantID")
+}
+
+// getRouteUUID extracts the named UUID parameter from the URL path of the HTTP request r.
+func getRouteUUID(r *http.Request, name string) (string, error) {
+	id := mux.Vars(r)[name]
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return id, fmt.Errorf("invalid %s %s: %v", name, id, err)
+	}
+	return id, nil
+}
+
+// getClientIP returns the address of the client. X-Forwarded-For is set by
+// the client, so it is only read when the request comes from a proxy listed
+// in TRUSTED_PROXIES, and then only the right-most hop no trusted proxy added.
+func getClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	proxies := trustedProxies()
+	if !isTrustedProxy(remote, proxies) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(hop, proxies) {
+			return hop
+		}
+	}
+	return remote
+}
+
+// trustedProxies reads the comma separated IPs and CIDR ranges of the
+// TRUSTED_PROXIES env var, such as the addresses of the load balancer.
+func trustedProxies() []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("invalid TRUSTED_PROXIES entry [%s]", entry)
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+func isTrustedProxy(address string, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // ConvertToDBArray converts a slice of strings to a format suitable for PostgreSQL array type.
 // Example:
 //
@@ -84,6 +197,103 @@ func ConvertFromDBArray(dbArray string) []string {
 	return strings.Split(dbArray, ",")
 }
 
+// slugify turns a name into a lowercase, URL friendly identifier.
+// Example:
+//
+//	input: "Bolsos de Mañana"
+//	output: "bolsos-de-manana"
+func slugify(name string) string {
+	replacer := strings.NewReplacer(
+		"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	)
+	name = replacer.Replace(strings.ToLower(strings.TrimSpace(name)))
+
+	var slug strings.Builder
+	lastDash := true
+	for _, c := range name {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			slug.WriteRune(c)
+			lastDash = false
+			continue
+		}
+		if !lastDash {
+			slug.WriteRune('-')
+			lastDash = true
+		}
+	}
+
+	return strings.TrimSuffix(slug.String(), "-")
+}
+
+// maxTagLength is the length of the tags column, in characters.
+const maxTagLength = 50
+
+// normalizeTags lower-cases and trims tags, dropping empty and repeated ones.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag [%s] is longer than %d characters", tag, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
+
+// normalizeSKU trims and upper-cases a SKU, empty SKUs become nil.
+func normalizeSKU(sku *string) *string {
+	if sku == nil {
+		return nil
+	}
+	normalized := strings.ToUpper(strings.TrimSpace(*sku))
+	if normalized == "" {
+		return nil
+	}
+	return &normalized
+}
+
+// normalizeBarcode trims a barcode and validates it as an EAN-8, UPC-A or
+// EAN-13 code, including its check digit. Empty barcodes become nil.
+func normalizeBarcode(barcode *string) (*string, error) {
+	if barcode == nil {
+		return nil, nil
+	}
+	normalized := strings.TrimSpace(*barcode)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	if len(normalized) != 8 && len(normalized) != 12 && len(normalized) != 13 {
+		return nil, fmt.Errorf("invalid barcode %s: must have 8, 12 or 13 digits", normalized)
+	}
+
+	// The check digit is the last one, weights alternate 3 and 1 from right to left
+	sum := 0
+	for i := len(normalized) - 2; i >= 0; i-- {
+		digit := normalized[i]
+		if digit < '0' || digit > '9' {
+			return nil, fmt.Errorf("invalid barcode %s: must contain only digits", normalized)
+		}
+		weight := 1
+		if (len(normalized)-2-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(digit-'0') * weight
+	}
+	checkDigit := normalized[len(normalized)-1]
+	if checkDigit < '0' || checkDigit > '9' || int(checkDigit-'0') != (10-sum%10)%10 {
+		return nil, fmt.Errorf("invalid barcode %s: wrong check digit", normalized)
+	}
+
+	return &normalized, nil
+}
+
 func colorFromLocalConstants(color string) struct {
 	bgColor   string
 	textColor string
@@ -110,43 +320,15 @@ func findProductVariation(products []*Product, pID string) *Product {
 	return nil
 }
 
-// formatCurrency formats an integer value as a currency string.
+// formatCurrency formats money in the locale set with MONEY_LOCALE.
 // Examples:
 //
-//	formattedValue := formatCurrency(45000, "COP")
-//	// Output: COP 45,000
-//	formattedValue := formatCurrency(120000, "COP")
-//	// Output: COP 120,000
-//	formattedValue := formatCurrency(60000, "COP")
-//	// Output: COP 60,000
-func formatCurrency(value int, currency string) string {
-	// Convert int to string
-	valueStr := strconv.Itoa(value)
-
-	// Split value string into integer and decimal parts
-	parts := strings.Split(valueStr, ".")
-
-	// Format integer part with commas for thousands
-	integerPart := parts[0]
-	var formattedInteger string
-	for i := len(integerPart); i > 0; i -= 3 {
-		start := i - 3
-		if start < 0 {
-			start = 0
-		}
-		formattedInteger = integerPart[start:i] + formattedInteger
-		if start != 0 {
-			formattedInteger = "," + formattedInteger
-		}
-	}
-
-	// Construct formatted currency string
-	formattedValue := currency + " " + formattedInteger
-	if len(parts) > 1 {
-		formattedValue += "." + parts[1]
-	}
-
-	return formattedValue
+//	formattedValue := formatCurrency(MoneyFromUnits(45000, "COP"))
+//	// Output: COP 45.000
+//	formattedValue := formatCurrency(NewMoney(125050, "USD"))
+//	// Output: USD 1.250,50
+func formatCurrency(value Money) string {
+	return value.Format(moneyLocale())
 }
 
 // Color represents a color option
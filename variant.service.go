@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (server *APIServer) handleCreateCatalogVariant(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+
+	req := new(CreateCatalogVariantRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	// New variants go last unless a position is given
+	if req.Position == nil {
+		variants, err := server.store.GetCatalogVariants(productID)
+		if err != nil {
+			return err
+		}
+		position := len(variants)
+		req.Position = &position
+	}
+
+	variant, err := server.newCatalogVariant(productID, *req)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateCatalogVariant(variant); err != nil {
+		return err
+	}
+
+	// Recovering variant from DB
+	createdVariant, err := server.store.GetCatalogVariantByID(productID, variant.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdVariant)
+}
+
+// newCatalogVariant validates a variant request and uploads its image when
+// needed, the variant is not stored yet.
+func (server *APIServer) newCatalogVariant(productID string, req CreateCatalogVariantRequest) (*CatalogVariant, error) {
+	variant, err := NewCatalogVariant(
+		productID,
+		req.ColorHex,
+		req.ColorName,
+		req.Image,
+		req.Price,
+		req.UnitCost,
+		req.SKU,
+		req.Barcode,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	variant.SKU, variant.Barcode, err = server.normalizeProductCodes("", "", variant.SKU, variant.Barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Position != nil {
+		variant.Position = *req.Position
+	}
+	variant.Stock = req.Stock
+
+	variant.Image, err = server.uploadImageIfBase64(variant.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (server *APIServer) handleGetCatalogVariants(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+
+	variants, err := server.store.GetCatalogVariants(productID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, variants)
+}
+
+func (server *APIServer) handleGetCatalogVariantByID(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	variantID, err := getVariantID(r)
+	if err != nil {
+		return err
+	}
+
+	variant, err := server.store.GetCatalogVariantByID(productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, variant)
+}
+
+func (server *APIServer) handleUpdateCatalogVariant(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	variantID, err := getVariantID(r)
+	if err != nil {
+		return err
+	}
+
+	oldVariant, err := server.store.GetCatalogVariantByID(productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	req := new(UpdateCatalogVariantRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	variant := *oldVariant
+	if err := req.Apply(&variant); err != nil {
+		return err
+	}
+
+	// A new image is always a base64 file, an empty one removes the image
+	if req.Image != nil && *req.Image != oldVariant.Image {
+		variant.Image, err = server.uploadImageIfBase64(*req.Image)
+		if err != nil {
+			return err
+		}
+		if oldVariant.Image != "" {
+			err = BucketBasics.DeleteFile(BucketBasics{S3Client: server.s3Client}, oldVariant.Image)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	variant.SKU, variant.Barcode, err = server.normalizeProductCodes("", variantID, variant.SKU, variant.Barcode)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.UpdateCatalogVariant(&variant, req.sent["stock"], getAuthUserID(r)); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedVariant, err := server.store.GetCatalogVariantByID(productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedVariant)
+}
+
+func (server *APIServer) handleDeleteCatalogVariant(w http.ResponseWriter, r *http.Request) error {
+	productID, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	variantID, err := getVariantID(r)
+	if err != nil {
+		return err
+	}
+
+	variant, err := server.store.GetCatalogVariantByID(productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	if variant.Image != "" {
+		err = BucketBasics.DeleteFile(BucketBasics{S3Client: server.s3Client}, variant.Image)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := server.store.DeleteCatalogVariant(productID, variantID); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": variantID})
+}
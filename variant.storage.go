@@ -0,0 +1,369 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+func (s *PostgresStore) CreateCatalogVariantsTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS catalog_variants (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+            color_hex VARCHAR(20) NOT NULL DEFAULT '',
+            color_name VARCHAR(255) NOT NULL DEFAULT '',
+            image VARCHAR(255) NOT NULL DEFAULT '',
+            price BIGINT,
+            unit_cost BIGINT,
+            sku VARCHAR(64),
+            barcode VARCHAR(14),
+            stock INTEGER,
+            position INTEGER NOT NULL DEFAULT 0,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        );
+
+        ALTER TABLE catalog_variants ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+        ALTER TABLE catalog_variants ADD COLUMN IF NOT EXISTS stock INTEGER;
+        ALTER TABLE catalog_variants ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
+
+        CREATE INDEX IF NOT EXISTS catalog_variants_product_id_idx ON catalog_variants (product_id, position);
+        CREATE UNIQUE INDEX IF NOT EXISTS catalog_variants_sku_idx ON catalog_variants (sku) WHERE sku IS NOT NULL;
+        CREATE UNIQUE INDEX IF NOT EXISTS catalog_variants_barcode_idx ON catalog_variants (barcode) WHERE barcode IS NOT NULL;
+    `)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureUpdatedAtTrigger("catalog_variants"); err != nil {
+		return err
+	}
+
+	return s.migrateCatalogVariantsJSON()
+}
+
+// migrateCatalogVariantsJSON moves the variants stored in the legacy
+// products.catalog_variants JSONB column into the catalog_variants table and
+// drops the column. It is a no-op once the column is gone.
+func (s *PostgresStore) migrateCatalogVariantsJSON() error {
+	var columnExists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'products' AND column_name = 'catalog_variants')
+	`).Scan(&columnExists)
+	if err != nil {
+		return err
+	}
+	if !columnExists {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO catalog_variants (
+			id,
+			product_id,
+			color_hex,
+			color_name,
+			image,
+			position,
+			created_at,
+			updated_at
+		)
+		SELECT
+			(v.value->>'id')::UUID,
+			p.id,
+			COALESCE(v.value->>'color_hex', ''),
+			COALESCE(v.value->>'color_name', ''),
+			COALESCE(v.value->>'image', ''),
+			(v.ordinality - 1)::INTEGER,
+			COALESCE((v.value->>'created_at')::TIMESTAMPTZ, CURRENT_TIMESTAMP),
+			COALESCE((v.value->>'updated_at')::TIMESTAMPTZ, CURRENT_TIMESTAMP)
+		FROM
+			products p,
+			JSONB_ARRAY_ELEMENTS(p.catalog_variants) WITH ORDINALITY AS v(value, ordinality)
+		WHERE
+			JSONB_TYPEOF(p.catalog_variants) = 'array'
+		ON CONFLICT (id) DO NOTHING;
+
+		ALTER TABLE products DROP COLUMN catalog_variants;
+	`)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Printf("error rolling back transaction: %v", rollbackErr)
+		}
+		return fmt.Errorf("error migrating catalog variants: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+const catalogVariantColumns = `
+	id, product_id, color_hex, color_name, image,
+	price, unit_cost, sku, barcode, stock, position, created_at, updated_at`
+
+func (s *PostgresStore) CreateCatalogVariant(variant *CatalogVariant) error {
+	return insertCatalogVariant(s.db, variant)
+}
+
+// rowQueryer runs single-row queries on the database or in a transaction.
+type rowQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func insertCatalogVariant(db rowQueryer, variant *CatalogVariant) error {
+	query := `
+        INSERT INTO catalog_variants (
+            product_id,
+            color_hex,
+            color_name,
+            image,
+            price,
+            unit_cost,
+            sku,
+            barcode,
+            stock,
+            position
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id
+    `
+
+	var id string
+	err := db.QueryRow(
+		query,
+		variant.ProductID,
+		variant.ColorHex,
+		variant.ColorName,
+		variant.Image,
+		variant.Price,
+		variant.UnitCost,
+		variant.SKU,
+		variant.Barcode,
+		variant.Stock,
+		variant.Position,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted variant
+	variant.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetCatalogVariantByID(productID, id string) (*CatalogVariant, error) {
+	rows, err := s.db.Query(`
+		SELECT `+catalogVariantColumns+`
+		FROM catalog_variants WHERE product_id = $1 AND id = $2`, productID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCatalogVariants(rows)
+	}
+
+	return nil, fmt.Errorf("variant [%s] not found", id)
+}
+
+func (s *PostgresStore) GetCatalogVariants(productID string) ([]CatalogVariant, error) {
+	variantsByProduct, err := s.getCatalogVariantsByProductIDs([]string{productID})
+	if err != nil {
+		return nil, err
+	}
+	return variantsByProduct[productID], nil
+}
+
+// getCatalogVariantsByProductIDs loads the variants of several products with a
+// single query, keyed by product ID and sorted by display position.
+func (s *PostgresStore) getCatalogVariantsByProductIDs(productIDs []string) (map[string][]CatalogVariant, error) {
+	variantsByProduct := make(map[string][]CatalogVariant)
+	if len(productIDs) == 0 {
+		return variantsByProduct, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+catalogVariantColumns+`
+		FROM catalog_variants
+		WHERE product_id = ANY($1)
+		ORDER BY position, created_at`, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		variant, err := scanIntoCatalogVariants(rows)
+		if err != nil {
+			return nil, err
+		}
+		variantsByProduct[variant.ProductID] = append(variantsByProduct[variant.ProductID], *variant)
+	}
+
+	return variantsByProduct, rows.Err()
+}
+
+// attachCatalogVariants fills CatalogVariants on each of the given products.
+func (s *PostgresStore) attachCatalogVariants(products ...*Product) error {
+	var productIDs []string
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	variantsByProduct, err := s.getCatalogVariantsByProductIDs(productIDs)
+	if err != nil {
+		return err
+	}
+
+	// Products without variants are always available
+	for _, product := range products {
+		product.CatalogVariants = variantsByProduct[product.ID]
+		product.InStock = len(product.CatalogVariants) == 0
+		for _, variant := range product.CatalogVariants {
+			product.InStock = product.InStock || variant.InStock
+		}
+	}
+
+	return nil
+}
+
+func scanIntoCatalogVariants(rows *sql.Rows) (*CatalogVariant, error) {
+	variant := new(CatalogVariant)
+	var sku sql.NullString
+	var barcode sql.NullString
+	var stock sql.NullInt64
+
+	err := rows.Scan(
+		&variant.ID,
+		&variant.ProductID,
+		&variant.ColorHex,
+		&variant.ColorName,
+		&variant.Image,
+		&variant.Price,
+		&variant.UnitCost,
+		&sku,
+		&barcode,
+		&stock,
+		&variant.Position,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if sku.Valid {
+		variant.SKU = &sku.String
+	}
+
+	if barcode.Valid {
+		variant.Barcode = &barcode.String
+	}
+
+	variant.Stock = nullIntToPtr(stock)
+	variant.InStock = variant.Stock == nil || *variant.Stock > 0
+
+	return variant, nil
+}
+
+// UpdateCatalogVariant stores the variant and records the changes of its
+// price and unit cost in the same transaction. The stock is only written when
+// setStock is true, otherwise the stock taken by sales since the variant was
+// read would come back.
+func (s *PostgresStore) UpdateCatalogVariant(variant *CatalogVariant, setStock bool, changedBy *string) error {
+	query := `
+		UPDATE catalog_variants
+		SET
+		    color_hex = $1,
+		    color_name = $2,
+		    image = $3,
+		    sku = $4,
+		    barcode = $5,
+		    stock = CASE WHEN $10 THEN $6 ELSE stock END,
+		    position = $7
+		WHERE id = $8 AND product_id = $9
+	`
+
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			query,
+			variant.ColorHex,
+			variant.ColorName,
+			variant.Image,
+			variant.SKU,
+			variant.Barcode,
+			variant.Stock,
+			variant.Position,
+			variant.ID,
+			variant.ProductID,
+			setStock,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := setVariantPriceTx(tx, variant.ProductID, variant.ID, PriceFieldPrice, variant.Price, changedBy); err != nil {
+			return err
+		}
+		return setVariantPriceTx(tx, variant.ProductID, variant.ID, PriceFieldUnitCost, variant.UnitCost, changedBy)
+	})
+}
+
+// GetCatalogVariantByColor finds the variant of a product with the given color
+// name, ignoring case. It returns nil when the product has no such variant.
+func (s *PostgresStore) GetCatalogVariantByColor(productID, color string) (*CatalogVariant, error) {
+	rows, err := s.db.Query(`
+		SELECT `+catalogVariantColumns+`
+		FROM catalog_variants
+		WHERE product_id = $1 AND LOWER(color_name) = LOWER($2)
+		ORDER BY position
+		LIMIT 1`, productID, color)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoCatalogVariants(rows)
+	}
+
+	return nil, nil
+}
+
+func (s *PostgresStore) DeleteCatalogVariant(productID, id string) error {
+	_, err := s.db.Exec("DELETE FROM catalog_variants WHERE id = $1 AND product_id = $2", id, productID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CatalogVariant is a color variant of a product shown in the public catalog.
+// Price and SKU are optional, when Price is nil the product price applies, and
+// so does the product unit cost when UnitCost is nil.
+// Stock is only tracked when set, untracked variants are always in stock.
+type CatalogVariant struct {
+	ID        string    `json:"id"`
+	ProductID string    `json:"product_id"`
+	ColorHex  string    `json:"color_hex"`
+	ColorName string    `json:"color_name"`
+	Image     string    `json:"image"`
+	Price     *Money    `json:"price,omitempty"`
+	UnitCost  *Money    `json:"unit_cost,omitempty"`
+	SKU       *string   `json:"sku,omitempty"`
+	Barcode   *string   `json:"barcode,omitempty"`
+	Stock     *int      `json:"stock,omitempty"`
+	InStock   bool      `json:"in_stock"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateCatalogVariantRequest struct {
+	ColorHex  string  `json:"color_hex"`
+	ColorName string  `json:"color_name"`
+	Image     string  `json:"image"`
+	Price     *Money  `json:"price,omitempty"`
+	UnitCost  *Money  `json:"unit_cost,omitempty"`
+	SKU       *string `json:"sku,omitempty"`
+	Barcode   *string `json:"barcode,omitempty"`
+	Stock     *int    `json:"stock,omitempty"`
+	Position  *int    `json:"position,omitempty"`
+}
+
+// UpdateCatalogVariantRequest only changes the fields that are sent, the
+// others keep their value. Sending null clears the price, unit cost, SKU,
+// barcode and stock.
+type UpdateCatalogVariantRequest struct {
+	ColorHex  *string `json:"color_hex"`
+	ColorName *string `json:"color_name"`
+	Image     *string `json:"image"`
+	Price     *Money  `json:"price"`
+	UnitCost  *Money  `json:"unit_cost"`
+	SKU       *string `json:"sku"`
+	Barcode   *string `json:"barcode"`
+	Stock     *int    `json:"stock"`
+	Position  *int    `json:"position"`
+
+	sent map[string]bool
+}
+
+func (req *UpdateCatalogVariantRequest) UnmarshalJSON(data []byte) error {
+	type fields UpdateCatalogVariantRequest
+	if err := json.Unmarshal(data, (*fields)(req)); err != nil {
+		return err
+	}
+
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return err
+	}
+	req.sent = make(map[string]bool, len(sent))
+	for key := range sent {
+		req.sent[key] = true
+	}
+	return nil
+}
+
+// Apply copies the sent fields to variant, except the image which needs to
+// be uploaded first.
+func (req *UpdateCatalogVariantRequest) Apply(variant *CatalogVariant) error {
+	if err := validateVariantPrices(req.Price, req.UnitCost); err != nil {
+		return err
+	}
+
+	if req.ColorHex != nil {
+		variant.ColorHex = *req.ColorHex
+	}
+	if req.ColorName != nil {
+		variant.ColorName = *req.ColorName
+	}
+	if req.Position != nil {
+		variant.Position = *req.Position
+	}
+	if req.sent["price"] {
+		variant.Price = req.Price
+	}
+	if req.sent["unit_cost"] {
+		variant.UnitCost = req.UnitCost
+	}
+	if req.sent["sku"] {
+		variant.SKU = req.SKU
+	}
+	if req.sent["barcode"] {
+		variant.Barcode = req.Barcode
+	}
+	if req.sent["stock"] {
+		variant.Stock = req.Stock
+	}
+	return nil
+}
+
+func NewCatalogVariant(
+	productID string,
+	colorHex string,
+	colorName string,
+	image string,
+	price *Money,
+	unitCost *Money,
+	sku *string,
+	barcode *string,
+) (*CatalogVariant, error) {
+	if err := validateVariantPrices(price, unitCost); err != nil {
+		return nil, err
+	}
+
+	return &CatalogVariant{
+		ProductID: productID,
+		ColorHex:  colorHex,
+		ColorName: colorName,
+		Image:     image,
+		Price:     price,
+		UnitCost:  unitCost,
+		SKU:       sku,
+		Barcode:   barcode,
+	}, nil
+}
+
+// validateVariantPrices checks the optional price and unit cost of a variant,
+// they follow the same rules as the ones of products.
+func validateVariantPrices(price, unitCost *Money) error {
+	if price != nil {
+		if err := validatePrice(*price); err != nil {
+			return err
+		}
+	}
+	return validateUnitCost(unitCost)
+}
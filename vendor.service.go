@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (server *APIServer) handleCreateVendor(w http.ResponseWriter, r *http.Request) error {
+	req := new(CreateVendorRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	vendor, err := NewVendor(
+		req.Name,
+		req.TaxID,
+		req.Email,
+		req.Phone,
+		req.Notes,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.CreateVendor(vendor); err != nil {
+		return err
+	}
+
+	// Recovering vendor from DB
+	createdVendor, err := server.store.GetVendorByID(vendor.ID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, createdVendor)
+}
+
+func (server *APIServer) handleGetVendors(w http.ResponseWriter, _ *http.Request) error {
+	vendors, err := server.store.GetVendors()
+	if err != nil {
+		return err
+	}
+	if vendors == nil {
+		vendors = []*Vendor{}
+	}
+	return WriteJSON(w, http.StatusOK, vendors)
+}
+
+func (server *APIServer) handleGetVendorByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	vendor, err := server.store.GetVendorByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, vendor)
+}
+
+func (server *APIServer) handleUpdateVendor(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetVendorByID(id)
+	if err != nil {
+		return err
+	}
+
+	var vendor Vendor
+	if err := json.NewDecoder(r.Body).Decode(&vendor); err != nil {
+		return err
+	}
+
+	vendor.ID = id
+	if err := vendor.Validate(); err != nil {
+		return err
+	}
+
+	if err := server.store.UpdateVendor(&vendor); err != nil {
+		return err
+	}
+
+	// Retrieve the updated information from the database to get the most up-to-date data
+	updatedVendor, err := server.store.GetVendorByID(id)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, updatedVendor)
+}
+
+func (server *APIServer) handleDeleteVendor(w http.ResponseWriter, r *http.Request) error {
+	id, err := getID(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.GetVendorByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := server.store.DeleteVendor(id); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
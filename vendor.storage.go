@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+func (s *PostgresStore) CreateVendorsTable() error {
+	// Create the table if it doesn't exist
+	_, err := s.db.Exec(`
+        CREATE TABLE IF NOT EXISTS vendors (
+            id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            tax_id VARCHAR(50) UNIQUE,
+            email VARCHAR(255),
+            phone VARCHAR(50),
+            notes TEXT,
+            created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureUpdatedAtTrigger("vendors")
+}
+
+const vendorColumns = `
+	id, name, tax_id, email, phone, notes, created_at, updated_at`
+
+func (s *PostgresStore) CreateVendor(vendor *Vendor) error {
+	query := `
+        INSERT INTO vendors (
+            name,
+            tax_id,
+            email,
+            phone,
+            notes
+        )
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+
+	var id string
+	err := s.db.QueryRow(
+		query,
+		vendor.Name,
+		vendor.TaxID,
+		vendor.Email,
+		vendor.Phone,
+		vendor.Notes,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Set the ID of the inserted vendor
+	vendor.ID = id
+
+	return nil
+}
+
+func (s *PostgresStore) GetVendorByID(id string) (*Vendor, error) {
+	rows, err := s.db.Query(`
+		SELECT `+vendorColumns+`
+		FROM vendors WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		return scanIntoVendors(rows)
+	}
+
+	return nil, fmt.Errorf("vendor [%s] not found", id)
+}
+
+func (s *PostgresStore) GetVendors() ([]*Vendor, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + vendorColumns + `
+		FROM vendors ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}(rows)
+
+	var vendors []*Vendor
+	for rows.Next() {
+		vendor, err := scanIntoVendors(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		vendors = append(vendors, vendor)
+	}
+
+	return vendors, nil
+}
+
+func scanIntoVendors(rows *sql.Rows) (*Vendor, error) {
+	vendor := new(Vendor)
+	var taxID, email, phone, notes sql.NullString
+
+	err := rows.Scan(
+		&vendor.ID,
+		&vendor.Name,
+		&taxID,
+		&email,
+		&phone,
+		&notes,
+		&vendor.CreatedAt,
+		&vendor.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vendor.TaxID = nullStringToPtr(taxID)
+	vendor.Email = nullStringToPtr(email)
+	vendor.Phone = nullStringToPtr(phone)
+	vendor.Notes = nullStringToPtr(notes)
+
+	return vendor, nil
+}
+
+func (s *PostgresStore) UpdateVendor(vendor *Vendor) error {
+	query := `
+		UPDATE vendors
+		SET
+		    name = $1,
+		    tax_id = $2,
+		    email = $3,
+		    phone = $4,
+		    notes = $5
+		WHERE id = $6
+	`
+
+	_, err := s.db.Exec(
+		query,
+		vendor.Name,
+		vendor.TaxID,
+		vendor.Email,
+		vendor.Phone,
+		vendor.Notes,
+		vendor.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteVendor deletes a vendor, its expenses are left without one.
+func (s *PostgresStore) DeleteVendor(id string) error {
+	_, err := s.db.Exec("DELETE FROM vendors WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
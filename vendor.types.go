@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Vendor is a supplier expenses are paid to.
+type Vendor struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TaxID     *string   `json:"tax_id"`
+	Email     *string   `json:"email"`
+	Phone     *string   `json:"phone"`
+	Notes     *string   `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateVendorRequest struct {
+	Name  string  `json:"name"`
+	TaxID *string `json:"tax_id"`
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
+	Notes *string `json:"notes"`
+}
+
+func NewVendor(
+	name string,
+	taxID *string,
+	email *string,
+	phone *string,
+	notes *string,
+) (*Vendor, error) {
+	vendor := &Vendor{
+		Name:  name,
+		TaxID: taxID,
+		Email: email,
+		Phone: phone,
+		Notes: notes,
+	}
+
+	if err := vendor.Validate(); err != nil {
+		return nil, err
+	}
+
+	return vendor, nil
+}
+
+// Validate checks the vendor has a name. An empty tax ID (NIT) is dropped so
+// it doesn't clash with the unique index.
+func (v *Vendor) Validate() error {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return fmt.Errorf("vendor name is required")
+	}
+	if v.TaxID != nil {
+		taxID := strings.TrimSpace(*v.TaxID)
+		if taxID == "" {
+			v.TaxID = nil
+		} else {
+			v.TaxID = &taxID
+		}
+	}
+	return nil
+}